@@ -0,0 +1,109 @@
+package access
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/memoio/xspace-server/contract/token"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
+)
+
+var ErrInvalidPolicy = xerrors.New("invalid access policy")
+
+// Evaluator checks dataNFT access policies, holders policies are checked
+// against the chain.
+type Evaluator struct {
+	store           *database.DataStore
+	tokenController *token.TokenController
+}
+
+func NewEvaluator(store *database.DataStore, tokenController *token.TokenController) *Evaluator {
+	return &Evaluator{store: store, tokenController: tokenController}
+}
+
+// CanRead reports whether the user owning the wallets can read the content
+// of the NFT, either one of them owns it or the policy lets one in.
+func (e *Evaluator) CanRead(ctx context.Context, nft database.NFT, wallets []string) (bool, error) {
+	if slices.Contains(wallets, nft.Address) {
+		return true, nil
+	}
+
+	policy, err := e.store.GetAccessPolicy(nft.TokenID)
+	if err != nil {
+		return false, err
+	}
+	if policy.ExpireAt != nil && time.Now().After(*policy.ExpireAt) {
+		return false, nil
+	}
+
+	switch policy.Mode {
+	case database.AccessPublic:
+		return true, nil
+	case database.AccessAllowlist:
+		for _, allowed := range strings.Split(policy.Allowlist, ",") {
+			for _, address := range wallets {
+				if strings.EqualFold(allowed, address) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	case database.AccessHolders:
+		minBalance, ok := new(big.Int).SetString(policy.MinBalance, 10)
+		if !ok {
+			return false, ErrInvalidPolicy
+		}
+		for _, address := range wallets {
+			balance, err := e.tokenController.BalanceOf(ctx, common.HexToAddress(policy.Contract), common.HexToAddress(address))
+			if err != nil {
+				return false, err
+			}
+			if balance.Cmp(minBalance) >= 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, nil
+	}
+}
+
+// Validate normalizes the policy and rejects malformed ones.
+func Validate(policy *database.AccessPolicy) error {
+	switch policy.Mode {
+	case database.AccessOwner, database.AccessPublic:
+		policy.Contract, policy.MinBalance, policy.Allowlist = "", "", ""
+	case database.AccessHolders:
+		if !common.IsHexAddress(policy.Contract) {
+			return xerrors.Errorf("contract must be an address: %w", ErrInvalidPolicy)
+		}
+		policy.Contract = common.HexToAddress(policy.Contract).Hex()
+		if policy.MinBalance == "" {
+			policy.MinBalance = "1"
+		}
+		minBalance, ok := new(big.Int).SetString(policy.MinBalance, 10)
+		if !ok || minBalance.Sign() <= 0 {
+			return xerrors.Errorf("minBalance must be a positive integer: %w", ErrInvalidPolicy)
+		}
+		policy.Allowlist = ""
+	case database.AccessAllowlist:
+		addresses := strings.Split(policy.Allowlist, ",")
+		for i, address := range addresses {
+			address = strings.TrimSpace(address)
+			if !common.IsHexAddress(address) {
+				return xerrors.Errorf("%q is not an address: %w", address, ErrInvalidPolicy)
+			}
+			addresses[i] = common.HexToAddress(address).Hex()
+		}
+		policy.Allowlist = strings.Join(addresses, ",")
+		policy.Contract, policy.MinBalance = "", ""
+	default:
+		return xerrors.Errorf("unknown mode %q: %w", policy.Mode, ErrInvalidPolicy)
+	}
+	return nil
+}
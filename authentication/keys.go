@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/memoio/xspace-server/config"
+	"golang.org/x/xerrors"
+)
+
+const (
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+var ErrUnknownKey = xerrors.New("unknown signing key")
+
+// KeySet holds the key signing new tokens and all the keys whose tokens are
+// still accepted. To rotate, add a new key, make it active and keep the old
+// one configured until the refresh tokens it signed expire.
+type KeySet struct {
+	active *jwtKey
+	keys   map[string]*jwtKey
+}
+
+type jwtKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// NewKeySet loads the keys from their PEM files. It fails if no key is
+// configured or the active key has no private key.
+func NewKeySet(cfg config.AuthConfig) (*KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, xerrors.New("no json web token key is configured, generate one with `server keygen`")
+	}
+
+	ks := &KeySet{keys: make(map[string]*jwtKey, len(cfg.Keys))}
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.ID == "" {
+			return nil, xerrors.New("json web token key has no id")
+		}
+		if _, ok := ks.keys[keyCfg.ID]; ok {
+			return nil, xerrors.Errorf("duplicated json web token key %s", keyCfg.ID)
+		}
+
+		key, err := loadKey(keyCfg)
+		if err != nil {
+			return nil, xerrors.Errorf("load json web token key %s: %w", keyCfg.ID, err)
+		}
+		ks.keys[key.id] = key
+	}
+
+	active, ok := ks.keys[cfg.ActiveKey]
+	if !ok {
+		return nil, xerrors.Errorf("active json web token key %q is not configured", cfg.ActiveKey)
+	}
+	if active.privateKey == nil {
+		return nil, xerrors.Errorf("active json web token key %s has no private key", active.id)
+	}
+	ks.active = active
+
+	return ks, nil
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.method, claims)
+	token.Header["kid"] = ks.active.id
+	return token.SignedString(ks.active.privateKey)
+}
+
+// algorithms returns the signing algorithms of the keys, tokens signed with
+// other algorithms are rejected.
+func (ks *KeySet) algorithms() []string {
+	var algs []string
+	for _, key := range ks.keys {
+		if !slices.Contains(algs, key.method.Alg()) {
+			algs = append(algs, key.method.Alg())
+		}
+	}
+	return algs
+}
+
+// keyFunc finds the verification key by the token's kid and makes sure the
+// token is signed with the key's algorithm.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, xerrors.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.publicKey, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys verifying xspace tokens.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk := JWK{Kid: key.id, Alg: key.method.Alg(), Use: "sig"}
+		switch pub := key.publicKey.(type) {
+		case *ecdsa.PublicKey:
+			jwk.Kty, jwk.Crv = "EC", "P-256"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+func loadKey(cfg config.JWTKeyConfig) (*jwtKey, error) {
+	key := &jwtKey{id: cfg.ID}
+	switch cfg.Algorithm {
+	case AlgES256:
+		key.method = jwt.SigningMethodES256
+	case AlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, xerrors.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	if cfg.PrivateKeyFile != "" {
+		block, err := readPEM(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.privateKey = privateKey
+		key.publicKey = privateKey.(crypto.Signer).Public()
+	} else if cfg.PublicKeyFile != "" {
+		block, err := readPEM(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key.publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, xerrors.New("neither private key nor public key is configured")
+	}
+
+	switch pub := key.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if cfg.Algorithm != AlgES256 || pub.Curve != elliptic.P256() {
+			return nil, xerrors.Errorf("%s needs a P-256 key", cfg.Algorithm)
+		}
+	case ed25519.PublicKey:
+		if cfg.Algorithm != AlgEdDSA {
+			return nil, xerrors.Errorf("%s needs an Ed25519 key", cfg.Algorithm)
+		}
+	default:
+		return nil, xerrors.Errorf("unsupported key type %T", key.publicKey)
+	}
+
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, xerrors.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
+
+// GenerateKey generates a private key for the algorithm, PEM (PKCS#8)
+// encoded.
+func GenerateKey(alg string) ([]byte, error) {
+	var privateKey crypto.PrivateKey
+	var err error
+	switch alg {
+	case AlgES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, xerrors.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/memoio/xspace-server/config"
+)
+
+// testClaims returns the claims of a valid access token.
+func testClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		Type: AccessToken,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Audience:  jwt.ClaimStrings{"xspace-api"},
+			Issuer:    "xspace",
+			Subject:   testAddress,
+		},
+	}
+}
+
+// writePublicKey writes the public key of the PEM private key file into a
+// PEM file.
+func writePublicKey(t *testing.T, privateKeyFile string) string {
+	t.Helper()
+	block, err := readPEM(privateKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(privateKey.(crypto.Signer).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeyID(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+
+	token, err := c.keys.sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.verifyJsonWebToken("Bearer "+token, AccessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same key under another kid
+	for _, kid := range []interface{}{"k2", nil, 1} {
+		forged := jwt.NewWithClaims(jwt.SigningMethodES256, testClaims())
+		forged.Header["kid"] = kid
+		token, err := forged.SignedString(c.keys.active.privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.verifyJsonWebToken("Bearer "+token, AccessToken); err != ErrValidToken {
+			t.Errorf("token with kid %v: %v, expected ErrValidToken", kid, err)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	cfg := testAuthConfig(t)
+	oldKey := cfg.Keys[0]
+	c, _, _ := newTestController(t, cfg)
+	oldToken, err := c.keys.sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key signs, the old one only verifies
+	newKey := config.JWTKeyConfig{ID: "k2", Algorithm: AlgEdDSA, PrivateKeyFile: writeKey(t, AlgEdDSA)}
+	cfg.ActiveKey = "k2"
+	cfg.Keys = []config.JWTKeyConfig{{ID: "k1", Algorithm: AlgES256, PublicKeyFile: writePublicKey(t, oldKey.PrivateKeyFile)}, newKey}
+	c, _, _ = newTestController(t, cfg)
+	if _, err := c.verifyJsonWebToken("Bearer "+oldToken, AccessToken); err != nil {
+		t.Fatalf("token of the old key: %v", err)
+	}
+	newToken, err := c.keys.sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "k2" || token.Method != jwt.SigningMethodEdDSA {
+		t.Fatalf("new token signed by %v with %s", token.Header["kid"], token.Method.Alg())
+	}
+
+	// a key without a private key can't be the active one
+	cfg.ActiveKey = "k1"
+	if _, err := NewKeySet(cfg); err == nil {
+		t.Fatal("key without a private key is active")
+	}
+
+	cfg.ActiveKey = "k2"
+	cfg.Keys = []config.JWTKeyConfig{newKey}
+	c, _, _ = newTestController(t, cfg)
+	if _, err := c.verifyJsonWebToken("Bearer "+oldToken, AccessToken); err != ErrValidToken {
+		t.Fatalf("token of the removed key: %v, expected ErrValidToken", err)
+	}
+	if _, err := c.verifyJsonWebToken("Bearer "+newToken, AccessToken); err != nil {
+		t.Fatalf("token of the new key: %v", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	cfg := testAuthConfig(t)
+	cfg.Keys = append(cfg.Keys, config.JWTKeyConfig{ID: "a", Algorithm: AlgEdDSA, PrivateKeyFile: writeKey(t, AlgEdDSA)})
+	ks, err := NewKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "a" || jwks.Keys[1].Kid != "k1" {
+		t.Fatalf("jwks %+v", jwks)
+	}
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	okp := jwks.Keys[0]
+	if okp.Kty != "OKP" || okp.Crv != "Ed25519" || okp.Alg != AlgEdDSA || okp.Use != "sig" || okp.Y != "" {
+		t.Fatalf("ed25519 jwk %+v", okp)
+	}
+	if !ed25519.PublicKey(decode(okp.X)).Equal(ks.keys["a"].publicKey) {
+		t.Fatal("ed25519 jwk doesn't hold the public key")
+	}
+
+	ec := jwks.Keys[1]
+	if ec.Kty != "EC" || ec.Crv != "P-256" || ec.Alg != AlgES256 || ec.Use != "sig" {
+		t.Fatalf("ec jwk %+v", ec)
+	}
+	x, y := decode(ec.X), decode(ec.Y)
+	publicKey := ks.keys["k1"].publicKey.(*ecdsa.PublicKey)
+	if len(x) != 32 || len(y) != 32 || new(big.Int).SetBytes(x).Cmp(publicKey.X) != 0 || new(big.Int).SetBytes(y).Cmp(publicKey.Y) != 0 {
+		t.Fatal("ec jwk doesn't hold the public key")
+	}
+}
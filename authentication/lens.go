@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/xerrors"
+)
+
+var ErrProfileNotFound = xerrors.New("lens profile not found")
+
+// LensProfiles finds the owners of lens profiles.
+type LensProfiles interface {
+	// OwnerOf returns ErrProfileNotFound if the profile doesn't exist
+	OwnerOf(ctx context.Context, profileID *big.Int) (common.Address, error)
+}
+
+// lensProvider signs in lens profiles, the challenge is a siwe message
+// signed by the profile's owner.
+type lensProvider struct {
+	c        *AuthController
+	profiles LensProfiles
+}
+
+func NewLensProvider(c *AuthController, profiles LensProfiles) LoginProvider {
+	return &lensProvider{c: c, profiles: profiles}
+}
+
+func (p *lensProvider) Challenge(ctx context.Context, request ChallengeRequest) (string, error) {
+	challenge, err := p.c.newChallenge(ProviderLens, request.Origin)
+	if err != nil {
+		return "", err
+	}
+
+	profileID, ok := new(big.Int).SetString(request.ProfileID, 0)
+	if !ok || profileID.Sign() <= 0 {
+		return "", invalidMessage("invalid lens profile id %q", request.ProfileID)
+	}
+	if request.ChainID <= 0 {
+		return "", invalidMessage("invalid chain id %d", request.ChainID)
+	}
+
+	owner, err := p.ownerOf(ctx, profileID)
+	if err != nil {
+		return "", err
+	}
+	challenge.Subject = fmt.Sprintf("%#x", profileID)
+	challenge.Address = owner.Hex()
+	challenge.ChainID = request.ChainID
+
+	return p.c.siweMessage(challenge, lensStatement(challenge.Subject))
+}
+
+func (p *lensProvider) Verify(ctx context.Context, request LoginRequest) (*Identity, error) {
+	message, challenge, err := p.c.verifyMessage(ProviderLens, request.Message)
+	if err != nil {
+		return nil, err
+	}
+	if message.GetStatement() == nil || *message.GetStatement() != lensStatement(challenge.Subject) {
+		return nil, invalidMessage("statement doesn't match the challenge")
+	}
+
+	publicKey, err := p.c.verifySignature(ctx, message.GetAddress(), request.Message, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// the profile may have been transferred since the challenge
+	profileID, _ := new(big.Int).SetString(challenge.Subject, 0)
+	owner, err := p.ownerOf(ctx, profileID)
+	if err != nil {
+		return nil, err
+	}
+	if owner.Hex() != challenge.Address {
+		return nil, invalidMessage("lens profile %s is no longer owned by %s", challenge.Subject, challenge.Address)
+	}
+
+	return &Identity{
+		Provider:  ProviderLens,
+		Subject:   challenge.Subject,
+		Address:   challenge.Address,
+		ChainID:   challenge.ChainID,
+		PublicKey: publicKey,
+	}, nil
+}
+
+func (p *lensProvider) ownerOf(ctx context.Context, profileID *big.Int) (common.Address, error) {
+	owner, err := p.profiles.OwnerOf(ctx, profileID)
+	if xerrors.Is(err, ErrProfileNotFound) {
+		return common.Address{}, invalidMessage("lens profile %#x not found", profileID)
+	}
+	return owner, err
+}
+
+func lensStatement(profileID string) string {
+	return fmt.Sprintf("%s with Lens profile %s", purposeStatement, profileID)
+}
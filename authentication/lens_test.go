@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// memoryProfiles maps the lens profile ids to their owners.
+type memoryProfiles map[string]common.Address
+
+func (p memoryProfiles) OwnerOf(ctx context.Context, profileID *big.Int) (common.Address, error) {
+	owner, ok := p[profileID.String()]
+	if !ok {
+		return common.Address{}, ErrProfileNotFound
+	}
+	return owner, nil
+}
+
+func TestLensLogin(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	owner, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	profiles := memoryProfiles{"1": crypto.PubkeyToAddress(owner.PublicKey)}
+	c.RegisterProvider(ProviderLens, NewLensProvider(c, profiles))
+
+	for _, tc := range []struct {
+		name   string
+		signer *ecdsa.PrivateKey
+		tamper func(string) string
+		// a part of the rejection, empty if the login is valid
+		reason string
+	}{
+		{"valid", owner, nil, ""},
+		{"wrong signer", other, nil, ErrInvalidSignature.Message},
+		{"other profile", owner, func(m string) string { return strings.Replace(m, "Lens profile 0x1", "Lens profile 0x2", 1) }, "statement"},
+		{"tampered message", owner, func(m string) string { return strings.Replace(m, "Chain ID: 1", "Chain ID: 2", 1) }, "chain id"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			message, err := c.Challenge(context.Background(), ChallengeRequest{Provider: ProviderLens, Origin: "https://xspace.io", ProfileID: "1", ChainID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature := signMessage(t, tc.signer, message)
+			if tc.tamper != nil {
+				message = tc.tamper(message)
+			}
+
+			identity, err := c.providers[ProviderLens].Verify(context.Background(), LoginRequest{Provider: ProviderLens, Message: message, Signature: signature})
+			if tc.reason != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.reason) {
+					t.Fatalf("%v, expected a rejection for %q", err, tc.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Provider != ProviderLens || identity.Subject != "0x1" || identity.Address != profiles["1"].Hex() || identity.PublicKey == nil {
+				t.Fatalf("identity %+v", identity)
+			}
+		})
+	}
+}
+
+func TestLensProfileTransferred(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	owner, _ := crypto.GenerateKey()
+	profiles := memoryProfiles{"1": crypto.PubkeyToAddress(owner.PublicKey)}
+	c.RegisterProvider(ProviderLens, NewLensProvider(c, profiles))
+
+	if _, err := c.Challenge(context.Background(), ChallengeRequest{Provider: ProviderLens, Origin: "https://xspace.io", ProfileID: "2", ChainID: 1}); err == nil {
+		t.Fatal("challenge for an unknown profile")
+	}
+
+	message, err := c.Challenge(context.Background(), ChallengeRequest{Provider: ProviderLens, Origin: "https://xspace.io", ProfileID: "1", ChainID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles["1"] = common.HexToAddress(testAddress)
+	_, err = c.providers[ProviderLens].Verify(context.Background(), LoginRequest{Provider: ProviderLens, Message: message, Signature: signMessage(t, owner, message)})
+	if err == nil || !strings.Contains(err.Error(), "no longer owned") {
+		t.Fatalf("profile transferred after the challenge: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mr-tron/base58"
+	"golang.org/x/xerrors"
+)
+
+// the provider of link challenges, no one logs in with it
+const providerLink = "link"
+
+var ErrWalletLinked = xerrors.New("the wallet belongs to another user with other wallets")
+
+var linkNonce = regexp.MustCompile(`(?m)^Nonce: ([0-9a-f]+)$`)
+
+type LinkRequest struct {
+	Message string `json:"message,omitempty"`
+	// signature of the wallet the user is logged in with
+	Signature string `json:"signature,omitempty"`
+	// signature of the wallet being linked
+	LinkSignature string `json:"linkSignature,omitempty"`
+}
+
+// LinkChallenge returns the message both the wallet the user is logged in
+// with and the wallet being linked sign to link them.
+func (c *AuthController) LinkChallenge(origin, address, linkAddress string) (string, error) {
+	challenge, err := c.newChallenge(providerLink, origin)
+	if err != nil {
+		return "", err
+	}
+
+	_, linkAddress, err = parseWallet(linkAddress)
+	if err != nil {
+		return "", err
+	}
+	if linkAddress == address {
+		return "", invalidMessage("wallet %s is the one logged in", linkAddress)
+	}
+	challenge.Subject = address
+	challenge.Address = linkAddress
+
+	nonce, err := c.GetNonce(challenge)
+	if err != nil {
+		return "", err
+	}
+	return linkMessage(challenge, nonce), nil
+}
+
+// LinkWallet verifies the link challenge signed by both wallets and adds
+// the linked wallet to the user logged in with address.
+func (c *AuthController) LinkWallet(ctx context.Context, userID, address string, request LinkRequest) (*Identity, error) {
+	if userID == "" {
+		// a token issued before users existed
+		return nil, ErrValidToken
+	}
+
+	message := strings.TrimSpace(request.Message)
+	match := linkNonce.FindStringSubmatch(message)
+	if match == nil {
+		return nil, invalidMessage("malformed link message")
+	}
+
+	challenge, ok, err := c.VerifyNonce(match[1])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, invalidMessage("nonce is unknown, used or expired")
+	}
+	if challenge.Provider != providerLink {
+		return nil, invalidMessage("nonce was issued for %s login", challenge.Provider)
+	}
+	if challenge.Subject != address {
+		return nil, invalidMessage("challenge was issued to %s", challenge.Subject)
+	}
+	if message != linkMessage(&challenge, match[1]) {
+		return nil, invalidMessage("message doesn't match the challenge")
+	}
+	if !c.allowedDomain(challenge.Domain) {
+		return nil, invalidMessage("domain %s is not allowed", challenge.Domain)
+	}
+
+	if _, err := c.verifyWallet(ctx, address, message, request.Signature); err != nil {
+		return nil, err
+	}
+	provider, _, _ := parseWallet(challenge.Address)
+	publicKey, err := c.verifyWallet(ctx, challenge.Address, message, request.LinkSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider:  provider,
+		Subject:   challenge.Address,
+		Address:   challenge.Address,
+		PublicKey: publicKey,
+	}
+	if identity.PublicKey != nil && c.keyStore != nil {
+		err = c.keyStore.SavePublicKey(identity.Address, identity.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return identity, c.users.LinkIdentity(userID, *identity)
+}
+
+// verifyWallet checks the signature of an ethereum or solana wallet.
+func (c *AuthController) verifyWallet(ctx context.Context, address, message, signature string) ([]byte, error) {
+	provider, address, err := parseWallet(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if provider == ProviderEthereum {
+		return c.verifySignature(ctx, common.HexToAddress(address), message, signature)
+	}
+
+	publicKey, _ := base58.Decode(address)
+	sig, err := base58.Decode(signature)
+	if err != nil || !ed25519.Verify(publicKey, []byte(message), sig) {
+		return nil, ErrInvalidSignature
+	}
+	return nil, nil
+}
+
+// parseWallet returns the provider of a hex ethereum or base58 solana
+// address and the address in its canonical form.
+func parseWallet(address string) (string, string, error) {
+	if common.IsHexAddress(address) {
+		return ProviderEthereum, common.HexToAddress(address).Hex(), nil
+	}
+
+	publicKey, err := base58.Decode(address)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return "", "", invalidMessage("invalid wallet address %q", address)
+	}
+	return ProviderSolana, base58.Encode(publicKey), nil
+}
+
+func linkMessage(challenge *Challenge, nonce string) string {
+	return fmt.Sprintf("%s wants you to link a wallet to your xspace account:\n%s\n\nThe message is only used to link %s to the account of %s\n\nURI: %s\nVersion: 1\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		challenge.Domain, challenge.Address, challenge.Address, challenge.Subject, challenge.URI, nonce,
+		challenge.IssuedAt.UTC().Format(time.RFC3339), challenge.ExpireAt.UTC().Format(time.RFC3339))
+}
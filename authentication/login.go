@@ -1,135 +1,177 @@
 package auth
 
 import (
-	"encoding/hex"
+	"context"
+	"crypto/ecdsa"
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/spruceid/siwe-go"
-	"golang.org/x/xerrors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/memoio/xspace-server/config"
 )
 
 var purposeStatement = "The message is only used for login"
 
-type EIP4361Request struct {
-	EIP191Message string `json:"message,omitempty"`
-	Signature     string `json:"signature,omitempty"`
-
-	// // used for registe
-	// Recommender string `json:"recommender,omitempty"`
-	// Source      string `json:"source,omitempty"`
-}
-
 var (
-	ErrNullToken      = xerrors.New("Token is Null, not found in `Authorization: Bearer ` header")
-	ErrValidToken     = xerrors.New("Invalid token")
-	ErrValidTokenType = xerrors.New("InValid token type")
+	ErrNullToken        = &TokenError{Code: "TOKEN_MISSING", Message: "Token is Null, not found in `Authorization: Bearer ` header"}
+	ErrTokenMalformed   = &TokenError{Code: "TOKEN_MALFORMED", Message: "Malformed token"}
+	ErrTokenExpired     = &TokenError{Code: "TOKEN_EXPIRED", Message: "Token is expired"}
+	ErrTokenNotValidYet = &TokenError{Code: "TOKEN_NOT_VALID_YET", Message: "Token is not valid yet"}
+	ErrValidToken       = &TokenError{Code: "TOKEN_INVALID", Message: "Invalid token"}
+	ErrValidTokenType   = &TokenError{Code: "TOKEN_WRONG_TYPE", Message: "InValid token type"}
+	ErrInvalidSignature = &TokenError{Code: "SIGNATURE_INVALID", Message: "Got wrong address/signature"}
 
 	// ChainID = 985
 	Version = 1
 
-	JWTKey []byte
-
 	DidToken     = 0
 	AccessToken  = 1
 	RefreshToken = 2
-
-	LensMod = 0x10
-	EthMod  = 0x11
 )
 
-func InitAuthConfig(jwtKey string, domain string, url string) {
-	var err error
-	JWTKey, err = hex.DecodeString(jwtKey)
-	if err != nil {
-		JWTKey = []byte("memo.io")
-	}
+// PublicKeyStore records the public key recovered from a login signature.
+type PublicKeyStore interface {
+	SavePublicKey(address string, publicKey []byte) error
+}
+
+// WalletVerifier checks signatures of smart contract wallets (EIP-1271),
+// including undeployed ones (EIP-6492), on the chain the server is
+// connected to.
+type WalletVerifier interface {
+	IsValidSignature(ctx context.Context, account common.Address, hash common.Hash, signature []byte) (bool, error)
 }
 
 type AuthController struct {
 	*NonceManager
-	jwtKey []byte
+	keys     *KeySet
+	parser   *jwt.Parser
+	issuer   string
+	audience string
+	// domains the frontends are served on, challenges are only issued to
+	// them
+	allowedDomains []string
+	keyStore       PublicKeyStore
+	sessions       SessionStore
+	users          UserStore
+	wallets        WalletVerifier
+	providers      map[string]LoginProvider
 }
 
-func NewAuthController(jwtKey string) (*AuthController, error) {
-	JWTKey, err := hex.DecodeString(jwtKey)
+// NewAuthController creates the controller with the ethereum and solana
+// login providers, wallets may be nil to only accept signatures of
+// externally owned accounts.
+func NewAuthController(cfg config.AuthConfig, nonces NonceStore, keyStore PublicKeyStore, sessions SessionStore, users UserStore, wallets WalletVerifier) (*AuthController, error) {
+	keys, err := NewKeySet(cfg)
 	if err != nil {
-		JWTKey = []byte("memo.io")
+		return nil, err
 	}
-	return &AuthController{
-		NonceManager: NewNonceManager(30*int64(time.Second.Seconds()), 1*int64(time.Minute.Seconds())),
-		jwtKey:       JWTKey,
-	}, nil
+	c := &AuthController{
+		NonceManager:   NewNonceManager(nonces, time.Duration(cfg.NonceTTL)*time.Second),
+		keys:           keys,
+		parser:         newParser(cfg, keys),
+		issuer:         cfg.Issuer,
+		audience:       cfg.Audience,
+		allowedDomains: cfg.AllowedDomains,
+		keyStore:       keyStore,
+		sessions:       sessions,
+		users:          users,
+		wallets:        wallets,
+		providers:      make(map[string]LoginProvider),
+	}
+	c.RegisterProvider(ProviderEthereum, &ethProvider{c})
+	c.RegisterProvider(ProviderSolana, &solanaProvider{c})
+	return c, nil
 }
 
-func (c *AuthController) Challenge(domain, address, uri string, chainID int) (string, error) {
-	var opt = map[string]interface{}{
-		"chainId":   chainID,
-		"statement": purposeStatement,
-	}
-	msg, err := siwe.InitMessage(domain, address, uri, c.GetNonce(), opt)
+// JWKS returns the public keys verifying the tokens issued by the
+// controller.
+func (c *AuthController) JWKS() JWKS {
+	return c.keys.JWKS()
+}
+
+// Challenge returns the message the user signs to log in with the
+// request's provider.
+func (c *AuthController) Challenge(ctx context.Context, request ChallengeRequest) (string, error) {
+	provider, err := c.provider(request.Provider)
 	if err != nil {
 		return "", err
 	}
-	return msg.String(), nil
+	return provider.Challenge(ctx, request)
 }
 
-func (c *AuthController) Login(request interface{}) (string, string, error) {
-	req, ok := request.(EIP4361Request)
-	if !ok {
-		return "", "", fmt.Errorf("")
+// Login verifies the signed challenge and starts a session of the user the
+// identity belongs to, the access token and refresh token are returned.
+func (c *AuthController) Login(ctx context.Context, request LoginRequest, userAgent string) (string, string, error) {
+	provider, err := c.provider(request.Provider)
+	if err != nil {
+		return "", "", err
 	}
-	return c.loginWithEth(req)
-}
 
-func (c *AuthController) loginWithEth(request EIP4361Request) (string, string, error) {
-	message, err := parseLensMessage(request.EIP191Message)
+	identity, err := provider.Verify(ctx, request)
 	if err != nil {
 		return "", "", err
 	}
 
-	// if message.GetChainID() != ChainID {
-	// 	return "", "", "", logs.AuthenticationFailed{Message: "Got wrong chain id"}
-	// }
-
-	if !c.VerifyNonce(message.GetNonce()) {
-		return "", "", xerrors.New("Got wrong nonce")
+	if identity.PublicKey != nil && c.keyStore != nil {
+		err = c.keyStore.SavePublicKey(identity.Address, identity.PublicKey)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
-	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(request.EIP191Message), request.EIP191Message)))
-	sig, err := hexutil.Decode(request.Signature)
+	userID, err := c.users.ResolveUser(*identity)
 	if err != nil {
 		return "", "", err
 	}
 
-	sig[len(sig)-1] %= 27
-	pubKey, err := crypto.SigToPub(hash, sig)
+	return c.newSession(identity, userID, userAgent)
+}
+
+// verifySignature checks that address signed the EIP-191 message, as an
+// externally owned account or as a contract wallet. The public key is
+// returned if the signature is an ECDSA one.
+func (c *AuthController) verifySignature(ctx context.Context, address common.Address, message, signature string) ([]byte, error) {
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+	sig, err := hexutil.Decode(signature)
 	if err != nil {
-		return "", "", err
+		return nil, ErrInvalidSignature
 	}
 
-	if message.GetAddress().Hex() != crypto.PubkeyToAddress(*pubKey).Hex() {
-		return "", "", xerrors.New("Got wrong address/signature")
+	pubKey := recoverPubKey(hash, sig)
+	if pubKey != nil && address == crypto.PubkeyToAddress(*pubKey) {
+		return crypto.FromECDSAPub(pubKey), nil
 	}
 
-	accessToken, err := genAccessToken(message.GetAddress().Hex(), message.GetChainID())
+	// not signed by an externally owned account, try a contract wallet
+	if c.wallets == nil {
+		return nil, ErrInvalidSignature
+	}
+	ok, err := c.wallets.IsValidSignature(ctx, address, common.BytesToHash(hash), sig)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-
-	refreshToken, err := genRefreshToken(message.GetAddress().Hex(), message.GetChainID())
-
-	return accessToken, refreshToken, err
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+	// a contract wallet has no public key to wrap object keys to
+	return nil, nil
 }
 
-func parseLensMessage(message string) (*siwe.Message, error) {
-	message = strings.TrimPrefix(message, "\n")
-	message = strings.TrimPrefix(message, "https://")
-	message = strings.TrimPrefix(message, "http://")
-	message = strings.TrimSuffix(message, "\n ")
+// recoverPubKey returns the public key of an ECDSA signature, or nil if the
+// signature isn't one.
+func recoverPubKey(hash, sig []byte) *ecdsa.PublicKey {
+	if len(sig) != crypto.SignatureLength {
+		return nil
+	}
 
-	return siwe.ParseMessage(message)
+	sig = append([]byte{}, sig...)
+	sig[len(sig)-1] %= 27
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return nil
+	}
+	return pubKey
 }
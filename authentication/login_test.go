@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/memoio/xspace-server/config"
+)
+
+// writeKey generates a private key of the algorithm into a PEM file.
+func writeKey(t *testing.T, alg string) string {
+	t.Helper()
+	pem, err := GenerateKey(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(path, pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testAuthConfig(t *testing.T) config.AuthConfig {
+	return config.AuthConfig{
+		ActiveKey:      "k1",
+		Keys:           []config.JWTKeyConfig{{ID: "k1", Algorithm: AlgES256, PrivateKeyFile: writeKey(t, AlgES256)}},
+		Issuer:         "xspace",
+		Audience:       "xspace-api",
+		NonceTTL:       300,
+		AllowedDomains: []string{"xspace.io"},
+	}
+}
+
+func newTestController(t *testing.T, cfg config.AuthConfig) (*AuthController, *memorySessions, *memoryUsers) {
+	t.Helper()
+	sessions := &memorySessions{sessions: make(map[string]Session)}
+	users := &memoryUsers{roles: make(map[string]string), banned: make(map[string]bool)}
+	c, err := NewAuthController(cfg, NewMemoryNonceStore(), nil, sessions, users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, sessions, users
+}
+
+// memorySessions is a SessionStore in memory.
+type memorySessions struct {
+	lock     sync.Mutex
+	sessions map[string]Session
+}
+
+func (s *memorySessions) CreateSession(session *Session) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+func (s *memorySessions) GetSession(id string) (Session, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *memorySessions) RotateSession(id, oldTokenID, newTokenID string, expireAt time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || session.Revoked || session.CurrentTokenID != oldTokenID {
+		return ErrSessionReused
+	}
+	session.CurrentTokenID = newTokenID
+	session.RefreshedAt = time.Now()
+	session.ExpireAt = expireAt
+	s.sessions[id] = session
+	return nil
+}
+
+func (s *memorySessions) RevokeSession(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	session.Revoked = true
+	s.sessions[id] = session
+	return nil
+}
+
+func (s *memorySessions) ListSessions(userID, address string) ([]Session, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var sessions []Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && (userID != "" || session.Address == address) && !session.Revoked {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// memoryUsers is a UserStore in memory, the user of an identity is its
+// address.
+type memoryUsers struct {
+	lock   sync.Mutex
+	roles  map[string]string
+	banned map[string]bool
+}
+
+func (u *memoryUsers) ResolveUser(identity Identity) (string, error) {
+	return identity.Address, nil
+}
+
+func (u *memoryUsers) LinkIdentity(userID string, identity Identity) error {
+	return nil
+}
+
+func (u *memoryUsers) GetRole(address string) (string, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if role, ok := u.roles[address]; ok {
+		return role, nil
+	}
+	return RoleUser, nil
+}
+
+func (u *memoryUsers) IsBanned(userID string) (bool, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.banned[userID], nil
+}
@@ -5,90 +5,131 @@ import (
 	"encoding/hex"
 	"sync"
 	"time"
-
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
-type NonceManager struct {
-	handledNonce  *sync.Map
-	handlingNonce *sync.Map
-	modifyMutex   sync.Mutex
+// Challenge records what a nonce was issued for, the signed message must
+// match it.
+type Challenge struct {
+	// the login provider and the identity within it
+	Provider string
+	Subject  string
+	Domain   string
+	URI      string
+	Address  string
+	ChainID  int
+	IssuedAt time.Time
+	ExpireAt time.Time
+}
 
-	ExpireEpoch    int64
-	ModifyEpoch    int64
-	LastModifyTime int64
+// NonceStore keeps the nonces issued in challenges until they are used or
+// expire. A store shared by all replicas lets a challenge issued by one
+// replica be verified by another.
+type NonceStore interface {
+	PutNonce(nonce string, challenge Challenge) error
+	// ConsumeNonce removes the nonce and returns its challenge if it was
+	// issued and has not expired. A nonce can be consumed only once.
+	ConsumeNonce(nonce string) (Challenge, bool, error)
 }
 
-func NewNonceManager(expireEpoch int64, modifyEpoch int64) *NonceManager {
-	return &NonceManager{
-		handlingNonce:  new(sync.Map),
-		handledNonce:   new(sync.Map),
-		ExpireEpoch:    expireEpoch,
-		ModifyEpoch:    modifyEpoch,
-		LastModifyTime: time.Now().Unix(),
-	}
+type NonceManager struct {
+	store NonceStore
+	ttl   time.Duration
 }
 
-func (non *NonceManager) GetNonce() string {
-	now := time.Now().Unix()
-	if now-non.LastModifyTime >= non.ModifyEpoch {
-		non.clearExpiredNonce()
+func NewNonceManager(store NonceStore, ttl time.Duration) *NonceManager {
+	return &NonceManager{
+		store: store,
+		ttl:   ttl,
 	}
+}
 
+// GetNonce issues a nonce for the challenge, its IssuedAt and ExpireAt are
+// set here.
+func (non *NonceManager) GetNonce(challenge *Challenge) (string, error) {
 	b := make([]byte, 16)
 	_, err := rand.Read(b)
 	if err != nil {
-		return ""
+		return "", err
 	}
 
-	nonce := hex.EncodeToString(crypto.Keccak256(b, []byte(time.Now().String())))
-	non.handlingNonce.Store(nonce, time.Now().Unix()+non.ExpireEpoch)
+	// siwe messages carry seconds only
+	challenge.IssuedAt = time.Now().UTC().Truncate(time.Second)
+	challenge.ExpireAt = challenge.IssuedAt.Add(non.ttl)
+
+	nonce := hex.EncodeToString(b)
+	if err := non.store.PutNonce(nonce, *challenge); err != nil {
+		return "", err
+	}
 
-	return nonce
+	return nonce, nil
 }
 
-func (non *NonceManager) VerifyNonce(nonce string) bool {
+func (non *NonceManager) VerifyNonce(nonce string) (Challenge, bool, error) {
 	if nonce == "" {
-		return false
+		return Challenge{}, false, nil
 	}
 
-	now := time.Now().Unix()
-	if now-non.LastModifyTime >= non.ModifyEpoch {
-		non.clearExpiredNonce()
-	}
+	return non.store.ConsumeNonce(nonce)
+}
 
-	expireTime, ok := non.handlingNonce.Load(nonce)
-	if ok {
-		non.handlingNonce.Delete(nonce)
-		expireTimeInt, _ := expireTime.(int64)
-		if now < expireTimeInt {
-			return true
-		} else {
-			return false
-		}
+// MemoryNonceStore keeps nonces in process memory, it only works with a
+// single replica.
+type MemoryNonceStore struct {
+	lock      sync.Mutex
+	nonces    map[string]Challenge
+	lastSweep time.Time
+}
+
+var _ NonceStore = (*MemoryNonceStore)(nil)
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:    make(map[string]Challenge),
+		lastSweep: time.Now(),
 	}
+}
 
-	if time.Now().Unix()-non.LastModifyTime < non.ExpireEpoch {
-		expireTime, ok = non.handledNonce.Load(nonce)
-		if ok {
-			non.handledNonce.Delete(nonce)
-			expireTimeInt, _ := expireTime.(int64)
-			if now < expireTimeInt {
-				return true
-			}
-		}
+func (s *MemoryNonceStore) PutNonce(nonce string, challenge Challenge) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sweep()
+	s.nonces[nonce] = challenge
+	return nil
+}
+
+func (s *MemoryNonceStore) ConsumeNonce(nonce string) (Challenge, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	challenge, ok := s.nonces[nonce]
+	if !ok {
+		return Challenge{}, false, nil
 	}
+	delete(s.nonces, nonce)
+	return challenge, time.Now().Before(challenge.ExpireAt), nil
+}
 
-	return false
+// Len returns the number of nonces kept, including expired ones not swept
+// yet.
+func (s *MemoryNonceStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.nonces)
 }
 
-func (non *NonceManager) clearExpiredNonce() {
-	now := time.Now().Unix()
-	non.modifyMutex.Lock()
-	defer non.modifyMutex.Unlock()
-	if now-non.LastModifyTime >= non.ModifyEpoch {
-		non.handledNonce = non.handlingNonce
-		non.handlingNonce = new(sync.Map)
-		non.LastModifyTime = now
+// sweep drops the expired nonces at most once a minute, the caller must
+// hold the lock.
+func (s *MemoryNonceStore) sweep() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for nonce, challenge := range s.nonces {
+		if !now.Before(challenge.ExpireAt) {
+			delete(s.nonces, nonce)
+		}
 	}
 }
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStoreConsume(t *testing.T) {
+	store := NewMemoryNonceStore()
+	manager := NewNonceManager(store, time.Minute)
+
+	challenge := Challenge{Provider: "siwe", Address: "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"}
+	nonce, err := manager.GetNonce(&challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge.ExpireAt.Sub(challenge.IssuedAt) != time.Minute {
+		t.Fatalf("issued at %v, expires at %v", challenge.IssuedAt, challenge.ExpireAt)
+	}
+
+	got, ok, err := manager.VerifyNonce(nonce)
+	if err != nil || !ok || got != challenge {
+		t.Fatalf("verify: %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, err := manager.VerifyNonce(nonce); err != nil || ok {
+		t.Fatalf("second verify: %v, %v", ok, err)
+	}
+	if _, ok, err := manager.VerifyNonce(""); err != nil || ok {
+		t.Fatalf("empty nonce: %v, %v", ok, err)
+	}
+}
+
+func TestMemoryNonceStoreConsumeConcurrent(t *testing.T) {
+	store := NewMemoryNonceStore()
+	challenge := Challenge{ExpireAt: time.Now().Add(time.Minute)}
+
+	for i := 0; i < 10; i++ {
+		nonce := fmt.Sprintf("concurrent%d", i)
+		if err := store.PutNonce(nonce, challenge); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		var consumed atomic.Int32
+		for j := 0; j < 16; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, ok, _ := store.ConsumeNonce(nonce); ok {
+					consumed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if n := consumed.Load(); n != 1 {
+			t.Fatalf("nonce consumed %d times", n)
+		}
+	}
+}
+
+func TestMemoryNonceStoreExpiry(t *testing.T) {
+	store := NewMemoryNonceStore()
+	if err := store.PutNonce("expired", Challenge{ExpireAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := store.ConsumeNonce("expired"); err != nil || ok {
+		t.Fatalf("expired nonce: %v, %v", ok, err)
+	}
+	if store.Len() != 0 {
+		t.Fatalf("%d nonces kept after consuming the expired one", store.Len())
+	}
+}
+
+func TestMemoryNonceStoreSweep(t *testing.T) {
+	store := NewMemoryNonceStore()
+	for i := 0; i < 3; i++ {
+		if err := store.PutNonce(fmt.Sprintf("old%d", i), Challenge{ExpireAt: time.Now().Add(-time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.Len() != 3 {
+		t.Fatalf("%d nonces before the sweep", store.Len())
+	}
+
+	store.lock.Lock()
+	store.lastSweep = time.Now().Add(-time.Minute)
+	store.lock.Unlock()
+
+	if err := store.PutNonce("new", Challenge{ExpireAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 1 {
+		t.Fatalf("%d nonces after the sweep", store.Len())
+	}
+	if _, ok, err := store.ConsumeNonce("new"); err != nil || !ok {
+		t.Fatalf("consume after the sweep: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+)
+
+// Login providers, the provider of a request defaults to ethereum.
+const (
+	ProviderEthereum = "ethereum"
+	ProviderLens     = "lens"
+	ProviderSolana   = "solana"
+)
+
+// LoginProvider signs in users with one kind of identity. It issues the
+// challenge message the identity signs and verifies the signed message.
+type LoginProvider interface {
+	Challenge(ctx context.Context, request ChallengeRequest) (string, error)
+	// Verify checks the signed challenge and returns the identity that
+	// signed it.
+	Verify(ctx context.Context, request LoginRequest) (*Identity, error)
+}
+
+type ChallengeRequest struct {
+	Provider string
+	// Origin of the frontend, its host must be an allowed domain
+	Origin string
+	// the wallet signing the challenge, unused by lens
+	Address string
+	// the lens profile id
+	ProfileID string
+	ChainID   int
+}
+
+type LoginRequest struct {
+	Provider  string `json:"provider,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Identity is who signed a login challenge.
+type Identity struct {
+	Provider string
+	// the id of the identity within the provider: the address of a wallet
+	// or the id of a lens profile
+	Subject string
+	// the wallet holding the points and NFTs, the owner of a lens profile
+	Address string
+	ChainID int
+	// the secp256k1 public key recovered from the signature, nil if it
+	// isn't an ECDSA signature
+	PublicKey []byte
+}
+
+// UserStore maps identities to xspace users. Identities sharing a wallet
+// address, such as a lens profile and the wallet owning it, belong to the
+// same user.
+type UserStore interface {
+	// ResolveUser returns the id of the identity's user, the user is created
+	// on the identity's first login.
+	ResolveUser(identity Identity) (string, error)
+	// LinkIdentity adds a wallet to the user, with the lens profiles it
+	// owns. A wallet whose user has other wallets returns ErrWalletLinked,
+	// one whose user is banned ErrUserBanned.
+	LinkIdentity(userID string, identity Identity) error
+	// GetRole returns the role of the address, RoleUser if it has none.
+	GetRole(address string) (string, error)
+	IsBanned(userID string) (bool, error)
+}
+
+// RegisterProvider adds a login provider or replaces the one with the same
+// name.
+func (c *AuthController) RegisterProvider(name string, provider LoginProvider) {
+	c.providers[name] = provider
+}
+
+func (c *AuthController) provider(name string) (LoginProvider, error) {
+	if name == "" {
+		name = ProviderEthereum
+	}
+	provider, ok := c.providers[name]
+	if !ok {
+		return nil, invalidMessage("unsupported login provider %q", name)
+	}
+	return provider, nil
+}
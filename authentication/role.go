@@ -0,0 +1,39 @@
+package auth
+
+// Roles of addresses, a role includes the permissions of the roles below it.
+const (
+	RoleUser     = "user"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+var ErrUserBanned = &TokenError{Code: "USER_BANNED", Message: "the user is banned"}
+
+var roleRanks = map[string]int{
+	RoleUser:     0,
+	RoleOperator: 1,
+	RoleAdmin:    2,
+}
+
+// ValidRole reports whether role is one of the roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether role grants the permissions of required, an empty
+// role is RoleUser.
+func HasRole(role, required string) bool {
+	if role == "" {
+		role = RoleUser
+	}
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// CanonicalAddress returns the checksummed form of an ethereum address or
+// the base58 form of a solana one.
+func CanonicalAddress(address string) (string, error) {
+	_, address, err := parseWallet(address)
+	return address, err
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrSessionRevoked  = &TokenError{Code: "SESSION_REVOKED", Message: "session has been revoked"}
+	ErrSessionReused   = &TokenError{Code: "SESSION_REVOKED", Message: "refresh token has been used, the session is revoked"}
+	ErrSessionNotFound = xerrors.New("session not found")
+)
+
+// Session is a login session, i.e. a family of refresh tokens. Every refresh
+// rotates the family's current refresh token, presenting an older one means
+// the token was stolen and revokes the whole family.
+type Session struct {
+	ID             string
+	UserID         string
+	Address        string
+	Provider       string
+	Subject        string // the lens profile id, or the address
+	ChainID        int
+	CurrentTokenID string
+	UserAgent      string
+	CreatedAt      time.Time
+	RefreshedAt    time.Time
+	ExpireAt       time.Time
+	Revoked        bool
+	// the role of the address, looked up whenever tokens are issued
+	Role string
+}
+
+type SessionStore interface {
+	CreateSession(session *Session) error
+	GetSession(id string) (Session, error)
+	// RotateSession replaces the session's current refresh token oldTokenID
+	// with newTokenID, it returns ErrSessionReused if oldTokenID is not the
+	// current one.
+	RotateSession(id, oldTokenID, newTokenID string, expireAt time.Time) error
+	RevokeSession(id string) error
+	// ListSessions returns the user's sessions that are neither expired nor
+	// revoked, those of the address if the user is empty: a token issued
+	// before users existed.
+	ListSessions(userID, address string) ([]Session, error)
+}
+
+// Refresh verifies the refresh token and rotates it, the new access token
+// and refresh token are returned.
+func (c *AuthController) Refresh(tokenString string) (string, string, error) {
+	claims, err := c.verifyJsonWebToken(tokenString, RefreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.SessionID == "" || claims.ID == "" {
+		return "", "", ErrValidToken
+	}
+
+	session, err := c.sessions.GetSession(claims.SessionID)
+	if err != nil {
+		return "", "", err
+	}
+	if session.Revoked {
+		return "", "", ErrSessionRevoked
+	}
+	if err := c.checkUser(&session); err != nil {
+		return "", "", err
+	}
+
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	expireAt := time.Now().Add(refreshTokenLifetime)
+	err = c.sessions.RotateSession(session.ID, claims.ID, tokenID, expireAt)
+	if xerrors.Is(err, ErrSessionReused) {
+		if err := c.sessions.RevokeSession(session.ID); err != nil {
+			return "", "", err
+		}
+		return "", "", ErrSessionReused
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := c.genAccessTokenWithFlag(&session, claims.IsRegistered)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := c.genRefreshToken(&session, tokenID)
+	return accessToken, refreshToken, err
+}
+
+// VerifyAccessToken verifies the access token and checks that its session
+// has not been revoked. The user of the claims is the session's current
+// one: a wallet linked or unlinked since the token was issued acts for the
+// user it belongs to now.
+func (c *AuthController) VerifyAccessToken(tokenString string) (*Claims, error) {
+	claims, err := c.verifyJsonWebToken(tokenString, AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionID == "" {
+		return nil, ErrValidToken
+	}
+
+	session, err := c.sessions.GetSession(claims.SessionID)
+	if xerrors.Is(err, ErrSessionNotFound) {
+		return nil, ErrValidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.Revoked {
+		return nil, ErrSessionRevoked
+	}
+	claims.UserID = session.UserID
+
+	return claims, nil
+}
+
+// Logout revokes the session.
+func (c *AuthController) Logout(sessionID string) error {
+	return c.sessions.RevokeSession(sessionID)
+}
+
+// ListSessions returns the sessions of all the user's wallets, those of the
+// address if the token was issued before users existed.
+func (c *AuthController) ListSessions(userID, address string) ([]Session, error) {
+	return c.sessions.ListSessions(userID, address)
+}
+
+// RevokeSession revokes one of the user's sessions.
+func (c *AuthController) RevokeSession(userID, address, sessionID string) error {
+	session, err := c.sessions.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID || (userID == "" && session.Address != address) {
+		return ErrSessionNotFound
+	}
+	return c.sessions.RevokeSession(sessionID)
+}
+
+// newSession starts a session and returns its access token and refresh
+// token.
+func (c *AuthController) newSession(identity *Identity, userID, userAgent string) (string, string, error) {
+	sessionID, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:             sessionID,
+		UserID:         userID,
+		Address:        identity.Address,
+		Provider:       identity.Provider,
+		Subject:        identity.Subject,
+		ChainID:        identity.ChainID,
+		CurrentTokenID: tokenID,
+		UserAgent:      userAgent,
+		CreatedAt:      now,
+		RefreshedAt:    now,
+		ExpireAt:       now.Add(refreshTokenLifetime),
+	}
+	if err := c.checkUser(session); err != nil {
+		return "", "", err
+	}
+	err = c.sessions.CreateSession(session)
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := c.genAccessToken(session)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := c.genRefreshToken(session, tokenID)
+	return accessToken, refreshToken, err
+}
+
+// checkUser rejects a banned user and sets the role of the session's
+// address.
+func (c *AuthController) checkUser(session *Session) error {
+	if session.UserID != "" {
+		banned, err := c.users.IsBanned(session.UserID)
+		if err != nil {
+			return err
+		}
+		if banned {
+			return ErrUserBanned
+		}
+	}
+
+	role, err := c.users.GetRole(session.Address)
+	if err != nil {
+		return err
+	}
+	session.Role = role
+	return nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/xerrors"
+)
+
+const testAddress = "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+
+// startSession logs in the address as the user and returns the session id,
+// its access token and refresh token.
+func startSession(t *testing.T, c *AuthController, userID, address string) (string, string, string) {
+	t.Helper()
+	identity := &Identity{Provider: ProviderEthereum, Subject: address, Address: address, ChainID: 1}
+	accessToken, refreshToken, err := c.newSession(identity, userID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := c.VerifyAccessToken("Bearer " + accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims.SessionID, "Bearer " + accessToken, "Bearer " + refreshToken
+}
+
+func TestRefreshRotation(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	sessionID, _, refreshToken := startSession(t, c, "user", testAddress)
+
+	accessToken, newRefreshToken, err := c.Refresh(refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if "Bearer "+newRefreshToken == refreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	claims, err := c.VerifyAccessToken("Bearer " + accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SessionID != sessionID || claims.UserID != "user" || claims.Subject != testAddress {
+		t.Fatalf("refreshed access token has claims %+v", claims)
+	}
+
+	if _, _, err := c.Refresh("Bearer " + newRefreshToken); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshReuse(t *testing.T) {
+	c, sessions, _ := newTestController(t, testAuthConfig(t))
+	sessionID, accessToken, refreshToken := startSession(t, c, "user", testAddress)
+
+	_, newRefreshToken, err := c.Refresh(refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rotated token was stolen, its reuse revokes the whole session
+	if _, _, err := c.Refresh(refreshToken); err != ErrSessionReused {
+		t.Fatalf("reused refresh token: %v, expected ErrSessionReused", err)
+	}
+	if session, _ := sessions.GetSession(sessionID); !session.Revoked {
+		t.Fatal("session not revoked after a refresh token reuse")
+	}
+	if _, _, err := c.Refresh("Bearer " + newRefreshToken); err != ErrSessionRevoked {
+		t.Fatalf("current refresh token: %v, expected ErrSessionRevoked", err)
+	}
+	if _, err := c.VerifyAccessToken(accessToken); err != ErrSessionRevoked {
+		t.Fatalf("access token: %v, expected ErrSessionRevoked", err)
+	}
+}
+
+func TestRefreshBannedUser(t *testing.T) {
+	c, _, users := newTestController(t, testAuthConfig(t))
+	_, _, refreshToken := startSession(t, c, "user", testAddress)
+
+	users.banned["user"] = true
+	if _, _, err := c.Refresh(refreshToken); err != ErrUserBanned {
+		t.Fatalf("refresh of a banned user: %v, expected ErrUserBanned", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	sessionID, accessToken, refreshToken := startSession(t, c, "user", testAddress)
+
+	if err := c.Logout(sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.VerifyAccessToken(accessToken); err != ErrSessionRevoked {
+		t.Fatalf("access token after logout: %v, expected ErrSessionRevoked", err)
+	}
+	if _, _, err := c.Refresh(refreshToken); err != ErrSessionRevoked {
+		t.Fatalf("refresh after logout: %v, expected ErrSessionRevoked", err)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	const other = "0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2"
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	sessionID, accessToken, _ := startSession(t, c, "user", testAddress)
+	otherID, otherToken, _ := startSession(t, c, "other", other)
+
+	if err := c.RevokeSession("user", testAddress, otherID); !xerrors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("revoke of another user's session: %v, expected ErrSessionNotFound", err)
+	}
+	if _, err := c.VerifyAccessToken(otherToken); err != nil {
+		t.Fatalf("other user's session was revoked: %v", err)
+	}
+
+	// a token issued before users existed revokes the sessions of its
+	// address only
+	if err := c.RevokeSession("", other, sessionID); !xerrors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("revoke without a user: %v, expected ErrSessionNotFound", err)
+	}
+
+	if err := c.RevokeSession("user", testAddress, sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.VerifyAccessToken(accessToken); err != ErrSessionRevoked {
+		t.Fatalf("access token of the revoked session: %v, expected ErrSessionRevoked", err)
+	}
+}
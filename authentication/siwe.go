@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spruceid/siwe-go"
+)
+
+// invalidMessage rejects a challenge request or a signed login message.
+func invalidMessage(format string, args ...interface{}) *TokenError {
+	return &TokenError{Code: "SIWE_INVALID", Message: fmt.Sprintf(format, args...)}
+}
+
+// ethProvider signs in Ethereum wallets with Sign-In with Ethereum
+// (EIP-4361), contract wallets included.
+type ethProvider struct {
+	c *AuthController
+}
+
+func (p *ethProvider) Challenge(ctx context.Context, request ChallengeRequest) (string, error) {
+	challenge, err := p.c.newChallenge(ProviderEthereum, request.Origin)
+	if err != nil {
+		return "", err
+	}
+	if !common.IsHexAddress(request.Address) {
+		return "", invalidMessage("invalid address %q", request.Address)
+	}
+	if request.ChainID <= 0 {
+		return "", invalidMessage("invalid chain id %d", request.ChainID)
+	}
+	challenge.Address = common.HexToAddress(request.Address).Hex()
+	challenge.Subject = challenge.Address
+	challenge.ChainID = request.ChainID
+
+	return p.c.siweMessage(challenge, purposeStatement)
+}
+
+func (p *ethProvider) Verify(ctx context.Context, request LoginRequest) (*Identity, error) {
+	message, challenge, err := p.c.verifyMessage(ProviderEthereum, request.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := p.c.verifySignature(ctx, message.GetAddress(), request.Message, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:  ProviderEthereum,
+		Subject:   challenge.Subject,
+		Address:   challenge.Address,
+		ChainID:   challenge.ChainID,
+		PublicKey: publicKey,
+	}, nil
+}
+
+// newChallenge checks the frontend's origin a challenge is requested from.
+func (c *AuthController) newChallenge(provider, origin string) (*Challenge, error) {
+	uri, err := url.Parse(origin)
+	if err != nil || uri.Host == "" || (uri.Scheme != "https" && uri.Scheme != "http") {
+		return nil, invalidMessage("invalid origin %q", origin)
+	}
+	if !c.allowedDomain(uri.Host) {
+		return nil, invalidMessage("domain %s is not allowed", uri.Host)
+	}
+
+	return &Challenge{
+		Provider: provider,
+		Domain:   uri.Host,
+		URI:      uri.Scheme + "://" + uri.Host,
+	}, nil
+}
+
+// siweMessage issues a nonce for the challenge and returns the siwe message
+// to sign.
+func (c *AuthController) siweMessage(challenge *Challenge, statement string) (string, error) {
+	nonce, err := c.GetNonce(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	var opt = map[string]interface{}{
+		"chainId":        challenge.ChainID,
+		"statement":      statement,
+		"issuedAt":       challenge.IssuedAt,
+		"expirationTime": challenge.ExpireAt,
+	}
+	msg, err := siwe.InitMessage(challenge.Domain, challenge.Address, challenge.URI, nonce, opt)
+	if err != nil {
+		return "", err
+	}
+	return msg.String(), nil
+}
+
+// verifyMessage parses the signed siwe message, uses up its nonce and checks
+// every field against the challenge the nonce was issued for. A message
+// signed on another site has another domain and is rejected, the signature
+// is checked by the caller.
+func (c *AuthController) verifyMessage(provider, raw string) (*siwe.Message, Challenge, error) {
+	message, err := parseLensMessage(raw)
+	if err != nil {
+		return nil, Challenge{}, invalidMessage("malformed siwe message: %s", err)
+	}
+	// the fields checked below must be the ones signed
+	if message.String() != strings.TrimSpace(raw) {
+		return nil, Challenge{}, invalidMessage("siwe message is not in EIP-4361 format")
+	}
+
+	challenge, ok, err := c.VerifyNonce(message.GetNonce())
+	if err != nil {
+		return nil, Challenge{}, err
+	}
+	if !ok {
+		return nil, Challenge{}, invalidMessage("nonce is unknown, used or expired")
+	}
+	if challenge.Provider != provider {
+		return nil, Challenge{}, invalidMessage("nonce was issued for %s login", challenge.Provider)
+	}
+
+	if message.GetDomain() != challenge.Domain || !c.allowedDomain(message.GetDomain()) {
+		return nil, Challenge{}, invalidMessage("domain %s doesn't match the challenge", message.GetDomain())
+	}
+	uri := message.GetURI()
+	if uri.String() != challenge.URI {
+		return nil, Challenge{}, invalidMessage("uri %s doesn't match the challenge", uri.String())
+	}
+	if message.GetAddress().Hex() != challenge.Address {
+		return nil, Challenge{}, invalidMessage("address %s doesn't match the challenge", message.GetAddress().Hex())
+	}
+	if message.GetChainID() != challenge.ChainID {
+		return nil, Challenge{}, invalidMessage("chain id %d doesn't match the challenge", message.GetChainID())
+	}
+	if message.GetVersion() != "1" {
+		return nil, Challenge{}, invalidMessage("unsupported siwe version %s", message.GetVersion())
+	}
+
+	issuedAt, err := time.Parse(time.RFC3339, message.GetIssuedAt())
+	if err != nil || !issuedAt.Equal(challenge.IssuedAt) {
+		return nil, Challenge{}, invalidMessage("issued at %s doesn't match the challenge", message.GetIssuedAt())
+	}
+	if message.GetExpirationTime() == nil {
+		return nil, Challenge{}, invalidMessage("expiration time is missing")
+	}
+	expireAt, err := time.Parse(time.RFC3339, *message.GetExpirationTime())
+	if err != nil || expireAt.After(challenge.ExpireAt) {
+		return nil, Challenge{}, invalidMessage("expiration time %s is after the challenge's", *message.GetExpirationTime())
+	}
+	if ok, err := message.ValidAt(time.Now().UTC()); !ok {
+		return nil, Challenge{}, invalidMessage("%s", err)
+	}
+
+	return message, challenge, nil
+}
+
+func (c *AuthController) allowedDomain(domain string) bool {
+	return slices.Contains(c.allowedDomains, domain)
+}
+
+// parseLensMessage parses the message as signed, Lens wraps it in newlines.
+func parseLensMessage(message string) (*siwe.Message, error) {
+	return siwe.ParseMessage(strings.TrimSpace(message))
+}
+
+// MessageAddress returns the canonical address on the second line of a
+// login or link message, the wallet claiming to sign it, or "" if it has
+// none. The message isn't verified, the address only keys rate limits.
+func MessageAddress(message string) string {
+	lines := strings.SplitN(strings.TrimSpace(message), "\n", 3)
+	if len(lines) < 2 {
+		return ""
+	}
+	address, err := CanonicalAddress(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return ""
+	}
+	return address
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spruceid/siwe-go"
+)
+
+// siweFields are the fields of a siwe login message.
+type siweFields struct {
+	domain, address, uri, nonce string
+	chainID                     int
+	issuedAt, expireAt          time.Time
+	notBefore                   *time.Time
+}
+
+// challengeFields requests a login challenge for the address and returns
+// its fields.
+func challengeFields(t *testing.T, c *AuthController, address string) siweFields {
+	t.Helper()
+	raw, err := c.Challenge(context.Background(), ChallengeRequest{Origin: "https://xspace.io", Address: address, ChainID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := siwe.ParseMessage(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuedAt, _ := time.Parse(time.RFC3339, message.GetIssuedAt())
+	expireAt, _ := time.Parse(time.RFC3339, *message.GetExpirationTime())
+	uri := message.GetURI()
+	return siweFields{
+		domain:   message.GetDomain(),
+		address:  message.GetAddress().Hex(),
+		uri:      uri.String(),
+		nonce:    message.GetNonce(),
+		chainID:  message.GetChainID(),
+		issuedAt: issuedAt,
+		expireAt: expireAt,
+	}
+}
+
+func (f siweFields) message(t *testing.T) string {
+	t.Helper()
+	opts := map[string]interface{}{
+		"chainId":        f.chainID,
+		"statement":      purposeStatement,
+		"issuedAt":       f.issuedAt,
+		"expirationTime": f.expireAt,
+	}
+	if f.notBefore != nil {
+		opts["notBefore"] = *f.notBefore
+	}
+	message, err := siwe.InitMessage(f.domain, f.address, f.uri, f.nonce, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message.String()
+}
+
+// signMessage signs the message with the key as an EIP-191 personal
+// message.
+func signMessage(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+func TestVerifyMessage(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	const other = "0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2"
+	later := time.Now().Add(time.Hour)
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(f *siweFields)
+		// a part of the rejection message, empty if the message is valid
+		reason string
+	}{
+		{"valid", func(f *siweFields) {}, ""},
+		{"wrong domain", func(f *siweFields) { f.domain = "evil.io" }, "domain"},
+		{"wrong uri", func(f *siweFields) { f.uri = "https://evil.io" }, "uri"},
+		{"wrong address", func(f *siweFields) { f.address = other }, "address"},
+		{"wrong chain id", func(f *siweFields) { f.chainID = 2 }, "chain id"},
+		{"wrong issued at", func(f *siweFields) { f.issuedAt = f.issuedAt.Add(-time.Second) }, "issued at"},
+		{"later expiration", func(f *siweFields) { f.expireAt = f.expireAt.Add(time.Second) }, "expiration time"},
+		{"expired", func(f *siweFields) { f.expireAt = f.issuedAt.Add(-time.Second) }, "expired"},
+		{"not yet valid", func(f *siweFields) { f.notBefore = &later }, "not yet valid"},
+		{"unknown nonce", func(f *siweFields) { f.nonce = "00112233445566778899aabbccddeeff" }, "nonce"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := challengeFields(t, c, testAddress)
+			tc.mutate(&fields)
+
+			_, challenge, err := c.verifyMessage(ProviderEthereum, fields.message(t))
+			if tc.reason == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if challenge.Address != testAddress || challenge.ChainID != 1 {
+					t.Fatalf("challenge %+v", challenge)
+				}
+				return
+			}
+
+			tokenErr, ok := err.(*TokenError)
+			if !ok || tokenErr.Code != "SIWE_INVALID" || !strings.Contains(tokenErr.Message, tc.reason) {
+				t.Fatalf("%v, expected a rejection for the %s", err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestVerifyMessageNonce(t *testing.T) {
+	cfg := testAuthConfig(t)
+	c, _, _ := newTestController(t, cfg)
+
+	message := challengeFields(t, c, testAddress).message(t)
+	if _, _, err := c.verifyMessage(ProviderEthereum, message); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.verifyMessage(ProviderEthereum, message); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("reused nonce: %v", err)
+	}
+
+	// the nonce of a challenge of another provider
+	link, err := c.LinkChallenge("https://xspace.io", "0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2", testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := challengeFields(t, c, testAddress)
+	fields.nonce = linkNonce.FindStringSubmatch(link)[1]
+	if _, _, err := c.verifyMessage(ProviderEthereum, fields.message(t)); err == nil || !strings.Contains(err.Error(), "issued for link") {
+		t.Fatalf("nonce of a link challenge: %v", err)
+	}
+
+	// an expired nonce
+	cfg.NonceTTL = 1
+	c, _, _ = newTestController(t, cfg)
+	message = challengeFields(t, c, testAddress).message(t)
+	time.Sleep(2 * time.Second)
+	if _, _, err := c.verifyMessage(ProviderEthereum, message); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("expired nonce: %v", err)
+	}
+}
+
+func TestEthereumLogin(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	key, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	for _, tc := range []struct {
+		name   string
+		signer *ecdsa.PrivateKey
+		tamper func(string) string
+		err    error
+	}{
+		{"valid", key, nil, nil},
+		{"wrong signer", otherKey, nil, ErrInvalidSignature},
+		{"tampered message", key, func(m string) string { return strings.Replace(m, purposeStatement, "Transfer all tokens", 1) }, ErrInvalidSignature},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			message := challengeFields(t, c, address).message(t)
+			signature := signMessage(t, tc.signer, message)
+			if tc.tamper != nil {
+				message = tc.tamper(message)
+			}
+
+			accessToken, _, err := c.Login(context.Background(), LoginRequest{Message: message, Signature: signature}, "test")
+			if err != tc.err {
+				t.Fatalf("%v, expected %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			claims, err := c.VerifyAccessToken("Bearer " + accessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != address || claims.Provider != ProviderEthereum {
+				t.Fatalf("claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestMessageAddress(t *testing.T) {
+	const address = "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+	for message, expected := range map[string]string{
+		"\nxspace.io wants you to sign in with your Ethereum account:\n0x5b38da6a701c568545dcfcb03fcb875f56beddc4\n\nSign in\n": address,
+		"xspace.io wants you to link a wallet to your xspace account:\n" + address + "\n\n":                                     address,
+		"xspace.io wants you to sign in with your Ethereum account:\nnot an address\n":                                          "",
+		"xspace.io wants you to sign in with your Ethereum account:":                                                            "",
+		"": "",
+	} {
+		if got := MessageAddress(message); got != expected {
+			t.Errorf("MessageAddress(%q) = %q, expected %q", message, got, expected)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+const solanaChainID = "mainnet"
+
+var solanaNonce = regexp.MustCompile(`(?m)^Nonce: ([0-9a-f]+)$`)
+
+// solanaProvider signs in Solana wallets with Sign-In with Solana. The
+// message follows the EIP-4361 layout with a Solana account, it is signed
+// with ed25519 and both the address and the signature are base58 encoded.
+type solanaProvider struct {
+	c *AuthController
+}
+
+func (p *solanaProvider) Challenge(ctx context.Context, request ChallengeRequest) (string, error) {
+	challenge, err := p.c.newChallenge(ProviderSolana, request.Origin)
+	if err != nil {
+		return "", err
+	}
+
+	publicKey, err := base58.Decode(request.Address)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return "", invalidMessage("invalid solana address %q", request.Address)
+	}
+	challenge.Address = base58.Encode(publicKey)
+	challenge.Subject = challenge.Address
+
+	nonce, err := p.c.GetNonce(challenge)
+	if err != nil {
+		return "", err
+	}
+	return solanaMessage(challenge, nonce), nil
+}
+
+func (p *solanaProvider) Verify(ctx context.Context, request LoginRequest) (*Identity, error) {
+	message := strings.TrimSpace(request.Message)
+	match := solanaNonce.FindStringSubmatch(message)
+	if match == nil {
+		return nil, invalidMessage("malformed sign in with solana message")
+	}
+
+	challenge, ok, err := p.c.VerifyNonce(match[1])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, invalidMessage("nonce is unknown, used or expired")
+	}
+	if challenge.Provider != ProviderSolana {
+		return nil, invalidMessage("nonce was issued for %s login", challenge.Provider)
+	}
+	// the message must be the challenge, so every field matches it
+	if message != solanaMessage(&challenge, match[1]) {
+		return nil, invalidMessage("message doesn't match the challenge")
+	}
+	if !p.c.allowedDomain(challenge.Domain) {
+		return nil, invalidMessage("domain %s is not allowed", challenge.Domain)
+	}
+
+	publicKey, _ := base58.Decode(challenge.Address)
+	signature, err := base58.Decode(request.Signature)
+	if err != nil || !ed25519.Verify(publicKey, []byte(message), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return &Identity{
+		Provider: ProviderSolana,
+		Subject:  challenge.Subject,
+		Address:  challenge.Address,
+	}, nil
+}
+
+func solanaMessage(challenge *Challenge, nonce string) string {
+	return fmt.Sprintf("%s wants you to sign in with your Solana account:\n%s\n\n%s\n\nURI: %s\nVersion: 1\nChain ID: %s\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		challenge.Domain, challenge.Address, purposeStatement, challenge.URI, solanaChainID, nonce,
+		challenge.IssuedAt.UTC().Format(time.RFC3339), challenge.ExpireAt.UTC().Format(time.RFC3339))
+}
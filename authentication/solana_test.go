@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+func TestSolanaLogin(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	address := base58.Encode(publicKey)
+
+	for _, tc := range []struct {
+		name   string
+		signer ed25519.PrivateKey
+		tamper func(string) string
+		// a part of the rejection, empty if the login is valid
+		reason string
+	}{
+		{"valid", privateKey, nil, ""},
+		{"wrong signer", otherKey, nil, ErrInvalidSignature.Message},
+		{"tampered message", privateKey, func(m string) string { return strings.Replace(m, "Chain ID: mainnet", "Chain ID: devnet", 1) }, "doesn't match the challenge"},
+		{"other address", privateKey, func(m string) string {
+			return strings.Replace(m, address, base58.Encode(otherKey.Public().(ed25519.PublicKey)), 1)
+		}, "doesn't match the challenge"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			message, err := c.Challenge(context.Background(), ChallengeRequest{Provider: ProviderSolana, Origin: "https://xspace.io", Address: address})
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature := base58.Encode(ed25519.Sign(tc.signer, []byte(message)))
+			if tc.tamper != nil {
+				message = tc.tamper(message)
+			}
+
+			identity, err := c.providers[ProviderSolana].Verify(context.Background(), LoginRequest{Provider: ProviderSolana, Message: message, Signature: signature})
+			if tc.reason != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.reason) {
+					t.Fatalf("%v, expected a rejection for %q", err, tc.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Provider != ProviderSolana || identity.Subject != address || identity.Address != address {
+				t.Fatalf("identity %+v", identity)
+			}
+		})
+	}
+}
+
+func TestSolanaChallenge(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	for _, address := range []string{"", "not base58!", base58.Encode([]byte("short"))} {
+		_, err := c.Challenge(context.Background(), ChallengeRequest{Provider: ProviderSolana, Origin: "https://xspace.io", Address: address})
+		if err == nil {
+			t.Errorf("challenge for the address %q", address)
+		}
+	}
+
+	// a signed ethereum challenge isn't a solana login
+	message, err := c.Challenge(context.Background(), ChallengeRequest{Origin: "https://xspace.io", Address: testAddress, ChainID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.providers[ProviderSolana].Verify(context.Background(), LoginRequest{Provider: ProviderSolana, Message: message})
+	if err == nil || !strings.Contains(err.Error(), "issued for ethereum") {
+		t.Fatalf("ethereum challenge: %v", err)
+	}
+}
@@ -1,71 +1,77 @@
 package auth
 
 import (
+	"errors"
 	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/memoio/xspace-server/config"
 	"golang.org/x/xerrors"
 )
 
-var Domain = "xspace.com"
-
 type Claims struct {
 	Type         int  `json:"type,omitempty"`
 	IsRegistered bool `json:"isRegistered,omitempty"`
-	ChainID      int  `josn:"chainid,omitempty"`
+	ChainID      int  `json:"chainid,omitempty"`
+	// the session (refresh token family) the token belongs to
+	SessionID string `json:"sid,omitempty"`
+	// the xspace user and the login provider, the subject is the wallet
+	// address of the identity the user logged in with
+	UserID   string `json:"uid,omitempty"`
+	Provider string `json:"idp,omitempty"`
+	// the role of the address when the token was issued
+	Role string `json:"role,omitempty"`
 	// Nonce string `json:"nonce,omitempty"`
-	jwt.StandardClaims
+	jwt.RegisteredClaims
 }
 
-func VerifyAccessToken(tokenString string) (string, int, error) {
-	claims, err := verifyJsonWebToken(tokenString, AccessToken)
-	if err != nil {
-		return "", 0, err
-	}
-
-	return claims.Subject, claims.ChainID, nil
+// TokenError tells the client why its token is rejected: an expired access
+// token can be refreshed, otherwise the user has to log in again.
+type TokenError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
-func VerifyRefreshToken(tokenString string) (string, error) {
-	claims, err := verifyJsonWebToken(tokenString, RefreshToken)
-	if err != nil {
-		return "", err
-	}
-
-	return genAccessTokenWithFlag(claims.Subject, claims.ChainID, claims.IsRegistered)
+func (e *TokenError) Error() string {
+	return e.Message
 }
 
-func genAccessToken(subject string, chainID int) (string, error) {
-	return genJsonWebTokenWithFlag(subject, chainID, AccessToken, false)
-}
+const (
+	accessTokenLifetime  = 2 * time.Hour
+	refreshTokenLifetime = 7 * 24 * time.Hour
+)
 
-func genAccessTokenWithFlag(subject string, chainID int, isRegistered bool) (string, error) {
-	return genJsonWebTokenWithFlag(subject, chainID, AccessToken, isRegistered)
+func (c *AuthController) genAccessToken(session *Session) (string, error) {
+	return c.genJsonWebTokenWithFlag(session, AccessToken, false, "")
 }
 
-func genRefreshToken(subject string, chainID int) (string, error) {
-	return genJsonWebTokenWithFlag(subject, chainID, RefreshToken, false)
+func (c *AuthController) genAccessTokenWithFlag(session *Session, isRegistered bool) (string, error) {
+	return c.genJsonWebTokenWithFlag(session, AccessToken, isRegistered, "")
 }
 
-func genRefreshTokenWithFlag(subject string, chainID int, isRegistered bool) (string, error) {
-	return genJsonWebTokenWithFlag(subject, chainID, RefreshToken, isRegistered)
+func (c *AuthController) genRefreshToken(session *Session, tokenID string) (string, error) {
+	return c.genJsonWebTokenWithFlag(session, RefreshToken, false, tokenID)
 }
 
-func verifyJsonWebToken(tokenString string, jwtType int) (*Claims, error) {
+func (c *AuthController) verifyJsonWebToken(tokenString string, jwtType int) (*Claims, error) {
 	parts := strings.SplitN(tokenString, " ", 2)
-	if !(len(parts) == 2 && parts[0] == "Bearer") {
+	if !(len(parts) == 2 && parts[0] == "Bearer") || parts[1] == "" {
 		return nil, ErrNullToken
 	}
 
+	// check signature, algorithm, audience, issuer and time claims
 	claims := &Claims{}
-	_, _, err := new(jwt.Parser).ParseUnverified(parts[1], claims)
-	if err != nil {
-		return nil, ErrValidToken
-	}
-
-	// check Audience
-	if claims.Audience != Domain || claims.Issuer != Domain {
+	_, err := c.parser.ParseWithClaims(parts[1], claims, c.keys.keyFunc)
+	switch {
+	case err == nil:
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return nil, ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return nil, ErrTokenMalformed
+	default:
 		return nil, ErrValidToken
 	}
 
@@ -74,39 +80,39 @@ func verifyJsonWebToken(tokenString string, jwtType int) (*Claims, error) {
 		return nil, ErrValidTokenType
 	}
 
-	// check signature, Expires time and Issued time
-	token, err := parseToken(parts[1])
-	if err != nil || !token.Valid {
-		return nil, ErrValidToken
-	}
-
 	return claims, nil
 }
 
-func genJsonWebTokenWithFlag(subject string, chainID, jwtType int, isRegistered bool) (string, error) {
+func (c *AuthController) genJsonWebTokenWithFlag(session *Session, jwtType int, isRegistered bool, tokenID string) (string, error) {
 	var expireTime int64
 	if jwtType == AccessToken {
-		expireTime = time.Now().Add(2 * time.Hour).Unix()
+		expireTime = time.Now().Add(accessTokenLifetime).Unix()
 	} else if jwtType == RefreshToken {
-		expireTime = time.Now().Add(7 * 24 * time.Hour).Unix()
+		expireTime = time.Now().Add(refreshTokenLifetime).Unix()
 	} else {
 		return "", xerrors.Errorf("unsupported json web token type")
 	}
 
+	now := time.Now()
 	claims := &Claims{
 		Type:         jwtType,
 		IsRegistered: isRegistered,
-		ChainID:      chainID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expireTime,
-			IssuedAt:  time.Now().Unix(),
-			Audience:  Domain,
-			Issuer:    Domain,
-			Subject:   subject,
+		ChainID:      session.ChainID,
+		SessionID:    session.ID,
+		UserID:       session.UserID,
+		Provider:     session.Provider,
+		Role:         session.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(time.Unix(expireTime, 0)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Audience:  jwt.ClaimStrings{c.audience},
+			Issuer:    c.issuer,
+			Subject:   session.Address,
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JWTKey)
+	return c.keys.sign(claims)
 }
 
 // func ParseDidToken(tokenString string, did string) (*jwt.Token, error) {
@@ -125,8 +131,13 @@ func genJsonWebTokenWithFlag(subject string, chainID, jwtType int, isRegistered
 //     })
 // }
 
-func parseToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (i interface{}, err error) {
-		return JWTKey, nil
-	})
+func newParser(cfg config.AuthConfig, keys *KeySet) *jwt.Parser {
+	return jwt.NewParser(
+		jwt.WithValidMethods(keys.algorithms()),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Duration(cfg.ClockSkew)*time.Second),
+	)
 }
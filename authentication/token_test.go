@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/memoio/xspace-server/config"
+)
+
+func TestKeyAlgorithm(t *testing.T) {
+	cfg := testAuthConfig(t)
+	cfg.Keys = append(cfg.Keys, config.JWTKeyConfig{ID: "k2", Algorithm: AlgEdDSA, PrivateKeyFile: writeKey(t, AlgEdDSA)})
+	c, _, _ := newTestController(t, cfg)
+	der, err := x509.MarshalPKIXPublicKey(c.keys.keys["k1"].publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	for _, tc := range []struct {
+		name   string
+		method jwt.SigningMethod
+		key    interface{}
+	}{
+		{"none", jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType},
+		{"HS256 with the public key", jwt.SigningMethodHS256, publicKeyPEM},
+		{"HS256 with the public key DER", jwt.SigningMethodHS256, der},
+		// a configured algorithm, but not the one of the kid
+		{"EdDSA key of another kid", jwt.SigningMethodEdDSA, c.keys.keys["k2"].privateKey},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			forged := jwt.NewWithClaims(tc.method, testClaims())
+			forged.Header["kid"] = "k1"
+			token, err := forged.SignedString(tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.verifyJsonWebToken("Bearer "+token, AccessToken); err != ErrValidToken {
+				t.Fatalf("%v, expected ErrValidToken", err)
+			}
+		})
+	}
+}
+
+func TestTokenErrors(t *testing.T) {
+	c, _, _ := newTestController(t, testAuthConfig(t))
+	sign := func(mutate func(claims *Claims)) string {
+		claims := testClaims()
+		mutate(claims)
+		token, err := c.keys.sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	valid := sign(func(*Claims) {})
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", valid, nil},
+		{"no bearer", valid[len("Bearer "):], ErrNullToken},
+		{"empty", "Bearer ", ErrNullToken},
+		{"not a jwt", "Bearer token", ErrTokenMalformed},
+		{"bad encoding", valid + "!", ErrTokenMalformed},
+		{"expired", sign(func(claims *Claims) { claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }), ErrTokenExpired},
+		{"no expiration", sign(func(claims *Claims) { claims.ExpiresAt = nil }), ErrValidToken},
+		{"not valid yet", sign(func(claims *Claims) { claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour)) }), ErrTokenNotValidYet},
+		{"issued in the future", sign(func(claims *Claims) { claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour)) }), ErrTokenNotValidYet},
+		{"wrong audience", sign(func(claims *Claims) { claims.Audience = jwt.ClaimStrings{"other"} }), ErrValidToken},
+		{"wrong issuer", sign(func(claims *Claims) { claims.Issuer = "other" }), ErrValidToken},
+		{"refresh token", sign(func(claims *Claims) { claims.Type = RefreshToken }), ErrValidTokenType},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := c.verifyJsonWebToken(tc.token, AccessToken); err != tc.err {
+				t.Fatalf("%v, expected %v", err, tc.err)
+			}
+		})
+	}
+
+	// the client refreshes an expired token and logs in again otherwise
+	if ErrTokenExpired.Code == ErrTokenMalformed.Code || ErrTokenExpired.Code == ErrValidToken.Code {
+		t.Fatal("expired and invalid tokens share the error code")
+	}
+}
@@ -3,13 +3,18 @@ package cmd
 import (
 	"context"
 	"log"
-	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/config"
 	"github.com/memoio/xspace-server/server"
+	"github.com/memoio/xspace-server/tracing"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
 )
 
 var XspaceServerCmd = &cli.Command{
@@ -17,6 +22,32 @@ var XspaceServerCmd = &cli.Command{
 	Usage: "xspace server",
 	Subcommands: []*cli.Command{
 		xspaceServerRunCmd,
+		xspaceServerKeygenCmd,
+	},
+}
+
+var xspaceServerKeygenCmd = &cli.Command{
+	Name:  "keygen",
+	Usage: "generate a key signing json web tokens",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "alg",
+			Usage: "input the signing algorithm, ES256 or EdDSA",
+			Value: auth.AlgES256,
+		},
+		&cli.StringFlag{
+			Name:  "out",
+			Usage: "input the path of the generated PEM file",
+			Value: "jwt.pem",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		key, err := auth.GenerateKey(ctx.String("alg"))
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(ctx.String("out"), key, 0600)
 	},
 }
 
@@ -40,17 +71,26 @@ var xspaceServerRunCmd = &cli.Command{
 			Usage: "input chain name, e.g.(dev)",
 			Value: "dev",
 		},
-		// &cli.StringFlag{
-		// 	Name:  "ip",
-		// 	Usage: "input meeda store node's ip address",
-		// 	Value: "http://183.240.197.189:38082",
-		// },
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "input the path of the json config file",
+			Value: "",
+		},
+		&cli.StringFlag{
+			Name:  "ip",
+			Usage: "input meeda store node's address as host[:port] or http(s)://host[:port], data is stored on it instead of the local disk",
+			Value: "",
+		},
 	},
 	Action: func(ctx *cli.Context) error {
 		port := ctx.String("port")
 		// sk := ctx.String("sk")
 		chain := ctx.String("chain")
-		// ip := ctx.String("ip")
+
+		cfg, err := loadConfig(ctx)
+		if err != nil {
+			return err
+		}
 
 		// privateKey, err := crypto.HexToECDSA(sk)
 		// if err != nil {
@@ -63,28 +103,83 @@ var xspaceServerRunCmd = &cli.Command{
 		cctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		srv, err := server.NewServer(chain, port)
+		shutdownTracing, err := tracing.Init(cctx, cfg.Tracing)
+		if err != nil {
+			return err
+		}
+
+		app, err := server.NewServer(chain, port, cfg)
 		if err != nil {
 			log.Fatalf("new store node server: %s\n", err)
 		}
+		if err := app.Start(cctx); err != nil {
+			log.Fatalf("start server: %s\n", err)
+		}
 
-		go func() {
-			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				log.Fatalf("listen: %s\n", err)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		for sig := range signals {
+			if sig != syscall.SIGHUP {
+				break
 			}
-		}()
 
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		<-quit
+			cfg, err := loadConfig(ctx)
+			if err == nil {
+				err = app.Reload(cfg)
+			}
+			if err != nil {
+				log.Println("Reload config: ", err)
+				continue
+			}
+			log.Println("Config reloaded")
+		}
 		log.Println("Shutting down server...")
 
-		if err := srv.Shutdown(cctx); err != nil {
-			log.Fatal("Server forced to shutdown: ", err)
+		// stops the http server first, then the workers and the store
+		err = app.Stop(context.Background())
+		if err != nil {
+			log.Println("Server forced to shutdown: ", err)
+		}
+		// export the spans left
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Println("Shutdown tracing: ", err)
 		}
 
 		log.Println("Server exiting")
 
-		return nil
+		return err
 	},
 }
+
+// loadConfig reads the config file of the run command.
+func loadConfig(ctx *cli.Context) (*config.Config, error) {
+	cfg, err := config.LoadConfig(ctx.String("config"))
+	if err != nil {
+		return nil, err
+	}
+	if ip := ctx.String("ip"); ip != "" {
+		endpoint, err := meedaURL(ip)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Storage.Backend = "meeda"
+		cfg.Storage.MeedaURL = endpoint
+	}
+	return cfg, nil
+}
+
+// meedaURL turns the --ip flag, host[:port] or an http(s) URL, into the
+// endpoint of the meeda store node.
+func meedaURL(ip string) (string, error) {
+	if !strings.Contains(ip, "://") {
+		ip = "http://" + ip
+	}
+	u, err := url.Parse(ip)
+	if err != nil {
+		return "", xerrors.Errorf("invalid --ip %s: %w", ip, err)
+	}
+	if u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return "", xerrors.Errorf("invalid --ip %s: expected host[:port]", ip)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
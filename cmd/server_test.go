@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestMeedaURL(t *testing.T) {
+	for ip, expected := range map[string]string{
+		"183.240.197.189:38082":         "http://183.240.197.189:38082",
+		"localhost":                     "http://localhost",
+		"http://183.240.197.189:38082/": "http://183.240.197.189:38082",
+		"https://meeda.example":         "https://meeda.example",
+		"183.240.197.189:38082/object":  "",
+		"http://":                       "",
+	} {
+		endpoint, err := meedaURL(ip)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("meedaURL(%q) = %s, expected an error", ip, endpoint)
+			}
+			continue
+		}
+		if err != nil || endpoint != expected {
+			t.Errorf("meedaURL(%q) = %s, %v, expected %s", ip, endpoint, err, expected)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+
+	"golang.org/x/xerrors"
+)
+
+// Config is the config of the server. On SIGHUP the server reloads the log
+// level, the rate limit groups, the mint limits, the storage caps, the point
+// rewards, the partner max events and the webhook and realtime settings but
+// the realtime fanout; the other settings need a restart.
+type Config struct {
+	Auth      AuthConfig      `json:"auth"`
+	Chain     ChainConfig     `json:"chain"`
+	Lens      LensConfig      `json:"lens"`
+	Database  DatabaseConfig  `json:"database"`
+	Storage   StorageConfig   `json:"storage"`
+	Point     PointConfig     `json:"point"`
+	Mint      MintConfig      `json:"mint"`
+	Partner   PartnerConfig   `json:"partner"`
+	Webhook   WebhookConfig   `json:"webhook"`
+	Realtime  RealtimeConfig  `json:"realtime"`
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	Cors      CorsConfig      `json:"cors"`
+	Log       LogConfig       `json:"log"`
+	Tracing   TracingConfig   `json:"tracing"`
+	Shutdown  ShutdownConfig  `json:"shutdown"`
+
+	// addresses granted the admin role at startup, other roles are managed
+	// with the admin APIs
+	Admins []string `json:"admins"`
+}
+
+type AuthConfig struct {
+	// id of the key signing new json web tokens
+	ActiveKey string `json:"activeKey"`
+	// keys verifying json web tokens, keep a rotated key here until the
+	// tokens it signed expire
+	Keys []JWTKeyConfig `json:"keys"`
+	// the iss and aud claims of json web tokens
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	// seconds of clock skew tolerated when checking exp, nbf and iat
+	ClockSkew int64 `json:"clockSkew"`
+	// where challenge nonces are kept, "memory" or "database"; replicas
+	// behind a load balancer must share the database store
+	NonceStore string `json:"nonceStore"`
+	// seconds a challenge nonce stays valid
+	NonceTTL int64 `json:"nonceTTL"`
+	// hosts (with port if not the default) of the frontends allowed to
+	// request login challenges, the siwe domain must be one of them
+	AllowedDomains []string `json:"allowedDomains"`
+}
+
+type JWTKeyConfig struct {
+	// the kid in the token header
+	ID string `json:"id"`
+	// ES256 or EdDSA
+	Algorithm string `json:"algorithm"`
+	// PEM (PKCS#8) private key, required for the active key
+	PrivateKeyFile string `json:"privateKeyFile"`
+	// PEM (PKIX) public key, used when there is no private key
+	PublicKeyFile string `json:"publicKeyFile"`
+}
+
+type ChainConfig struct {
+	// overrides the rpc url of the chain selected by --chain
+	RPC string `json:"rpc"`
+}
+
+type LensConfig struct {
+	// rpc url of the chain lens is deployed on, empty disables lens login
+	RPC string `json:"rpc"`
+	// address of the LensHub contract
+	Hub string `json:"hub"`
+}
+
+type DatabaseConfig struct {
+	// sqlite or mysql
+	Driver string `json:"driver"`
+	DSN    string `json:"dsn"`
+}
+
+type StorageConfig struct {
+	// local or meeda
+	Backend string `json:"backend"`
+	// the directory used by the local filesystem backend
+	Path string `json:"path"`
+	// the url of the meeda store node used by the meeda backend
+	MeedaURL string `json:"meedaURL"`
+	// objects larger than it are uploaded to meeda in chunks
+	ChunkSize int64 `json:"chunkSize"`
+	// hex encoded 32-byte key sealing the keys of encrypted dataNFTs,
+	// encrypted dataNFTs are disabled if it is empty
+	EncryptionKey string `json:"encryptionKey"`
+	// max number of dataNFT objects per user, 0 means unlimited
+	MaxUserObjects int64 `json:"maxUserObjects"`
+	// max bytes of dataNFT objects per user, 0 means unlimited
+	MaxUserSpace int64 `json:"maxUserSpace"`
+}
+
+type PointConfig struct {
+	// points credited by one charge
+	ChargeReward int64 `json:"chargeReward"`
+	// seconds between two charges
+	ChargeInterval int64 `json:"chargeInterval"`
+}
+
+type MintConfig struct {
+	// max number of tweetNFTs an address can mint per day, 0 means unlimited
+	TweetDailyQuota int64 `json:"tweetDailyQuota"`
+	// max number of dataNFTs an address can mint per day, 0 means unlimited
+	DataDailyQuota int64 `json:"dataDailyQuota"`
+	// max size (bytes) of the file in one dataNFT
+	MaxFileSize int64 `json:"maxFileSize"`
+	// points debited for each mint
+	TweetCost int64 `json:"tweetCost"`
+	DataCost  int64 `json:"dataCost"`
+	// max characters of a minted tweet, 0 means unlimited
+	MaxTweetLength int `json:"maxTweetLength"`
+	// hosts the images of minted tweets are served from, any https host if
+	// empty
+	ImageHosts []string `json:"imageHosts"`
+}
+
+type PartnerConfig struct {
+	// hex encoded 32-byte key the partner API key secrets are derived from,
+	// partner API keys are disabled if it is empty
+	Secret string `json:"secret"`
+	// default number of requests a partner API key can make per minute,
+	// kept in the rateLimit store
+	RateLimit int `json:"rateLimit"`
+	// seconds the timestamp of a signed request may differ from the
+	// server's clock
+	SignatureTTL int64 `json:"signatureTTL"`
+	// max number of events in one request
+	MaxEvents int `json:"maxEvents"`
+}
+
+type WebhookConfig struct {
+	// attempts of a delivery before it is dead-lettered
+	MaxAttempts int `json:"maxAttempts"`
+	// seconds to wait for a subscriber's response
+	Timeout int64 `json:"timeout"`
+	// seconds before the first retry, doubled on every retry up to
+	// RetryMax
+	RetryBase int64 `json:"retryBase"`
+	RetryMax  int64 `json:"retryMax"`
+}
+
+type RealtimeConfig struct {
+	// seconds between the heartbeats of an event stream
+	Heartbeat int64 `json:"heartbeat"`
+	// updates buffered for a slow stream before it is told to resync
+	QueueSize int `json:"queueSize"`
+	// open streams of a user
+	MaxStreams int `json:"maxStreams"`
+	// how the events reach the streams of all replicas, "memory" for a
+	// single instance or "database"; replicas behind a load balancer must
+	// use the database, it needs a restart
+	Fanout string `json:"fanout"`
+}
+
+type RateLimitConfig struct {
+	// where the token buckets are kept, "memory" or "database"; replicas
+	// behind a load balancer must share the database store
+	Store string `json:"store"`
+	// proxies trusted to report the client IP in X-Forwarded-For, the
+	// client IP is the peer's if empty
+	TrustedProxies []string `json:"trustedProxies"`
+	// limits by route group: global (every API request), challenge, login
+	// and mint; a group in the file replaces the default one
+	Groups map[string]RateLimitRule `json:"groups"`
+}
+
+type RateLimitRule struct {
+	// requests per minute of a client IP, 0 means unlimited
+	IP int `json:"ip"`
+	// requests per minute of an address, 0 means unlimited: the
+	// authenticated one, the one a challenge is requested for or the one a
+	// login message claims to be signed by
+	Address int `json:"address"`
+	// requests a client can make at once, the per minute rate if 0
+	Burst int `json:"burst"`
+}
+
+type CorsConfig struct {
+	// origins of the frontends allowed to call the API with credentials,
+	// such as https://app.xspace.com, https://*.xspace.com for the
+	// subdomains or http://localhost:* for any port. The origins of the
+	// auth allowed domains if empty, otherwise they must include them.
+	Origins []string `json:"origins"`
+	// request headers the frontends may send
+	Headers []string `json:"headers"`
+	// seconds browsers cache a preflight response
+	MaxAge int64 `json:"maxAge"`
+	// policies of the paths starting with their prefixes, the longest
+	// matching prefix wins
+	Routes []CorsRouteConfig `json:"routes"`
+}
+
+type CorsRouteConfig struct {
+	Prefix string `json:"prefix"`
+	// origins allowed on the route, "*" for any origin without
+	// credentials, CORS is disabled on the route if empty
+	Origins []string `json:"origins"`
+}
+
+type LogConfig struct {
+	// debug, info, warn or error
+	Level string `json:"level"`
+}
+
+type TracingConfig struct {
+	// where spans are exported, "stdout" or "otlp"; tracing is disabled if
+	// empty
+	Exporter string `json:"exporter"`
+	// host:port of the OTLP/HTTP collector, localhost:4318 if empty
+	Endpoint string `json:"endpoint"`
+	// send the spans to the collector over http instead of https
+	Insecure bool `json:"insecure"`
+	// fraction of the traces sampled, between 0 and 1; a request keeps the
+	// sampling decision of its caller
+	SampleRate float64 `json:"sampleRate"`
+	// service.name of the exported spans
+	ServiceName string `json:"serviceName"`
+}
+
+type ShutdownConfig struct {
+	// seconds a component may take to stop, such as the http server
+	// waiting for the requests in flight
+	Timeout int64 `json:"timeout"`
+	// seconds the mint in flight may take to finish, it is interrupted
+	// afterwards and minted after the restart
+	MintTimeout int64 `json:"mintTimeout"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Auth: AuthConfig{
+			Issuer:         "xspace.com",
+			Audience:       "xspace.com",
+			ClockSkew:      30,
+			NonceStore:     "memory",
+			NonceTTL:       300,
+			AllowedDomains: []string{"xspace.com"},
+		},
+		Lens: LensConfig{
+			// LensHub on Polygon
+			Hub: "0xDb46d1Dc155634FbC732f92E853b10B288AD5a1d",
+		},
+		Database: DatabaseConfig{
+			Driver: "sqlite",
+			DSN:    "xspace.db",
+		},
+		Storage: StorageConfig{
+			Backend:        "local",
+			Path:           "data",
+			MeedaURL:       "http://183.240.197.189:38082",
+			ChunkSize:      4 << 20,
+			MaxUserObjects: 1000,
+			MaxUserSpace:   1 << 30,
+		},
+		Point: PointConfig{
+			ChargeReward:   50,
+			ChargeInterval: 6 * 60 * 60,
+		},
+		Mint: MintConfig{
+			TweetDailyQuota: 10,
+			DataDailyQuota:  5,
+			MaxFileSize:     8 << 20,
+			TweetCost:       10,
+			DataCost:        20,
+			MaxTweetLength:  280,
+			ImageHosts:      []string{"pbs.twimg.com"},
+		},
+		Partner: PartnerConfig{
+			RateLimit:    60,
+			SignatureTTL: 300,
+			MaxEvents:    100,
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts: 8,
+			Timeout:     10,
+			RetryBase:   10,
+			RetryMax:    60 * 60,
+		},
+		Realtime: RealtimeConfig{
+			Heartbeat:  15,
+			QueueSize:  64,
+			MaxStreams: 5,
+			Fanout:     "memory",
+		},
+		RateLimit: RateLimitConfig{
+			Store: "memory",
+			Groups: map[string]RateLimitRule{
+				"global":    {IP: 600, Burst: 100},
+				"challenge": {IP: 20, Address: 20, Burst: 10},
+				"login":     {IP: 20, Address: 20, Burst: 10},
+				"mint":      {IP: 60, Address: 30, Burst: 5},
+			},
+		},
+		Cors: CorsConfig{
+			Headers: []string{"Content-Type", "Authorization", "X-Request-ID"},
+			MaxAge:  600,
+			Routes: []CorsRouteConfig{
+				// public keys and rankings can be read by any site
+				{Prefix: "/.well-known/", Origins: []string{"*"}},
+				{Prefix: "/v1/project/", Origins: []string{"*"}},
+				// called by the partners' servers, not from browsers
+				{Prefix: "/v1/partner/"},
+			},
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			SampleRate:  1,
+			ServiceName: "xspace-server",
+		},
+		Shutdown: ShutdownConfig{
+			Timeout:     15,
+			MintTimeout: 30,
+		},
+	}
+}
+
+// LoadConfig reads the json config file at path. Fields missing from the
+// file keep their default values; an empty path returns the defaults.
+func LoadConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("read config %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, xerrors.Errorf("parse config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
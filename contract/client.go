@@ -0,0 +1,46 @@
+package contract
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/xerrors"
+)
+
+// ErrChain marks a failed request to the chain, as opposed to the chain
+// rejecting the request.
+var ErrChain = xerrors.New("chain request failed")
+
+// WrapError marks an rpc error as ErrChain.
+func WrapError(err error) error {
+	return xerrors.Errorf("%w: %s", ErrChain, err)
+}
+
+// Endpoints are the rpc urls of the memo chains.
+var Endpoints = map[string]string{
+	"dev":     "https://devchain.metamemo.one:8501",
+	"test":    "https://testchain.metamemo.one:24180",
+	"product": "https://chain.metamemo.one:8501",
+}
+
+// NewClient connects to the chain by name, endpoint overrides the chain's
+// default rpc url if it is not empty. The requests over http are traced and
+// their latency is observed by metrics.ChainRequestDuration.
+func NewClient(chain, endpoint string) (*ethclient.Client, error) {
+	if endpoint == "" {
+		var ok bool
+		endpoint, ok = Endpoints[chain]
+		if !ok {
+			return nil, xerrors.Errorf("unsupported chain %s", chain)
+		}
+	}
+	client, err := rpc.DialOptions(context.Background(), endpoint, rpc.WithHTTPClient(&http.Client{
+		Transport: &transport{next: http.DefaultTransport},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(client), nil
+}
@@ -0,0 +1,53 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	auth "github.com/memoio/xspace-server/authentication"
+	"golang.org/x/xerrors"
+)
+
+var ownerOfSelector = crypto.Keccak256([]byte("ownerOf(uint256)"))[:4]
+
+// HubController reads lens profiles from the LensHub contract, the profiles
+// are its ERC-721 tokens.
+type HubController struct {
+	caller bind.ContractCaller
+	hub    common.Address
+}
+
+var _ auth.LensProfiles = (*HubController)(nil)
+
+func NewHubController(caller bind.ContractCaller, hub common.Address) *HubController {
+	return &HubController{caller: caller, hub: hub}
+}
+
+// OwnerOf returns the address owning the profile.
+func (c *HubController) OwnerOf(ctx context.Context, profileID *big.Int) (common.Address, error) {
+	data := append(append([]byte{}, ownerOfSelector...), common.BigToHash(profileID).Bytes()...)
+	out, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &c.hub, Data: data}, nil)
+	if err != nil {
+		// ownerOf reverts for burnt and unknown tokens
+		var dataErr rpc.DataError
+		if errors.As(err, &dataErr) {
+			return common.Address{}, auth.ErrProfileNotFound
+		}
+		return common.Address{}, err
+	}
+	if len(out) != 32 {
+		return common.Address{}, xerrors.Errorf("%s is not an ERC-721 contract", c.hub.Hex())
+	}
+
+	owner := common.BytesToAddress(out)
+	if owner == (common.Address{}) {
+		return common.Address{}, auth.ErrProfileNotFound
+	}
+	return owner, nil
+}
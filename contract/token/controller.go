@@ -0,0 +1,38 @@
+package token
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/xspace-server/contract"
+	"golang.org/x/xerrors"
+)
+
+var balanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+// TokenController reads ERC-20 and ERC-721 contracts, which share the
+// balanceOf(address) method.
+type TokenController struct {
+	caller bind.ContractCaller
+}
+
+func NewTokenController(caller bind.ContractCaller) *TokenController {
+	return &TokenController{caller: caller}
+}
+
+// BalanceOf returns the holder's balance of the token contract.
+func (c *TokenController) BalanceOf(ctx context.Context, token, holder common.Address) (*big.Int, error) {
+	data := append(append([]byte{}, balanceOfSelector...), common.LeftPadBytes(holder.Bytes(), 32)...)
+	out, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, contract.WrapError(err)
+	}
+	if len(out) != 32 {
+		return nil, xerrors.Errorf("%s is not an ERC-20/ERC-721 contract", token.Hex())
+	}
+	return new(big.Int).SetBytes(out), nil
+}
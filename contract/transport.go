@@ -0,0 +1,62 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/memoio/xspace-server/metrics"
+	"github.com/memoio/xspace-server/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/xerrors"
+)
+
+// transport traces and observes the json-rpc requests to the chain.
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := "batch"
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var msg struct {
+			Method string `json:"method"`
+		}
+		if json.Unmarshal(body, &msg) == nil && msg.Method != "" {
+			method = msg.Method
+		}
+	}
+
+	ctx, span := tracing.Tracer().Start(req.Context(), "chain "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rpc.system", "jsonrpc"), attribute.String("rpc.method", method)),
+	)
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	failed := err
+	if err == nil && res.StatusCode != http.StatusOK {
+		failed = xerrors.Errorf("chain responded %s", res.Status)
+	}
+	result := "ok"
+	if failed != nil {
+		result = "error"
+	}
+	tracing.End(span, failed)
+	metrics.ChainRequestDuration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+	return res, err
+}
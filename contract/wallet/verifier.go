@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/memoio/xspace-server/contract"
+	"golang.org/x/xerrors"
+)
+
+var (
+	isValidSignatureSelector = crypto.Keccak256([]byte("isValidSignature(bytes32,bytes)"))[:4]
+	// returned by isValidSignature if the signature is valid (EIP-1271)
+	erc1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+	// suffix of the signatures of undeployed wallets (EIP-6492)
+	erc6492MagicSuffix = common.FromHex("0x6492649264926492649264926492649264926492649264926492649264926492")
+)
+
+var (
+	bytes32Type, _ = abi.NewType("bytes32", "", nil)
+	bytesType, _   = abi.NewType("bytes", "", nil)
+	addressType, _ = abi.NewType("address", "", nil)
+
+	isValidSignatureArgs = abi.Arguments{{Type: bytes32Type}, {Type: bytesType}}
+	// (address factory, bytes factoryCalldata, bytes signature)
+	erc6492Args = abi.Arguments{{Type: addressType}, {Type: bytesType}, {Type: bytesType}}
+)
+
+// validatorCode is the creation code run by an eth_call without a target to
+// validate the signature of an undeployed wallet. The constructor takes its
+// arguments appended to the code:
+//
+//	signer (32) | factory (32) | len(factoryCalldata) (32) | factoryCalldata | isValidSignature calldata
+//
+// If signer has no code it calls factory with factoryCalldata to deploy it,
+// then it calls signer with the isValidSignature calldata and returns a
+// 32-byte boolean, whether the magic value was returned. The eth_call
+// changes no state, the wallet stays undeployed.
+var validatorCode = common.FromHex("0x61005b380361005b6000396000513b60235760006000604051606060006020515af1505b6020600061005b380360405160600190036040516060016000515afa60203d10151660005160e01c631626ba7e141660005260206000f3")
+
+// Verifier checks signatures of smart contract wallets, such as Safe, with
+// EIP-1271 isValidSignature. Signatures of counterfactual wallets, which
+// are not deployed yet, are wrapped as described in EIP-6492.
+type Verifier struct {
+	caller bind.ContractCaller
+}
+
+func NewVerifier(caller bind.ContractCaller) *Verifier {
+	return &Verifier{caller: caller}
+}
+
+// IsValidSignature reports whether the wallet at account accepts the
+// signature of hash. It returns false for accounts without code, which are
+// verified by ecrecover.
+func (v *Verifier) IsValidSignature(ctx context.Context, account common.Address, hash common.Hash, signature []byte) (bool, error) {
+	code, err := v.caller.CodeAt(ctx, account, nil)
+	if err != nil {
+		return false, contract.WrapError(err)
+	}
+
+	if !bytes.HasSuffix(signature, erc6492MagicSuffix) {
+		if len(code) == 0 {
+			return false, nil
+		}
+		return v.isValidSignature(ctx, account, hash, signature)
+	}
+
+	values, err := erc6492Args.Unpack(signature[:len(signature)-len(erc6492MagicSuffix)])
+	if err != nil {
+		return false, xerrors.Errorf("malformed EIP-6492 signature: %w", err)
+	}
+	factory := values[0].(common.Address)
+	factoryCalldata := values[1].([]byte)
+	signature = values[2].([]byte)
+
+	// a deployed wallet checks the inner signature itself
+	if len(code) != 0 {
+		return v.isValidSignature(ctx, account, hash, signature)
+	}
+
+	calldata, err := isValidSignatureCalldata(hash, signature)
+	if err != nil {
+		return false, err
+	}
+
+	data := append([]byte{}, validatorCode...)
+	data = append(data, common.LeftPadBytes(account.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(factory.Bytes(), 32)...)
+	data = append(data, common.BigToHash(big.NewInt(int64(len(factoryCalldata)))).Bytes()...)
+	data = append(data, factoryCalldata...)
+	data = append(data, calldata...)
+
+	out, err := v.caller.CallContract(ctx, ethereum.CallMsg{Data: data}, nil)
+	if err != nil {
+		return false, contract.WrapError(err)
+	}
+	return len(out) == 32 && out[31] == 1, nil
+}
+
+func (v *Verifier) isValidSignature(ctx context.Context, account common.Address, hash common.Hash, signature []byte) (bool, error) {
+	calldata, err := isValidSignatureCalldata(hash, signature)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := v.caller.CallContract(ctx, ethereum.CallMsg{To: &account, Data: calldata}, nil)
+	if err != nil {
+		// wallets such as Safe revert on invalid signatures
+		var dataErr rpc.DataError
+		if errors.As(err, &dataErr) {
+			return false, nil
+		}
+		return false, contract.WrapError(err)
+	}
+	return len(out) == 32 && bytes.Equal(out[:4], erc1271MagicValue), nil
+}
+
+func isValidSignatureCalldata(hash common.Hash, signature []byte) ([]byte, error) {
+	args, err := isValidSignatureArgs.Pack([32]byte(hash), signature)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, isValidSignatureSelector...), args...), nil
+}
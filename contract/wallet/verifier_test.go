@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+var (
+	// walletRuntime is a mock EIP-1271 wallet: isValidSignature(hash, sig)
+	// reverts if sig is empty and returns the magic value if the first 32
+	// bytes of sig are hash.
+	//
+	//	PUSH1 0x44 CALLDATALOAD ISZERO PUSH1 0x27 JUMPI            // len(sig) == 0: revert
+	//	PUSH1 0x64 CALLDATALOAD PUSH1 0x04 CALLDATALOAD EQ         // sig[:32] == hash
+	//	PUSH1 0x16 JUMPI PUSH1 0x20 PUSH1 0x00 RETURN              // no: return 0
+	//	JUMPDEST PUSH4 0x1626ba7e PUSH1 0xe0 SHL PUSH1 0x00 MSTORE
+	//	PUSH1 0x20 PUSH1 0x00 RETURN                               // yes: return the magic value
+	//	JUMPDEST PUSH1 0x00 DUP1 REVERT
+	walletRuntime = common.FromHex("0x604435156027576064356004351460165760206000f35b631626ba7e60e01b60005260206000f35b600080fd")
+	// walletInit deploys walletRuntime
+	//
+	//	PUSH1 0x2c PUSH1 0x0c PUSH1 0x00 CODECOPY PUSH1 0x2c PUSH1 0x00 RETURN
+	walletInit = append(common.FromHex("0x602c600c600039602c6000f3"), walletRuntime...)
+	// factoryRuntime deploys a wallet with CREATE2, the calldata is the salt,
+	// and returns its address.
+	//
+	//	PUSH1 0x38 PUSH1 0x19 PUSH1 0x00 CODECOPY                  // walletInit
+	//	PUSH1 0x00 CALLDATALOAD PUSH1 0x38 PUSH1 0x00 PUSH1 0x00 CREATE2
+	//	PUSH1 0x00 MSTORE PUSH1 0x20 PUSH1 0x00 RETURN
+	factoryRuntime = append(common.FromHex("0x60386019600039600035603860006000f560005260206000f3"), walletInit...)
+)
+
+var (
+	deployedWallet = common.HexToAddress("0x1271000000000000000000000000000000000001")
+	factory        = common.HexToAddress("0x6492000000000000000000000000000000000001")
+	eoa            = common.HexToAddress("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+)
+
+func newTestVerifier(t *testing.T) (*Verifier, *simulated.Backend) {
+	t.Helper()
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		deployedWallet: {Code: walletRuntime},
+		factory:        {Code: factoryRuntime},
+	})
+	t.Cleanup(func() { backend.Close() })
+	return NewVerifier(backend.Client()), backend
+}
+
+// counterfactual returns the address the factory deploys the wallet of salt
+// at and the EIP-6492 wrapping of signature.
+func counterfactual(t *testing.T, salt common.Hash, signature []byte) (common.Address, []byte) {
+	t.Helper()
+	account := crypto.CreateAddress2(factory, salt, crypto.Keccak256(walletInit))
+	wrapped, err := erc6492Args.Pack(factory, salt.Bytes(), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account, append(wrapped, erc6492MagicSuffix...)
+}
+
+func TestVerifierDeployed(t *testing.T) {
+	verifier, _ := newTestVerifier(t)
+	ctx := context.Background()
+	hash := crypto.Keccak256Hash([]byte("login"))
+
+	for name, tc := range map[string]struct {
+		account   common.Address
+		signature []byte
+		valid     bool
+	}{
+		"valid":       {deployedWallet, hash.Bytes(), true},
+		"invalid":     {deployedWallet, crypto.Keccak256([]byte("other")), false},
+		"revert":      {deployedWallet, nil, false},
+		"no code":     {eoa, hash.Bytes(), false},
+		"not wallet":  {factory, hash.Bytes(), false},
+		"short":       {deployedWallet, hash.Bytes()[:4], false},
+		"with suffix": {deployedWallet, append(hash.Bytes(), 0x01), true},
+	} {
+		valid, err := verifier.IsValidSignature(ctx, tc.account, hash, tc.signature)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if valid != tc.valid {
+			t.Errorf("%s: valid %v, expected %v", name, valid, tc.valid)
+		}
+	}
+}
+
+func TestVerifierCounterfactual(t *testing.T) {
+	verifier, backend := newTestVerifier(t)
+	ctx := context.Background()
+	hash := crypto.Keccak256Hash([]byte("login"))
+	salt := common.HexToHash("0x01")
+
+	account, signature := counterfactual(t, salt, hash.Bytes())
+	valid, err := verifier.IsValidSignature(ctx, account, hash, signature)
+	if err != nil || !valid {
+		t.Fatalf("valid signature: %v, %v", valid, err)
+	}
+
+	_, signature = counterfactual(t, salt, crypto.Keccak256([]byte("other")))
+	valid, err = verifier.IsValidSignature(ctx, account, hash, signature)
+	if err != nil || valid {
+		t.Fatalf("invalid signature: %v, %v", valid, err)
+	}
+
+	_, signature = counterfactual(t, salt, nil)
+	valid, err = verifier.IsValidSignature(ctx, account, hash, signature)
+	if err != nil || valid {
+		t.Fatalf("reverting wallet: %v, %v", valid, err)
+	}
+
+	// the factory deploys another wallet than the signer
+	_, signature = counterfactual(t, common.HexToHash("0x02"), hash.Bytes())
+	valid, err = verifier.IsValidSignature(ctx, account, hash, signature)
+	if err != nil || valid {
+		t.Fatalf("other wallet: %v, %v", valid, err)
+	}
+
+	// the eth_call doesn't deploy the wallet
+	code, err := backend.Client().CodeAt(ctx, account, nil)
+	if err != nil || len(code) != 0 {
+		t.Fatalf("wallet deployed by the verification: %x, %v", code, err)
+	}
+}
+
+func TestVerifierCounterfactualDeployed(t *testing.T) {
+	verifier, _ := newTestVerifier(t)
+	ctx := context.Background()
+	hash := crypto.Keccak256Hash([]byte("login"))
+
+	// a wallet deployed since it signed checks the inner signature
+	_, signature := counterfactual(t, common.HexToHash("0x01"), hash.Bytes())
+	valid, err := verifier.IsValidSignature(ctx, deployedWallet, hash, signature)
+	if err != nil || !valid {
+		t.Fatalf("valid signature: %v, %v", valid, err)
+	}
+
+	_, signature = counterfactual(t, common.HexToHash("0x01"), crypto.Keccak256([]byte("other")))
+	valid, err = verifier.IsValidSignature(ctx, deployedWallet, hash, signature)
+	if err != nil || valid {
+		t.Fatalf("invalid signature: %v, %v", valid, err)
+	}
+}
+
+func TestVerifierMalformed(t *testing.T) {
+	verifier, _ := newTestVerifier(t)
+	signature := append(bytes.Repeat([]byte{0x01}, 40), erc6492MagicSuffix...)
+	if _, err := verifier.IsValidSignature(context.Background(), eoa, common.Hash{}, signature); err == nil {
+		t.Fatal("malformed EIP-6492 signature accepted")
+	}
+}
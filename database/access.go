@@ -0,0 +1,43 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AccessOwner     = "owner"
+	AccessPublic    = "public"
+	AccessHolders   = "holders"
+	AccessAllowlist = "allowlist"
+)
+
+// AccessPolicy decides who besides the owner can read a dataNFT's content.
+type AccessPolicy struct {
+	TokenID int64  `gorm:"primaryKey;autoIncrement:false"`
+	Mode    string `gorm:"size:16"`
+	// holders mode: the ERC-20/ERC-721 contract and the minimum balance
+	Contract   string `gorm:"size:42"`
+	MinBalance string
+	// allowlist mode: comma separated addresses
+	Allowlist string
+	// the policy falls back to owner-only after it expires, nil never expires
+	ExpireAt  *time.Time
+	UpdatedAt time.Time
+}
+
+// GetAccessPolicy returns the token's policy, owner-only if none is set.
+func (s *DataStore) GetAccessPolicy(tokenID int64) (AccessPolicy, error) {
+	var policy AccessPolicy
+	err := s.db.Where("token_id = ?", tokenID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return AccessPolicy{TokenID: tokenID, Mode: AccessOwner}, nil
+	}
+	return policy, err
+}
+
+func (s *DataStore) SaveAccessPolicy(policy *AccessPolicy) error {
+	return s.db.Save(policy).Error
+}
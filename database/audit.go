@@ -0,0 +1,51 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog records an action taken with the admin APIs.
+type AuditLog struct {
+	ID uint `gorm:"primaryKey"`
+	// the address of the operator or admin, "config" for the admins granted
+	// at startup
+	Actor  string `gorm:"index;size:64"`
+	Action string `gorm:"index;size:32"`
+	// the address, user or project acted on
+	Target string `gorm:"index;size:64"`
+	// json encoded parameters of the action
+	Detail    string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// ListAuditLogs returns the audit logs, newest first, of the action or of
+// all actions if it is empty.
+func (s *DataStore) ListAuditLogs(action string, page, size int) ([]AuditLog, error) {
+	query := s.db.Model(&AuditLog{})
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var logs []AuditLog
+	err := query.Order("id desc").
+		Offset((page - 1) * size).Limit(size).
+		Find(&logs).Error
+	return logs, err
+}
+
+// writeAudit records the action in the transaction taking it.
+func writeAudit(tx *gorm.DB, actor, action, target string, detail interface{}) error {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&AuditLog{
+		Actor:  actor,
+		Action: action,
+		Target: target,
+		Detail: string(data),
+	}).Error
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/glebarez/sqlite"
+	"golang.org/x/xerrors"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	ErrNotFound           = xerrors.New("record not found")
+	ErrInsufficientPoints = xerrors.New("insufficient points")
+	ErrMintQuotaExceeded  = xerrors.New("daily mint quota exceeded")
+	ErrChargeTooFrequent  = xerrors.New("charge too frequent")
+	ErrStorageCapExceeded = xerrors.New("storage cap exceeded")
+	ErrPrimaryWallet      = xerrors.New("the primary wallet can't be unlinked")
+)
+
+type DataStore struct {
+	db *gorm.DB
+
+	// unix seconds of the last sweep of the expired nonces
+	lastNonceSweep atomic.Int64
+	// unix seconds of the last sweep of the full rate limit buckets
+	lastBucketSweep atomic.Int64
+	// unix seconds of the last sweep of the realtime events read
+	lastRealtimeSweep atomic.Int64
+}
+
+func NewDataStore(driver, dsn string) (*DataStore, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case "sqlite", "":
+		dialector = sqlite.Open(dsn)
+	case "mysql":
+		dialector = mysql.Open(dsn)
+	default:
+		return nil, xerrors.Errorf("unsupported database driver %s", driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Use(tracingPlugin{}); err != nil {
+		return nil, err
+	}
+
+	if driver != "mysql" {
+		// sqlite allows only one writer, serialize all transactions
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	err = db.AutoMigrate(
+		&UserPoint{},
+		&PointRecord{},
+		&MintJob{},
+		&NFT{},
+		&UserStorage{},
+		&UserKey{},
+		&EncryptedKey{},
+		&AccessPolicy{},
+		&Session{},
+		&Nonce{},
+		&User{},
+		&Identity{},
+		&Role{},
+		&Ban{},
+		&AuditLog{},
+		&Project{},
+		&PartnerKey{},
+		&ScoreEvent{},
+		&ProjectScore{},
+		&WebhookSubscription{},
+		&WebhookDelivery{},
+		&WebhookAttempt{},
+		&ReferCode{},
+		&Referral{},
+		&OutboxEvent{},
+		&RateBucket{},
+		&RealtimeEvent{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataStore{db: db}, nil
+}
+
+// Ping checks the connection to the database.
+func (s *DataStore) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *DataStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
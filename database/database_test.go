@@ -0,0 +1,28 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testStores returns a store on a new sqlite database and, if
+// XSPACE_TEST_MYSQL_DSN is set, one on that mysql database.
+func testStores(t *testing.T) map[string]*DataStore {
+	t.Helper()
+	dsns := map[string]string{"sqlite": filepath.Join(t.TempDir(), "test.db")}
+	if dsn := os.Getenv("XSPACE_TEST_MYSQL_DSN"); dsn != "" {
+		dsns["mysql"] = dsn
+	}
+
+	stores := make(map[string]*DataStore)
+	for driver, dsn := range dsns {
+		store, err := NewDataStore(driver, dsn)
+		if err != nil {
+			t.Fatalf("%s: %s", driver, err)
+		}
+		t.Cleanup(func() { store.Close() })
+		stores[driver] = store
+	}
+	return stores
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserKey is the secp256k1 public key recovered when the user logs in.
+type UserKey struct {
+	Address   string `gorm:"primaryKey;size:42"`
+	PublicKey []byte
+	UpdatedAt time.Time
+}
+
+// EncryptedKey is the key of an encrypted dataNFT object. SealedKey is
+// encrypted with the server's master key, WrappedKey is encrypted to the
+// owner's public key and is empty until the owner's public key is known.
+type EncryptedKey struct {
+	ObjectKey  string `gorm:"primaryKey"`
+	SealedKey  []byte
+	Owner      string `gorm:"index;size:42"`
+	WrappedKey []byte
+	UpdatedAt  time.Time
+}
+
+func (s *DataStore) SavePublicKey(address string, publicKey []byte) error {
+	return s.db.Save(&UserKey{Address: address, PublicKey: publicKey}).Error
+}
+
+func (s *DataStore) GetPublicKey(address string) ([]byte, error) {
+	var key UserKey
+	err := s.db.Where("address = ?", address).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return key.PublicKey, err
+}
+
+func (s *DataStore) SaveEncryptedKey(key *EncryptedKey) error {
+	return s.db.Save(key).Error
+}
+
+func (s *DataStore) GetEncryptedKey(objectKey string) (EncryptedKey, error) {
+	var key EncryptedKey
+	err := s.db.Where("object_key = ?", objectKey).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, ErrNotFound
+	}
+	return key, err
+}
+
+func (s *DataStore) DeleteEncryptedKey(objectKey string) error {
+	return s.db.Where("object_key = ?", objectKey).Delete(&EncryptedKey{}).Error
+}
+
+// ListUnwrappedKeys returns the keys of objects owned by the address that
+// are not yet wrapped to its public key.
+func (s *DataStore) ListUnwrappedKeys(owner string) ([]EncryptedKey, error) {
+	var keys []EncryptedKey
+	err := s.db.Where("owner = ? AND (wrapped_key IS NULL OR length(wrapped_key) = 0)", owner).Find(&keys).Error
+	return keys, err
+}
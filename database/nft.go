@@ -0,0 +1,368 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/memoio/xspace-server/event"
+	"gorm.io/gorm"
+)
+
+const (
+	TweetNFT = 1
+	DataNFT  = 2
+)
+
+const (
+	MintPending = iota
+	MintDone
+	MintFailed
+)
+
+type MintJob struct {
+	ID      uint   `gorm:"primaryKey"`
+	Address string `gorm:"index;size:42"`
+	Type    int
+	Status  int `gorm:"index"`
+	TokenID int64
+	Cost    int64
+	Error   string
+
+	// tweetNFT content
+	Name     string
+	PostTime int64
+	Tweet    string
+	Images   string
+
+	// dataNFT content
+	ObjectKey string
+	FileName  string
+	Size      int64
+	Encrypted bool
+
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+type NFT struct {
+	TokenID    int64  `gorm:"primaryKey;autoIncrement:false"`
+	Address    string `gorm:"index;size:42"`
+	Type       int
+	JobID      uint
+	CreateTime time.Time
+}
+
+// MintLimits bounds the jobs an address can create, 0 means unlimited.
+type MintLimits struct {
+	// jobs of the same type per day
+	DailyQuota int64
+	// dataNFT objects and bytes kept in storage
+	MaxObjects int64
+	MaxSpace   int64
+}
+
+// CreateMintJob debits the job's cost from the owner's points, accounts the
+// dataNFT object in the owner's storage usage and stores the job in one
+// transaction.
+func (s *DataStore) CreateMintJob(job *MintJob, limits MintLimits) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUserPoint(tx, job.Address)
+		if err != nil {
+			return err
+		}
+
+		if limits.DailyQuota > 0 {
+			count, err := countMintJobs(tx, job.Address, job.Type, startOfDay(time.Now()))
+			if err != nil {
+				return err
+			}
+			if count >= limits.DailyQuota {
+				return ErrMintQuotaExceeded
+			}
+		}
+
+		if job.Type == DataNFT {
+			err := addStorage(tx, job.Address, 1, job.Size, limits.MaxObjects, limits.MaxSpace)
+			if err != nil {
+				return err
+			}
+		}
+
+		if user.Points < job.Cost {
+			return ErrInsufficientPoints
+		}
+
+		if job.Cost > 0 {
+			if err := addPoints(tx, &user, -job.Cost, mintActionName(job.Type)); err != nil {
+				return err
+			}
+		}
+
+		job.Status = MintPending
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return writeEvent(tx, mintJobUpdated(job))
+	})
+}
+
+// CountMintJobs returns the number of non-failed jobs of nftType created by
+// the address since the start of today (UTC).
+func (s *DataStore) CountMintJobs(address string, nftType int) (int64, error) {
+	return countMintJobs(s.db, address, nftType, startOfDay(time.Now()))
+}
+
+func (s *DataStore) GetMintJob(id uint) (MintJob, error) {
+	var job MintJob
+	err := s.db.First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return job, ErrNotFound
+	}
+	return job, err
+}
+
+// CountPendingMintJobs returns the number of jobs waiting to be minted.
+func (s *DataStore) CountPendingMintJobs() (int64, error) {
+	var count int64
+	err := s.db.Model(&MintJob{}).Where("status = ?", MintPending).Count(&count).Error
+	return count, err
+}
+
+func (s *DataStore) ListPendingMintJobs(limit int) ([]MintJob, error) {
+	var jobs []MintJob
+	err := s.db.Where("status = ?", MintPending).Order("id asc").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// CompleteMintJob marks the job as done and records the minted NFT.
+func (s *DataStore) CompleteMintJob(job *MintJob, tokenID int64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		job.Status = MintDone
+		job.TokenID = tokenID
+		if err := tx.Save(job).Error; err != nil {
+			return err
+		}
+
+		err := tx.Create(&NFT{
+			TokenID:    tokenID,
+			Address:    job.Address,
+			Type:       job.Type,
+			JobID:      job.ID,
+			CreateTime: time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := writeEvent(tx, mintJobUpdated(job)); err != nil {
+			return err
+		}
+
+		return writeEvent(tx, event.NFTMinted{
+			JobID:   job.ID,
+			TokenID: tokenID,
+			NFTType: job.Type,
+			Address: job.Address,
+		})
+	})
+}
+
+// FailMintJob marks the job as failed, refunds its cost and releases its
+// storage usage.
+func (s *DataStore) FailMintJob(job *MintJob, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		job.Status = MintFailed
+		job.Error = reason
+		if err := tx.Save(job).Error; err != nil {
+			return err
+		}
+		if err := writeEvent(tx, mintJobUpdated(job)); err != nil {
+			return err
+		}
+
+		if job.Type == DataNFT {
+			if err := addStorage(tx, job.Address, -1, -job.Size, 0, 0); err != nil {
+				return err
+			}
+		}
+
+		if job.Cost == 0 {
+			return nil
+		}
+		user, err := lockUserPoint(tx, job.Address)
+		if err != nil {
+			return err
+		}
+		return addPoints(tx, &user, job.Cost, "refund failed mint")
+	})
+}
+
+// BurnNFT deletes the NFT owned by address with its access policy and the
+// keys of its encrypted content, and releases its storage usage. The mint job is returned so that the caller
+// can remove the content.
+func (s *DataStore) BurnNFT(tokenID int64, address string) (MintJob, error) {
+	var job MintJob
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var nft NFT
+		err := tx.Where("token_id = ? AND address = ?", tokenID, address).First(&nft).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&job, nft.JobID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&nft).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("token_id = ?", tokenID).Delete(&AccessPolicy{}).Error; err != nil {
+			return err
+		}
+
+		if nft.Type == DataNFT {
+			if err := addStorage(tx, nft.Address, -1, -job.Size, 0, 0); err != nil {
+				return err
+			}
+		}
+		if job.Encrypted {
+			// the sealed copy too, nothing must decrypt a leftover copy of
+			// the content
+			if err := tx.Where("object_key = ?", job.ObjectKey).Delete(&EncryptedKey{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return writeEvent(tx, event.NFTBurned{TokenID: tokenID, NFTType: nft.Type, Address: address})
+	})
+
+	return job, err
+}
+
+func (s *DataStore) GetNFT(tokenID int64) (NFT, MintJob, error) {
+	var nft NFT
+	var job MintJob
+	err := s.db.Where("token_id = ?", tokenID).First(&nft).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nft, job, ErrNotFound
+	}
+	if err != nil {
+		return nft, job, err
+	}
+
+	err = s.db.First(&job, nft.JobID).Error
+	return nft, job, err
+}
+
+// TransferNFT changes the owner of the NFT from one address to another and
+// moves the storage usage of a dataNFT with it, the storage caps don't apply
+// since the transfer already happened on chain. The access policy set by the
+// previous owner is dropped, the new owner starts owner-only. The key of an
+// encrypted dataNFT is handed to the new owner but stays unwrapped until it
+// is wrapped to the new owner's public key.
+func (s *DataStore) TransferNFT(tokenID int64, from, to string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var nft NFT
+		err := tx.Where("token_id = ? AND address = ?", tokenID, from).First(&nft).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&nft).Update("address", to).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("token_id = ?", tokenID).Delete(&AccessPolicy{}).Error; err != nil {
+			return err
+		}
+		err = writeEvent(tx, event.NFTTransferred{TokenID: tokenID, NFTType: nft.Type, From: from, To: to})
+		if err != nil {
+			return err
+		}
+
+		var job MintJob
+		if err := tx.First(&job, nft.JobID).Error; err != nil {
+			return err
+		}
+		if nft.Type == DataNFT {
+			if err := addStorage(tx, from, -1, -job.Size, 0, 0); err != nil {
+				return err
+			}
+			if err := addStorage(tx, to, 1, job.Size, 0, 0); err != nil {
+				return err
+			}
+		}
+		if !job.Encrypted {
+			return nil
+		}
+		return tx.Model(&EncryptedKey{}).Where("object_key = ?", job.ObjectKey).
+			Updates(map[string]interface{}{"owner": to, "wrapped_key": nil}).Error
+	})
+}
+
+// ListNFTs returns the NFTs owned by the addresses, e.g. the wallets of a
+// user, of nftType or of all types if it is 0.
+func (s *DataStore) ListNFTs(addresses []string, nftType int, page, size int, asc bool) ([]NFT, error) {
+	query := s.db.Where("address IN ?", addresses)
+	if nftType != 0 {
+		query = query.Where("type = ?", nftType)
+	}
+
+	var nfts []NFT
+	order := "create_time desc"
+	if asc {
+		order = "create_time asc"
+	}
+	err := query.Order(order).Order("token_id asc").
+		Offset((page - 1) * size).Limit(size).
+		Find(&nfts).Error
+	return nfts, err
+}
+
+func (s *DataStore) LastTokenID() (int64, error) {
+	var tokenID int64
+	err := s.db.Model(&NFT{}).Select("coalesce(max(token_id), 0)").Scan(&tokenID).Error
+	return tokenID, err
+}
+
+func countMintJobs(tx *gorm.DB, address string, nftType int, since time.Time) (int64, error) {
+	var count int64
+	err := tx.Model(&MintJob{}).
+		Where("address = ? AND type = ? AND status <> ? AND created_at >= ?", address, nftType, MintFailed, since).
+		Count(&count).Error
+	return count, err
+}
+
+func mintJobUpdated(job *MintJob) event.MintJobUpdated {
+	status := "pending"
+	switch job.Status {
+	case MintDone:
+		status = "done"
+	case MintFailed:
+		status = "failed"
+	}
+
+	return event.MintJobUpdated{
+		JobID:   job.ID,
+		Address: job.Address,
+		NFTType: job.Type,
+		Status:  status,
+		TokenID: job.TokenID,
+		Error:   job.Error,
+	}
+}
+
+func mintActionName(nftType int) string {
+	if nftType == DataNFT {
+		return "mint data nft"
+	}
+	return "mint tweet nft"
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
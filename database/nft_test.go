@@ -0,0 +1,92 @@
+package database
+
+import "testing"
+
+const (
+	alice = "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+	bob   = "0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2"
+)
+
+// mintDataNFT records a dataNFT of the address minted with the token id.
+func mintDataNFT(t *testing.T, store *DataStore, address string, tokenID int64, encrypted bool) MintJob {
+	t.Helper()
+	job := MintJob{
+		Address:   address,
+		Type:      DataNFT,
+		ObjectKey: "data/object",
+		FileName:  "object",
+		Size:      10,
+		Encrypted: encrypted,
+	}
+	if err := store.CreateMintJob(&job, MintLimits{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompleteMintJob(&job, tokenID); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestTransferNFTAccessPolicy(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			mintDataNFT(t, store, alice, 1, false)
+			err := store.SaveAccessPolicy(&AccessPolicy{TokenID: 1, Mode: AccessAllowlist, Allowlist: bob})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := store.TransferNFT(1, alice, bob); err != nil {
+				t.Fatal(err)
+			}
+			policy, err := store.GetAccessPolicy(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if policy.Mode != AccessOwner || policy.Allowlist != "" {
+				t.Fatalf("policy after transfer: %+v, expected owner-only", policy)
+			}
+		})
+	}
+}
+
+func TestBurnNFTAccessPolicy(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			mintDataNFT(t, store, alice, 1, false)
+			if err := store.SaveAccessPolicy(&AccessPolicy{TokenID: 1, Mode: AccessPublic}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := store.BurnNFT(1, alice); err != nil {
+				t.Fatal(err)
+			}
+			var count int64
+			if err := store.db.Model(&AccessPolicy{}).Where("token_id = ?", 1).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Fatal("access policy kept after burn")
+			}
+		})
+	}
+}
+
+func TestBurnNFTEncryptedKey(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			job := mintDataNFT(t, store, alice, 1, true)
+			err := store.SaveEncryptedKey(&EncryptedKey{ObjectKey: job.ObjectKey, SealedKey: []byte("sealed"), Owner: alice, WrappedKey: []byte("wrapped")})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := store.BurnNFT(1, alice); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.GetEncryptedKey(job.ObjectKey); err != ErrNotFound {
+				t.Fatalf("encrypted key after burn: %v, expected ErrNotFound", err)
+			}
+		})
+	}
+}
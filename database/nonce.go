@@ -0,0 +1,78 @@
+package database
+
+import (
+	"time"
+
+	auth "github.com/memoio/xspace-server/authentication"
+)
+
+type Nonce struct {
+	Nonce    string `gorm:"primaryKey;size:32"`
+	Provider string `gorm:"size:16"`
+	Subject  string `gorm:"size:66"`
+	Domain   string
+	URI      string
+	Address  string `gorm:"size:64"`
+	ChainID  int
+	IssuedAt time.Time
+	ExpireAt time.Time `gorm:"index"`
+}
+
+var _ auth.NonceStore = (*DataStore)(nil)
+
+func (s *DataStore) PutNonce(nonce string, challenge auth.Challenge) error {
+	// drop expired nonces at most once a minute
+	now := time.Now()
+	last := s.lastNonceSweep.Load()
+	if now.Unix()-last >= 60 && s.lastNonceSweep.CompareAndSwap(last, now.Unix()) {
+		if err := s.db.Where("expire_at <= ?", now).Delete(&Nonce{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return s.db.Create(&Nonce{
+		Nonce:    nonce,
+		Provider: challenge.Provider,
+		Subject:  challenge.Subject,
+		Domain:   challenge.Domain,
+		URI:      challenge.URI,
+		Address:  challenge.Address,
+		ChainID:  challenge.ChainID,
+		IssuedAt: challenge.IssuedAt,
+		ExpireAt: challenge.ExpireAt,
+	}).Error
+}
+
+func (s *DataStore) ConsumeNonce(nonce string) (auth.Challenge, bool, error) {
+	var n Nonce
+	res := s.db.Where("nonce = ? AND expire_at > ?", nonce, time.Now()).Limit(1).Find(&n)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return auth.Challenge{}, false, res.Error
+	}
+
+	// only the request deleting the row may use the nonce, so concurrent
+	// logins can't use one nonce twice
+	res = s.db.Where("nonce = ?", nonce).Delete(&Nonce{})
+	if res.Error != nil || res.RowsAffected != 1 {
+		return auth.Challenge{}, false, res.Error
+	}
+
+	return auth.Challenge{
+		Provider: n.Provider,
+		Subject:  n.Subject,
+		Domain:   n.Domain,
+		URI:      n.URI,
+		Address:  n.Address,
+		ChainID:  n.ChainID,
+		IssuedAt: n.IssuedAt,
+		ExpireAt: n.ExpireAt,
+	}, true, nil
+}
+
+// CountNonces returns the number of nonces kept, including expired ones not
+// swept yet.
+func (s *DataStore) CountNonces() (int64, error) {
+	var count int64
+	err := s.db.Model(&Nonce{}).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	auth "github.com/memoio/xspace-server/authentication"
+)
+
+func testChallenge(ttl time.Duration) auth.Challenge {
+	now := time.Now().UTC().Truncate(time.Second)
+	return auth.Challenge{
+		Provider: "siwe",
+		Domain:   "xspace.example",
+		URI:      "https://xspace.example/login",
+		Address:  "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4",
+		ChainID:  985,
+		IssuedAt: now,
+		ExpireAt: now.Add(ttl),
+	}
+}
+
+func TestNonceConsume(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			challenge := testChallenge(time.Minute)
+			if err := store.PutNonce("consume", challenge); err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok, err := store.ConsumeNonce("consume")
+			if err != nil || !ok {
+				t.Fatalf("consume: %v, %v", ok, err)
+			}
+			if !got.IssuedAt.Equal(challenge.IssuedAt) || !got.ExpireAt.Equal(challenge.ExpireAt) {
+				t.Fatalf("times %v %v, expected %v %v", got.IssuedAt, got.ExpireAt, challenge.IssuedAt, challenge.ExpireAt)
+			}
+			got.IssuedAt, got.ExpireAt = challenge.IssuedAt, challenge.ExpireAt
+			if got != challenge {
+				t.Fatalf("challenge %+v, expected %+v", got, challenge)
+			}
+
+			if _, ok, err := store.ConsumeNonce("consume"); err != nil || ok {
+				t.Fatalf("second consume: %v, %v", ok, err)
+			}
+			if _, ok, err := store.ConsumeNonce("unknown"); err != nil || ok {
+				t.Fatalf("unknown nonce: %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestNonceConsumeConcurrent(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				nonce := fmt.Sprintf("concurrent%d", i)
+				if err := store.PutNonce(nonce, testChallenge(time.Minute)); err != nil {
+					t.Fatal(err)
+				}
+
+				var wg sync.WaitGroup
+				var consumed atomic.Int32
+				for j := 0; j < 16; j++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, ok, err := store.ConsumeNonce(nonce)
+						if err != nil {
+							t.Error(err)
+						}
+						if ok {
+							consumed.Add(1)
+						}
+					}()
+				}
+				wg.Wait()
+
+				if n := consumed.Load(); n != 1 {
+					t.Fatalf("nonce consumed %d times", n)
+				}
+			}
+		})
+	}
+}
+
+func TestNonceExpiry(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			if err := store.PutNonce("expired", testChallenge(-time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, err := store.ConsumeNonce("expired"); err != nil || ok {
+				t.Fatalf("expired nonce: %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestNonceSweep(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				if err := store.PutNonce(fmt.Sprintf("old%d", i), testChallenge(-time.Second)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// the first put swept, the next ones within the minute don't
+			if count, err := store.CountNonces(); err != nil || count != 3 {
+				t.Fatalf("%d nonces before the sweep, %v", count, err)
+			}
+
+			store.lastNonceSweep.Store(time.Now().Add(-time.Minute).Unix())
+			if err := store.PutNonce("new", testChallenge(time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if count, err := store.CountNonces(); err != nil || count != 1 {
+				t.Fatalf("%d nonces after the sweep, %v", count, err)
+			}
+			if _, ok, err := store.ConsumeNonce("new"); err != nil || !ok {
+				t.Fatalf("consume after the sweep: %v, %v", ok, err)
+			}
+		})
+	}
+}
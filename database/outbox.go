@@ -0,0 +1,78 @@
+package database
+
+import (
+	"time"
+
+	"github.com/memoio/xspace-server/event"
+	"gorm.io/gorm"
+)
+
+// OutboxEvent is an event written in the transaction of its state change,
+// it is removed once dispatched by the event bus.
+type OutboxEvent struct {
+	ID      uint   `gorm:"primaryKey"`
+	EventID string `gorm:"uniqueIndex;size:32"`
+	Type    string `gorm:"size:32"`
+	Project uint
+	Payload []byte
+	// the bus dispatching the event owns it until then
+	ClaimedUntil time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+// ClaimOutbox returns up to limit events, oldest first, that no bus is
+// dispatching and claims them for the lease.
+func (s *DataStore) ClaimOutbox(limit int, lease time.Duration) ([]event.Record, error) {
+	now := time.Now()
+
+	var events []OutboxEvent
+	err := s.db.Where("claimed_until < ?", now).Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]event.Record, 0, len(events))
+	for _, e := range events {
+		// another bus may have claimed the event since
+		result := s.db.Model(&OutboxEvent{}).
+			Where("id = ? AND claimed_until < ?", e.ID, now).
+			Update("claimed_until", now.Add(lease))
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// keep the order, the rest is claimed later
+			break
+		}
+
+		records = append(records, event.Record{
+			ID:        e.ID,
+			EventID:   e.EventID,
+			Type:      e.Type,
+			Project:   e.Project,
+			Payload:   e.Payload,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return records, nil
+}
+
+func (s *DataStore) DeleteOutbox(id uint) error {
+	return s.db.Delete(&OutboxEvent{}, id).Error
+}
+
+// writeEvent adds the event to the outbox in the transaction making the
+// state change.
+func writeEvent(tx *gorm.DB, e event.Event) error {
+	record, err := event.Encode(e)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&OutboxEvent{
+		EventID: record.EventID,
+		Type:    record.Type,
+		Project: record.Project,
+		Payload: record.Payload,
+	}).Error
+}
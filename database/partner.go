@@ -0,0 +1,79 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PartnerKey is an API key a cooperative project reports scores with. Only
+// the hash of its secret is kept.
+type PartnerKey struct {
+	ID         string `gorm:"primaryKey;size:32"`
+	ProjectID  uint   `gorm:"index"`
+	Name       string
+	SecretHash string `gorm:"size:64"`
+	// requests per minute
+	RateLimit int
+	Revoked   bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (s *DataStore) CreatePartnerKey(actor string, key *PartnerKey) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var project Project
+		err := tx.First(&project, key.ProjectID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, actor, "partner_key.create", key.ID, map[string]interface{}{
+			"project":   key.ProjectID,
+			"name":      key.Name,
+			"rateLimit": key.RateLimit,
+		})
+	})
+}
+
+func (s *DataStore) GetPartnerKey(id string) (PartnerKey, error) {
+	var key PartnerKey
+	err := s.db.Where("id = ?", id).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, ErrNotFound
+	}
+	return key, err
+}
+
+// ListPartnerKeys returns the keys of the project, or of all projects if
+// projectID is 0.
+func (s *DataStore) ListPartnerKeys(projectID uint) ([]PartnerKey, error) {
+	query := s.db.Model(&PartnerKey{})
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var keys []PartnerKey
+	err := query.Order("created_at desc").Find(&keys).Error
+	return keys, err
+}
+
+func (s *DataStore) RevokePartnerKey(actor, id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&PartnerKey{}).Where("id = ? AND revoked = ?", id, false).Update("revoked", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return writeAudit(tx, actor, "partner_key.revoke", id, nil)
+	})
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/memoio/xspace-server/event"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserPoint struct {
+	Address       string `gorm:"primaryKey;size:64"`
+	Points        int64
+	ChargingCount int
+	LastCharge    time.Time
+	UpdatedAt     time.Time
+}
+
+type PointRecord struct {
+	ID         uint   `gorm:"primaryKey"`
+	Address    string `gorm:"index;size:64"`
+	Point      int64
+	ActionName string
+	CreatedAt  time.Time
+}
+
+func (s *DataStore) GetUserPoint(address string) (UserPoint, error) {
+	var user UserPoint
+	err := s.db.Where("address = ?", address).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return UserPoint{Address: address}, nil
+	}
+	return user, err
+}
+
+// Charge credits reward points to the address if the last charge happened
+// at least interval ago.
+func (s *DataStore) Charge(address string, reward int64, interval time.Duration) (UserPoint, error) {
+	var user UserPoint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockUserPoint(tx, address)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if now.Sub(user.LastCharge) < interval {
+			return ErrChargeTooFrequent
+		}
+
+		user.ChargingCount++
+		user.LastCharge = now
+		if err := addPoints(tx, &user, reward, "charge"); err != nil {
+			return err
+		}
+
+		return writeEvent(tx, event.ChargeCompleted{
+			Address:       address,
+			Reward:        reward,
+			Balance:       user.Points,
+			ChargingCount: user.ChargingCount,
+		})
+	})
+
+	return user, err
+}
+
+// AddPoints credits (or debits if points is negative) the address's balance
+// and records the change in the point history.
+func (s *DataStore) AddPoints(address string, points int64, actionName string) (UserPoint, error) {
+	var user UserPoint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockUserPoint(tx, address)
+		if err != nil {
+			return err
+		}
+		if user.Points+points < 0 {
+			return ErrInsufficientPoints
+		}
+		return addPoints(tx, &user, points, actionName)
+	})
+
+	return user, err
+}
+
+// AdjustPoints credits or debits the address's balance on behalf of an
+// operator, the adjustment is audit logged.
+func (s *DataStore) AdjustPoints(actor, address string, points int64, reason string) (UserPoint, error) {
+	var user UserPoint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockUserPoint(tx, address)
+		if err != nil {
+			return err
+		}
+		if user.Points+points < 0 {
+			return ErrInsufficientPoints
+		}
+		if err := addPoints(tx, &user, points, "adjustment"); err != nil {
+			return err
+		}
+
+		return writeAudit(tx, actor, "points.adjust", address, map[string]interface{}{
+			"points": points,
+			"reason": reason,
+		})
+	})
+
+	return user, err
+}
+
+// ListPointRecords returns the point records of the addresses, e.g. the
+// wallets of a user.
+func (s *DataStore) ListPointRecords(addresses []string, page, size int, asc bool) ([]PointRecord, error) {
+	var records []PointRecord
+	err := s.db.Where("address IN ?", addresses).
+		Order(orderByCreatedAt(asc)).
+		Offset((page - 1) * size).Limit(size).
+		Find(&records).Error
+	return records, err
+}
+
+func lockUserPoint(tx *gorm.DB, address string) (UserPoint, error) {
+	var user UserPoint
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("address = ?", address).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return UserPoint{Address: address}, nil
+	}
+	return user, err
+}
+
+func addPoints(tx *gorm.DB, user *UserPoint, points int64, actionName string) error {
+	user.Points += points
+	if err := tx.Save(user).Error; err != nil {
+		return err
+	}
+
+	err := tx.Create(&PointRecord{
+		Address:    user.Address,
+		Point:      points,
+		ActionName: actionName,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return writeEvent(tx, event.PointsCredited{
+		Address: user.Address,
+		Points:  points,
+		Balance: user.Points,
+		Action:  actionName,
+	})
+}
+
+func orderByCreatedAt(asc bool) string {
+	if asc {
+		return "created_at asc"
+	}
+	return "created_at desc"
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Project is a cooperative project users earn scores in.
+type Project struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Start     time.Time `gorm:"column:start_time"`
+	End       time.Time `gorm:"column:end_time"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ListProjects returns the projects, the latest started first.
+func (s *DataStore) ListProjects() ([]Project, error) {
+	var projects []Project
+	err := s.db.Order("start_time desc").Order("id desc").Find(&projects).Error
+	return projects, err
+}
+
+func (s *DataStore) GetProject(id uint) (Project, error) {
+	var project Project
+	err := s.db.First(&project, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return project, ErrNotFound
+	}
+	return project, err
+}
+
+func (s *DataStore) CreateProject(actor string, project *Project) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, actor, "project.create", projectTarget(project.ID), project)
+	})
+}
+
+func (s *DataStore) UpdateProject(actor string, project *Project) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var current Project
+		err := tx.First(&current, project.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		project.CreatedAt = current.CreatedAt
+		if err := tx.Save(project).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, actor, "project.update", projectTarget(project.ID), project)
+	})
+}
+
+func (s *DataStore) DeleteProject(actor string, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&Project{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return writeAudit(tx, actor, "project.delete", projectTarget(id), nil)
+	})
+}
+
+func projectTarget(id uint) string {
+	return "project:" + strconv.FormatUint(uint64(id), 10)
+}
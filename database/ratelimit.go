@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/memoio/xspace-server/ratelimit"
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bucketWait is how long a request waits for the other requests taking
+// tokens from its bucket, it is denied once it is up.
+const bucketWait = time.Second
+
+// RateBucket is a token bucket of the rate limiter shared by the replicas.
+type RateBucket struct {
+	ID      string `gorm:"primaryKey;size:128"`
+	Tokens  float64
+	Updated time.Time
+	// when the bucket is full again and can be dropped
+	ExpireAt time.Time `gorm:"index"`
+}
+
+var _ ratelimit.Store = (*DataStore)(nil)
+
+func (s *DataStore) TakeToken(key string, rule ratelimit.Rule, now time.Time) (ratelimit.Result, error) {
+	// drop full buckets at most once a minute
+	last := s.lastBucketSweep.Load()
+	if now.Unix()-last >= 60 && s.lastBucketSweep.CompareAndSwap(last, now.Unix()) {
+		if err := s.db.Where("expire_at <= ?", now).Delete(&RateBucket{}).Error; err != nil {
+			return ratelimit.Result{}, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bucketWait)
+	defer cancel()
+
+	var result ratelimit.Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// a new bucket is full, concurrent requests creating it insert it
+		// once
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&RateBucket{
+			ID:       key,
+			Tokens:   float64(rule.Burst),
+			Updated:  now,
+			ExpireAt: now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// concurrent requests take the tokens of the bucket one at a time
+		var row RateBucket
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", key).First(&row).Error
+		if err != nil {
+			return err
+		}
+
+		var bucket ratelimit.Bucket
+		bucket, result = rule.Take(ratelimit.Bucket{Tokens: row.Tokens, Updated: row.Updated}, now)
+		return tx.Model(&RateBucket{}).Where("id = ?", key).Updates(map[string]interface{}{
+			"tokens":    bucket.Tokens,
+			"updated":   bucket.Updated,
+			"expire_at": rule.Full(bucket),
+		}).Error
+	})
+	if err != nil && ctx.Err() != nil {
+		return ratelimit.Result{}, xerrors.Errorf("rate bucket %s: %w", key, ratelimit.ErrContended)
+	}
+	return result, err
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/memoio/xspace-server/ratelimit"
+)
+
+func TestTakeTokenBurstRefill(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			rule := ratelimit.Rule{Rate: 60, Burst: 3}
+			now := time.Now()
+
+			for i := 0; i < 3; i++ {
+				res, err := store.TakeToken("key", rule, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !res.Allowed || res.Remaining != 2-i {
+					t.Fatalf("request %d of the burst: %+v", i, res)
+				}
+			}
+			res, err := store.TakeToken("key", rule, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Allowed {
+				t.Fatalf("request after the burst: %+v", res)
+			}
+
+			res, _ = store.TakeToken("key", rule, now.Add(time.Second))
+			if !res.Allowed || res.Remaining != 0 {
+				t.Fatalf("request after a refill: %+v", res)
+			}
+			res, _ = store.TakeToken("key", rule, now.Add(time.Hour))
+			if !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("request after a long wait: %+v", res)
+			}
+		})
+	}
+}
+
+func TestTakeTokenConcurrentBurst(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			rule := ratelimit.Rule{Rate: 1, Burst: 10}
+			now := time.Now()
+
+			var wg sync.WaitGroup
+			var allowed, denied atomic.Int32
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					res, err := store.TakeToken("burst", rule, now)
+					switch {
+					case err != nil:
+						t.Error(err)
+					case res.Allowed:
+						allowed.Add(1)
+					default:
+						denied.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if allowed.Load() != 10 || denied.Load() != 40 {
+				t.Fatalf("%d allowed and %d denied, expected 10 and 40", allowed.Load(), denied.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"time"
+
+	"github.com/memoio/xspace-server/event"
+)
+
+// RealtimeEvent is an event published to the realtime hubs of all
+// replicas, each hub reads the events after the last one it has read.
+type RealtimeEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	EventID   string `gorm:"size:32"`
+	Type      string `gorm:"size:32"`
+	Project   uint
+	Payload   []byte
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// how long the published events are kept for the hubs to read them
+const realtimeRetention = time.Minute
+
+func (s *DataStore) PublishRealtime(record event.Record) error {
+	// drop the events every hub has read at most once a minute
+	now := time.Now()
+	last := s.lastRealtimeSweep.Load()
+	if now.Unix()-last >= 60 && s.lastRealtimeSweep.CompareAndSwap(last, now.Unix()) {
+		if err := s.db.Where("created_at <= ?", now.Add(-realtimeRetention)).Delete(&RealtimeEvent{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return s.db.Create(&RealtimeEvent{
+		EventID: record.EventID,
+		Type:    record.Type,
+		Project: record.Project,
+		Payload: record.Payload,
+	}).Error
+}
+
+// ListRealtime returns up to limit events published after the event with
+// the id after, oldest first.
+func (s *DataStore) ListRealtime(after uint, limit int) ([]event.Record, error) {
+	var events []RealtimeEvent
+	err := s.db.Where("id > ?", after).Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]event.Record, 0, len(events))
+	for _, e := range events {
+		records = append(records, event.Record{
+			ID:        e.ID,
+			EventID:   e.EventID,
+			Type:      e.Type,
+			Project:   e.Project,
+			Payload:   e.Payload,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return records, nil
+}
+
+// LastRealtimeID returns the id of the last event published, 0 if there is
+// none.
+func (s *DataStore) LastRealtimeID() (uint, error) {
+	var e RealtimeEvent
+	res := s.db.Order("id desc").Limit(1).Find(&e)
+	return e.ID, res.Error
+}
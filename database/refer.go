@@ -0,0 +1,119 @@
+package database
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/memoio/xspace-server/event"
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReferCodeInvalid = xerrors.New("refer code not found")
+	ErrReferBound       = xerrors.New("a refer code is already bound")
+	ErrReferSelf        = xerrors.New("can't bind your own refer code")
+)
+
+// referAlphabet leaves out the characters easily mistaken for others.
+const referAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// ReferCode is the code an address shares to refer others.
+type ReferCode struct {
+	Address   string `gorm:"primaryKey;size:64"`
+	Code      string `gorm:"uniqueIndex;size:8"`
+	CreatedAt time.Time
+}
+
+// Referral binds an address to the address that referred it.
+type Referral struct {
+	Address   string `gorm:"primaryKey;size:64"`
+	Referrer  string `gorm:"index;size:64"`
+	Code      string `gorm:"size:8"`
+	CreatedAt time.Time
+}
+
+// GetReferCode returns the address's refer code, the code is created on the
+// first call.
+func (s *DataStore) GetReferCode(address string) (string, error) {
+	var code ReferCode
+	err := s.db.Where("address = ?", address).First(&code).Error
+	if err == nil {
+		return code.Code, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	for {
+		code = ReferCode{Address: address}
+		code.Code, err = newReferCode()
+		if err != nil {
+			return "", err
+		}
+
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			var count int64
+			err := tx.Model(&ReferCode{}).Where("code = ?", code.Code).Count(&count).Error
+			if err != nil || count > 0 {
+				return err
+			}
+			return tx.Create(&code).Error
+		})
+		if err != nil {
+			return "", err
+		}
+		if code.CreatedAt.IsZero() {
+			// the code is taken, draw another one
+			continue
+		}
+		return code.Code, nil
+	}
+}
+
+// BindReferCode records that the address was referred by the owner of the
+// code, an address binds one code only.
+func (s *DataStore) BindReferCode(address, code string) (Referral, error) {
+	referral := Referral{Address: address, Code: code}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var referrer ReferCode
+		err := tx.Where("code = ?", code).First(&referrer).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReferCodeInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if referrer.Address == address {
+			return ErrReferSelf
+		}
+
+		var count int64
+		if err := tx.Model(&Referral{}).Where("address = ?", address).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrReferBound
+		}
+
+		referral.Referrer = referrer.Address
+		if err := tx.Create(&referral).Error; err != nil {
+			return err
+		}
+
+		return writeEvent(tx, event.ReferralBound{Address: address, Referrer: referral.Referrer, Code: code})
+	})
+	return referral, err
+}
+
+func newReferCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = referAlphabet[int(b[i])%len(referAlphabet)]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	auth "github.com/memoio/xspace-server/authentication"
+	"gorm.io/gorm"
+)
+
+// Role is the role of an address, addresses without one are users.
+type Role struct {
+	Address   string `gorm:"primaryKey;size:64"`
+	Role      string `gorm:"size:16"`
+	UpdatedAt time.Time
+}
+
+// Ban keeps a user, with all its linked wallets, from logging in.
+type Ban struct {
+	UserID    string `gorm:"primaryKey;size:32"`
+	Reason    string
+	BannedBy  string `gorm:"size:64"`
+	CreatedAt time.Time
+}
+
+func (s *DataStore) GetRole(address string) (string, error) {
+	var role Role
+	err := s.db.Where("address = ?", address).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return auth.RoleUser, nil
+	}
+	return role.Role, err
+}
+
+// SetRole changes the role of the address. A demoted address loses its
+// sessions, so that it can't keep using the role of its tokens.
+func (s *DataStore) SetRole(actor, address, role string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var current Role
+		err := tx.Where("address = ?", address).First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			current.Role = auth.RoleUser
+		} else if err != nil {
+			return err
+		}
+		if current.Role == role {
+			return nil
+		}
+
+		if role == auth.RoleUser {
+			err = tx.Delete(&Role{Address: address}).Error
+		} else {
+			err = tx.Save(&Role{Address: address, Role: role}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if !auth.HasRole(role, current.Role) {
+			err := tx.Model(&Session{}).Where("address = ?", address).Update("revoked", true).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return writeAudit(tx, actor, "role.set", address, map[string]string{"from": current.Role, "to": role})
+	})
+}
+
+func (s *DataStore) IsBanned(userID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&Ban{}).Where("user_id = ?", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// BanUser bans the user and revokes the sessions of all its wallets.
+func (s *DataStore) BanUser(actor, userID, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := getUser(tx, userID); err != nil {
+			return err
+		}
+
+		err := tx.Save(&Ban{UserID: userID, Reason: reason, BannedBy: actor}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&Session{}).Where("user_id = ?", userID).Update("revoked", true).Error
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(tx, actor, "user.ban", userID, map[string]string{"reason": reason})
+	})
+}
+
+func (s *DataStore) UnbanUser(actor, userID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&Ban{UserID: userID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return writeAudit(tx, actor, "user.unban", userID, nil)
+	})
+}
+
+// GetUserIDByAddress returns the user the wallet is linked to.
+func (s *DataStore) GetUserIDByAddress(address string) (string, error) {
+	var identity Identity
+	err := s.db.Where("address = ?", address).Order("created_at asc").First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotFound
+	}
+	return identity.UserID, err
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	auth "github.com/memoio/xspace-server/authentication"
+)
+
+func TestSetRoleRevokesSessions(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			ctrl := newTestController(t, store)
+			key, _ := crypto.GenerateKey()
+			address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+			if err := store.SetRole("test", address, auth.RoleOperator); err != nil {
+				t.Fatal(err)
+			}
+			token := login(t, ctrl, key)
+			claims, err := ctrl.VerifyAccessToken(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Role != auth.RoleOperator {
+				t.Fatalf("token of an operator has the role %q", claims.Role)
+			}
+
+			// a promotion keeps the sessions, the new role comes with the
+			// next tokens
+			if err := store.SetRole("test", address, auth.RoleAdmin); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ctrl.VerifyAccessToken(token); err != nil {
+				t.Fatalf("token after a promotion: %v", err)
+			}
+
+			if err := store.SetRole("test", address, auth.RoleUser); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ctrl.VerifyAccessToken(token); err != auth.ErrSessionRevoked {
+				t.Fatalf("token after a demotion: %v, expected ErrSessionRevoked", err)
+			}
+			claims, err = ctrl.VerifyAccessToken(login(t, ctrl, key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Role != auth.RoleUser {
+				t.Fatalf("token after a demotion has the role %q", claims.Role)
+			}
+		})
+	}
+}
+
+func TestBanUserRevokesSessions(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			ctrl := newTestController(t, store)
+			keyA, _ := crypto.GenerateKey()
+			keyB, _ := crypto.GenerateKey()
+			addressB := crypto.PubkeyToAddress(keyB.PublicKey).Hex()
+
+			tokenA := login(t, ctrl, keyA)
+			claims, err := ctrl.VerifyAccessToken(tokenA)
+			if err != nil {
+				t.Fatal(err)
+			}
+			userID := claims.UserID
+			err = store.LinkIdentity(userID, auth.Identity{Provider: auth.ProviderEthereum, Subject: addressB, Address: addressB})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokenB := login(t, ctrl, keyB)
+
+			if err := store.BanUser("test", userID, "spam"); err != nil {
+				t.Fatal(err)
+			}
+			for _, token := range []string{tokenA, tokenB} {
+				if _, err := ctrl.VerifyAccessToken(token); err != auth.ErrSessionRevoked {
+					t.Fatalf("token of a banned user: %v, expected ErrSessionRevoked", err)
+				}
+			}
+			if _, err := signIn(ctrl, keyA); err != auth.ErrUserBanned {
+				t.Fatalf("login of a banned user: %v, expected ErrUserBanned", err)
+			}
+
+			if err := store.UnbanUser("test", userID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ctrl.VerifyAccessToken(login(t, ctrl, keyB)); err != nil {
+				t.Fatalf("login after the ban is lifted: %v", err)
+			}
+			if err := store.UnbanUser("test", userID); err != ErrNotFound {
+				t.Fatalf("unban of a user not banned: %v, expected ErrNotFound", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/memoio/xspace-server/event"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScoreEvent is an activity of a user reported by a cooperative project,
+// the project's event id makes reporting it again a no-op.
+type ScoreEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	ProjectID uint   `gorm:"uniqueIndex:idx_score_event"`
+	EventID   string `gorm:"uniqueIndex:idx_score_event;size:64"`
+	// the partner key reporting the event
+	KeyID     string `gorm:"size:32"`
+	Address   string `gorm:"index;size:64"`
+	Type      string `gorm:"size:32"`
+	Score     int64
+	Time      time.Time
+	CreatedAt time.Time
+}
+
+// ProjectScore is the total score of an address in a project.
+type ProjectScore struct {
+	ProjectID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Address   string `gorm:"primaryKey;size:64"`
+	Score     int64  `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+// RankEntry is an address on the leaderboard of a project with its xspace
+// points.
+type RankEntry struct {
+	Address string
+	Score   int64
+	Points  int64
+}
+
+// AddScoreEvent records the event and adds its score to the address's total
+// in the project. It returns false if the event was recorded before.
+func (s *DataStore) AddScoreEvent(scoreEvent *ScoreEvent) (bool, error) {
+	added := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// the unique index decides between concurrent reports of an event
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(scoreEvent)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		var score ProjectScore
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ? AND address = ?", scoreEvent.ProjectID, scoreEvent.Address).First(&score).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			score = ProjectScore{ProjectID: scoreEvent.ProjectID, Address: scoreEvent.Address}
+		} else if err != nil {
+			return err
+		}
+		score.Score += scoreEvent.Score
+		if err := tx.Save(&score).Error; err != nil {
+			return err
+		}
+
+		added = true
+		return writeEvent(tx, event.ProjectScored{
+			ProjectID: scoreEvent.ProjectID,
+			EventID:   scoreEvent.EventID,
+			Address:   scoreEvent.Address,
+			Kind:      scoreEvent.Type,
+			Score:     scoreEvent.Score,
+			Time:      scoreEvent.Time,
+		})
+	})
+	return added, err
+}
+
+// GetProjectRank returns the position, from 1, and the score of the address
+// on the leaderboard of the project.
+func (s *DataStore) GetProjectRank(projectID uint, address string) (int64, int64, error) {
+	var score ProjectScore
+	err := s.db.Where("project_id = ? AND address = ?", projectID, address).First(&score).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var ahead int64
+	err = s.db.Model(&ProjectScore{}).
+		Where("project_id = ? AND (score > ? OR (score = ? AND updated_at < ?))", projectID, score.Score, score.Score, score.UpdatedAt).
+		Count(&ahead).Error
+	return ahead + 1, score.Score, err
+}
+
+// ListProjectRank returns the addresses of the project by descending score,
+// of two equal scores the one updated earlier ranks higher.
+func (s *DataStore) ListProjectRank(projectID uint, page, size int) ([]RankEntry, error) {
+	var entries []RankEntry
+	err := s.db.Table("project_scores").
+		Select("project_scores.address, project_scores.score, coalesce(user_points.points, 0) as points").
+		Joins("left join user_points on user_points.address = project_scores.address").
+		Where("project_scores.project_id = ?", projectID).
+		Order("project_scores.score desc").Order("project_scores.updated_at asc").
+		Offset((page - 1) * size).Limit(size).
+		Scan(&entries).Error
+	return entries, err
+}
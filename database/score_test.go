@@ -0,0 +1,48 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAddScoreEventConcurrent(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			address := "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+			projectID := uint(time.Now().UnixNano() % 1000000)
+
+			var wg sync.WaitGroup
+			var added atomic.Int32
+			for i := 0; i < 16; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ok, err := store.AddScoreEvent(&ScoreEvent{
+						ProjectID: projectID,
+						EventID:   "event",
+						Address:   address,
+						Score:     10,
+						Time:      time.Now(),
+					})
+					if err != nil {
+						t.Error(err)
+					}
+					if ok {
+						added.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if n := added.Load(); n != 1 {
+				t.Fatalf("event added %d times", n)
+			}
+			_, score, err := store.GetProjectRank(projectID, address)
+			if err != nil || score != 10 {
+				t.Fatalf("score %d, %v", score, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	auth "github.com/memoio/xspace-server/authentication"
+	"gorm.io/gorm"
+)
+
+type Session struct {
+	ID             string `gorm:"primaryKey;size:32"`
+	UserID         string `gorm:"index;size:32"`
+	Address        string `gorm:"index;size:64"`
+	Provider       string `gorm:"size:16"`
+	Subject        string `gorm:"size:66"`
+	ChainID        int
+	CurrentTokenID string `gorm:"size:32"`
+	UserAgent      string
+	CreatedAt      time.Time
+	RefreshedAt    time.Time
+	ExpireAt       time.Time `gorm:"index"`
+	Revoked        bool
+}
+
+var _ auth.SessionStore = (*DataStore)(nil)
+
+func (s *DataStore) CreateSession(session *auth.Session) error {
+	return s.db.Create(&Session{
+		ID:             session.ID,
+		UserID:         session.UserID,
+		Address:        session.Address,
+		Provider:       session.Provider,
+		Subject:        session.Subject,
+		ChainID:        session.ChainID,
+		CurrentTokenID: session.CurrentTokenID,
+		UserAgent:      session.UserAgent,
+		CreatedAt:      session.CreatedAt,
+		RefreshedAt:    session.RefreshedAt,
+		ExpireAt:       session.ExpireAt,
+	}).Error
+}
+
+func (s *DataStore) GetSession(id string) (auth.Session, error) {
+	var session Session
+	err := s.db.Where("id = ?", id).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return auth.Session{}, auth.ErrSessionNotFound
+	}
+	return toAuthSession(session), err
+}
+
+func (s *DataStore) RotateSession(id, oldTokenID, newTokenID string, expireAt time.Time) error {
+	// compare-and-swap, so that one refresh token can be used only once
+	res := s.db.Model(&Session{}).
+		Where("id = ? AND current_token_id = ? AND revoked = ?", id, oldTokenID, false).
+		Updates(map[string]interface{}{
+			"current_token_id": newTokenID,
+			"refreshed_at":     time.Now(),
+			"expire_at":        expireAt,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return auth.ErrSessionReused
+	}
+	return nil
+}
+
+func (s *DataStore) RevokeSession(id string) error {
+	res := s.db.Model(&Session{}).Where("id = ?", id).Update("revoked", true)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return auth.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *DataStore) ListSessions(userID, address string) ([]auth.Session, error) {
+	query := s.db.Where("user_id = ?", userID)
+	if userID == "" {
+		query = query.Where("address = ?", address)
+	}
+
+	var sessions []Session
+	err := query.Where("revoked = ? AND expire_at > ?", false, time.Now()).
+		Order("refreshed_at desc").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]auth.Session, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, toAuthSession(session))
+	}
+	return res, nil
+}
+
+func toAuthSession(session Session) auth.Session {
+	return auth.Session{
+		ID:             session.ID,
+		UserID:         session.UserID,
+		Address:        session.Address,
+		Provider:       session.Provider,
+		Subject:        session.Subject,
+		ChainID:        session.ChainID,
+		CurrentTokenID: session.CurrentTokenID,
+		UserAgent:      session.UserAgent,
+		CreatedAt:      session.CreatedAt,
+		RefreshedAt:    session.RefreshedAt,
+		ExpireAt:       session.ExpireAt,
+		Revoked:        session.Revoked,
+	}
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	auth "github.com/memoio/xspace-server/authentication"
+)
+
+func TestListSessions(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			now := time.Now()
+			for _, session := range []auth.Session{
+				{ID: "a", UserID: "user", Address: "0xA"},
+				{ID: "b", UserID: "user", Address: "0xB"},
+				{ID: "revoked", UserID: "user", Address: "0xA"},
+				{ID: "expired", UserID: "user", Address: "0xB", ExpireAt: now.Add(-time.Minute)},
+				{ID: "other", UserID: "other", Address: "0xC"},
+				{ID: "legacy", Address: "0xA"},
+			} {
+				if session.ExpireAt.IsZero() {
+					session.ExpireAt = now.Add(time.Hour)
+				}
+				session.CreatedAt, session.RefreshedAt = now, now
+				if err := store.CreateSession(&session); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.RevokeSession("revoked"); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tc := range []struct {
+				userID, address string
+				expected        []string
+			}{
+				{"user", "0xA", []string{"a", "b"}},
+				{"user", "0xB", []string{"a", "b"}},
+				{"other", "0xC", []string{"other"}},
+				{"", "0xA", []string{"legacy"}},
+			} {
+				sessions, err := store.ListSessions(tc.userID, tc.address)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ids []string
+				for _, session := range sessions {
+					ids = append(ids, session.ID)
+				}
+				slices.Sort(ids)
+				if !slices.Equal(ids, tc.expected) {
+					t.Errorf("sessions of %q %q: %v, expected %v", tc.userID, tc.address, ids, tc.expected)
+				}
+			}
+		})
+	}
+}
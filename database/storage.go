@@ -0,0 +1,67 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserStorage is the number and total size of the dataNFT objects an
+// address keeps in storage.
+type UserStorage struct {
+	Address   string `gorm:"primaryKey;size:42"`
+	Count     int64
+	Space     int64 `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+func (s *DataStore) GetUserStorage(address string) (UserStorage, error) {
+	var usage UserStorage
+	err := s.db.Where("address = ?", address).First(&usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return UserStorage{Address: address}, nil
+	}
+	return usage, err
+}
+
+// ListTopStorageUsers returns the addresses using the most storage space.
+func (s *DataStore) ListTopStorageUsers(page, size int) ([]UserStorage, error) {
+	var usages []UserStorage
+	err := s.db.Where("count > 0").
+		Order("space desc").Order("address asc").
+		Offset((page - 1) * size).Limit(size).
+		Find(&usages).Error
+	return usages, err
+}
+
+// addStorage changes the address's object count and space by count and
+// space. If maxCount or maxSpace is positive, growing beyond it fails with
+// ErrStorageCapExceeded.
+func addStorage(tx *gorm.DB, address string, count, space, maxCount, maxSpace int64) error {
+	var usage UserStorage
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("address = ?", address).First(&usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		usage = UserStorage{Address: address}
+	} else if err != nil {
+		return err
+	}
+
+	usage.Count += count
+	usage.Space += space
+	if count > 0 && maxCount > 0 && usage.Count > maxCount {
+		return ErrStorageCapExceeded
+	}
+	if space > 0 && maxSpace > 0 && usage.Space > maxSpace {
+		return ErrStorageCapExceeded
+	}
+
+	if usage.Count < 0 {
+		usage.Count = 0
+	}
+	if usage.Space < 0 {
+		usage.Space = 0
+	}
+	return tx.Save(&usage).Error
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"github.com/memoio/xspace-server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// tracingPlugin wraps the statements in spans, children of the span in the
+// context of the statement if any.
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string {
+	return "tracing"
+}
+
+func (tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	type register func(name string, fn func(*gorm.DB)) error
+	for op, hooks := range map[string][2]register{
+		"create": {cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		"query":  {cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		"update": {cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		"delete": {cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		"row":    {cb.Row().Before("*").Register, cb.Row().After("*").Register},
+		"raw":    {cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	} {
+		if err := hooks[0]("tracing:start_"+op, startSpan(op)); err != nil {
+			return err
+		}
+		if err := hooks[1]("tracing:end_"+op, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startSpan(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := tracing.Tracer().Start(db.Statement.Context, "db "+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", db.Dialector.Name())),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+
+	err := db.Error
+	if xerrors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	auth "github.com/memoio/xspace-server/authentication"
+	"gorm.io/gorm"
+)
+
+type User struct {
+	ID string `gorm:"primaryKey;size:32"`
+	// the wallet shown for the user, the first wallet unless the user
+	// selects another linked one
+	PrimaryAddress string `gorm:"size:64"`
+	CreatedAt      time.Time
+}
+
+// Identity is a way a user logs in: a wallet or a lens profile.
+type Identity struct {
+	Provider string `gorm:"primaryKey;size:16"`
+	Subject  string `gorm:"primaryKey;size:66"`
+	UserID   string `gorm:"index;size:32"`
+	// the wallet address of the identity, the owner of a lens profile
+	Address   string `gorm:"index;size:64"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var _ auth.UserStore = (*DataStore)(nil)
+
+func (s *DataStore) ResolveUser(identity auth.Identity) (string, error) {
+	var userID string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var known Identity
+		err := tx.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&known).Error
+		if err == nil && known.Address == identity.Address {
+			userID = known.UserID
+			return nil
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// an identity of the same wallet, e.g. the wallet owning a lens
+		// profile, already has a user
+		var sibling Identity
+		err = tx.Where("address = ? AND NOT (provider = ? AND subject = ?)", identity.Address, identity.Provider, identity.Subject).
+			Order("created_at asc").First(&sibling).Error
+		switch {
+		case err == nil:
+			userID = sibling.UserID
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			userID, err = newUserID()
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(&User{ID: userID, PrimaryAddress: identity.Address}).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if known.UserID != "" {
+			// the lens profile was transferred, it leaves the previous
+			// owner's user for the new owner's
+			return tx.Model(&known).Updates(map[string]interface{}{
+				"user_id": userID,
+				"address": identity.Address,
+			}).Error
+		}
+
+		return tx.Create(&Identity{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			UserID:   userID,
+			Address:  identity.Address,
+		}).Error
+	})
+	return userID, err
+}
+
+func newUserID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// LinkIdentity moves the wallet and the identities sharing its address to
+// the user. The user the wallet belonged to is deleted as it has no
+// identity left, unless it is banned: the ban would be lost with it.
+func (s *DataStore) LinkIdentity(userID string, identity auth.Identity) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var identities []Identity
+		err := tx.Where("address = ?", identity.Address).Find(&identities).Error
+		if err != nil {
+			return err
+		}
+
+		previous := make(map[string]bool)
+		for _, known := range identities {
+			if known.UserID != userID {
+				previous[known.UserID] = true
+			}
+		}
+		for id := range previous {
+			var others int64
+			err := tx.Model(&Identity{}).Where("user_id = ? AND address <> ?", id, identity.Address).Count(&others).Error
+			if err != nil {
+				return err
+			}
+			if others > 0 {
+				return auth.ErrWalletLinked
+			}
+
+			var bans int64
+			if err := tx.Model(&Ban{}).Where("user_id = ?", id).Count(&bans).Error; err != nil {
+				return err
+			}
+			if bans > 0 {
+				return auth.ErrUserBanned
+			}
+		}
+
+		err = moveWallet(tx, identity.Address, userID)
+		if err != nil {
+			return err
+		}
+		for id := range previous {
+			if err := tx.Delete(&User{ID: id}).Error; err != nil {
+				return err
+			}
+		}
+
+		var wallet Identity
+		err = tx.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&wallet).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&Identity{
+				Provider: identity.Provider,
+				Subject:  identity.Subject,
+				UserID:   userID,
+				Address:  identity.Address,
+			}).Error
+		}
+		return err
+	})
+}
+
+// UnlinkWallet moves the wallet and the identities sharing its address to a
+// new user of their own. The primary wallet can't be unlinked.
+func (s *DataStore) UnlinkWallet(userID, address string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := getUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if user.PrimaryAddress == address {
+			return ErrPrimaryWallet
+		}
+
+		var count int64
+		err = tx.Model(&Identity{}).Where("user_id = ? AND address = ?", userID, address).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		newID, err := newUserID()
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&User{ID: newID, PrimaryAddress: address}).Error; err != nil {
+			return err
+		}
+		return moveWallet(tx, address, newID)
+	})
+}
+
+// SetPrimaryWallet selects one of the user's wallets as the primary one.
+func (s *DataStore) SetPrimaryWallet(userID, address string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&Identity{}).Where("user_id = ? AND address = ?", userID, address).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return tx.Model(&User{}).Where("id = ?", userID).Update("primary_address", address).Error
+	})
+}
+
+func (s *DataStore) GetUser(userID string) (User, error) {
+	return getUser(s.db, userID)
+}
+
+// ListUserWallets returns the addresses of the user's wallets, the primary
+// one first.
+func (s *DataStore) ListUserWallets(userID string) ([]string, error) {
+	user, err := getUser(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	err = s.db.Model(&Identity{}).Where("user_id = ?", userID).
+		Group("address").Order("min(created_at) asc").Pluck("address", &addresses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := make([]string, 0, len(addresses))
+	if user.PrimaryAddress != "" {
+		wallets = append(wallets, user.PrimaryAddress)
+	}
+	for _, address := range addresses {
+		if address != user.PrimaryAddress {
+			wallets = append(wallets, address)
+		}
+	}
+	return wallets, nil
+}
+
+func getUser(tx *gorm.DB, userID string) (User, error) {
+	var user User
+	err := tx.Where("id = ?", userID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, ErrNotFound
+	}
+	if err != nil || user.PrimaryAddress != "" {
+		return user, err
+	}
+
+	// users created before wallets could be linked
+	var first Identity
+	err = tx.Where("user_id = ?", userID).Order("created_at asc").First(&first).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+	user.PrimaryAddress = first.Address
+	return user, nil
+}
+
+// moveWallet gives the identities and sessions of the wallet to the user,
+// the tokens of the sessions act for the new user from then on.
+func moveWallet(tx *gorm.DB, address, userID string) error {
+	err := tx.Model(&Identity{}).Where("address = ?", address).Update("user_id", userID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&Session{}).Where("address = ?", address).Update("user_id", userID).Error
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/config"
+)
+
+func newTestController(t *testing.T, store *DataStore) *auth.AuthController {
+	t.Helper()
+	pem, err := auth.GenerateKey(auth.AlgES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(keyFile, pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl, err := auth.NewAuthController(config.AuthConfig{
+		ActiveKey:      "test",
+		Keys:           []config.JWTKeyConfig{{ID: "test", Algorithm: auth.AlgES256, PrivateKeyFile: keyFile}},
+		Issuer:         "xspace",
+		Audience:       "xspace",
+		NonceTTL:       300,
+		AllowedDomains: []string{"xspace.io"},
+	}, auth.NewMemoryNonceStore(), store, store, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctrl
+}
+
+// login signs in with the wallet of the key and returns its access token.
+func login(t *testing.T, ctrl *auth.AuthController, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	accessToken, err := signIn(ctrl, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accessToken
+}
+
+func signIn(ctrl *auth.AuthController, key *ecdsa.PrivateKey) (string, error) {
+	message, err := ctrl.Challenge(context.Background(), auth.ChallengeRequest{
+		Origin:  "https://xspace.io",
+		Address: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		ChainID: 1,
+	})
+	if err != nil {
+		return "", err
+	}
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		return "", err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+
+	accessToken, _, err := ctrl.Login(context.Background(), auth.LoginRequest{Message: message, Signature: hexutil.Encode(sig)}, "test")
+	return "Bearer " + accessToken, err
+}
+
+func TestUnlinkedWalletToken(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			ctrl := newTestController(t, store)
+			keyA, _ := crypto.GenerateKey()
+			keyB, _ := crypto.GenerateKey()
+			addressA := crypto.PubkeyToAddress(keyA.PublicKey).Hex()
+			addressB := crypto.PubkeyToAddress(keyB.PublicKey).Hex()
+
+			claims, err := ctrl.VerifyAccessToken(login(t, ctrl, keyA))
+			if err != nil {
+				t.Fatal(err)
+			}
+			userID := claims.UserID
+			err = store.LinkIdentity(userID, auth.Identity{Provider: auth.ProviderEthereum, Subject: addressB, Address: addressB})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokenB := login(t, ctrl, keyB)
+			claims, err = ctrl.VerifyAccessToken(tokenB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.UserID != userID {
+				t.Fatalf("linked wallet logged in as user %s, expected %s", claims.UserID, userID)
+			}
+
+			if err := store.UnlinkWallet(userID, addressB); err != nil {
+				t.Fatal(err)
+			}
+			claims, err = ctrl.VerifyAccessToken(tokenB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.UserID == userID {
+				t.Fatal("token of the unlinked wallet still acts for its previous user")
+			}
+			wallets, err := store.ListUserWallets(claims.UserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if slices.Contains(wallets, addressA) {
+				t.Fatalf("token of the unlinked wallet reaches %v", wallets)
+			}
+		})
+	}
+}
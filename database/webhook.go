@@ -0,0 +1,323 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DeliveryPending = iota
+	DeliverySucceeded
+	// the delivery failed too many times and is no longer retried
+	DeliveryDead
+)
+
+// WebhookSubscription posts the events of its types to a partner's url.
+type WebhookSubscription struct {
+	ID uint `gorm:"primaryKey"`
+	// the partner's project, 0 for our own bots which receive all events
+	ProjectID uint `gorm:"index"`
+	URL       string
+	// comma separated event types, empty for all types
+	Events string
+	// key of the HMAC signing the payloads
+	Secret    string `gorm:"size:64"`
+	Active    bool   `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Accepts reports whether the subscription receives the event. An event of
+// a project only goes to the project's subscriptions and our own, an event
+// of no project, such as a mint or a point credit, only to our own.
+func (s *WebhookSubscription) Accepts(eventType string, projectID uint) bool {
+	if s.ProjectID != 0 && s.ProjectID != projectID {
+		return false
+	}
+	if s.Events == "" {
+		return true
+	}
+	for _, t := range strings.Split(s.Events, ",") {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event queued for a subscription.
+type WebhookDelivery struct {
+	ID             uint   `gorm:"primaryKey"`
+	SubscriptionID uint   `gorm:"index"`
+	EventID        string `gorm:"index;size:32"`
+	EventType      string `gorm:"size:32"`
+	Payload        string
+	Status         int       `gorm:"index:idx_delivery_due"`
+	NextAttemptAt  time.Time `gorm:"index:idx_delivery_due"`
+	// the dispatcher posting the delivery owns it until then
+	LockedUntil time.Time `gorm:"index"`
+	Attempts    int
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// WebhookAttempt is the result of posting a delivery once.
+type WebhookAttempt struct {
+	ID         uint `gorm:"primaryKey"`
+	DeliveryID uint `gorm:"index"`
+	// 0 if no response was received
+	StatusCode int
+	Error      string
+	// milliseconds
+	Duration  int64
+	CreatedAt time.Time
+}
+
+func (s *DataStore) CreateWebhook(actor string, sub *WebhookSubscription) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if sub.ProjectID != 0 {
+			var project Project
+			err := tx.First(&project, sub.ProjectID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		sub.Active = true
+		if err := tx.Create(sub).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, actor, "webhook.create", webhookTarget(sub.ID), map[string]interface{}{
+			"project": sub.ProjectID,
+			"url":     sub.URL,
+			"events":  sub.Events,
+		})
+	})
+}
+
+// ListWebhooks returns the active subscriptions of the project, or of all
+// projects if projectID is 0.
+func (s *DataStore) ListWebhooks(projectID uint) ([]WebhookSubscription, error) {
+	query := s.db.Where("active = ?", true)
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var subs []WebhookSubscription
+	err := query.Order("id asc").Find(&subs).Error
+	return subs, err
+}
+
+// DeleteWebhook deactivates the subscription and dead-letters its pending
+// deliveries.
+func (s *DataStore) DeleteWebhook(actor string, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&WebhookSubscription{}).Where("id = ? AND active = ?", id, true).Update("active", false)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		err := tx.Model(&WebhookDelivery{}).Where("subscription_id = ? AND status = ?", id, DeliveryPending).
+			Updates(map[string]interface{}{"status": DeliveryDead, "last_error": "subscription deleted"}).Error
+		if err != nil {
+			return err
+		}
+		return writeAudit(tx, actor, "webhook.delete", webhookTarget(id), nil)
+	})
+}
+
+// EnqueueWebhookEvent queues the event for every active subscription
+// accepting it, an event queued before is skipped.
+func (s *DataStore) EnqueueWebhookEvent(eventID, eventType string, projectID uint, payload []byte) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&WebhookDelivery{}).Where("event_id = ?", eventID).Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+
+		var subs []WebhookSubscription
+		if err := tx.Where("active = ?", true).Find(&subs).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, sub := range subs {
+			if !sub.Accepts(eventType, projectID) {
+				continue
+			}
+			err := tx.Create(&WebhookDelivery{
+				SubscriptionID: sub.ID,
+				EventID:        eventID,
+				EventType:      eventType,
+				Payload:        string(payload),
+				Status:         DeliveryPending,
+				NextAttemptAt:  now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next
+// attempt is due, at most perSubscription of each subscription and none of
+// the skipped subscriptions, with their subscriptions. No other dispatcher
+// claims them for the lease.
+func (s *DataStore) ClaimDueDeliveries(limit, perSubscription int, lease time.Duration, skip []uint) ([]WebhookDelivery, map[uint]WebhookSubscription, error) {
+	now := time.Now()
+
+	// deliveries queued before the leases have no locked_until
+	query := s.db.Where("status = ? AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until < ?)", DeliveryPending, now, now)
+	if len(skip) > 0 {
+		query = query.Where("subscription_id NOT IN ?", skip)
+	}
+	var due []WebhookDelivery
+	if err := query.Order("next_attempt_at asc").Limit(limit).Find(&due).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var deliveries []WebhookDelivery
+	claimed := make(map[uint]int)
+	for _, delivery := range due {
+		if claimed[delivery.SubscriptionID] >= perSubscription {
+			continue
+		}
+
+		// another dispatcher may have claimed the delivery since
+		res := s.db.Model(&WebhookDelivery{}).
+			Where("id = ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", delivery.ID, DeliveryPending, now).
+			Update("locked_until", now.Add(lease))
+		if res.Error != nil {
+			return nil, nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+
+		delivery.LockedUntil = now.Add(lease)
+		deliveries = append(deliveries, delivery)
+		claimed[delivery.SubscriptionID]++
+	}
+	if len(deliveries) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]uint, 0, len(claimed))
+	for id := range claimed {
+		ids = append(ids, id)
+	}
+	var subs []WebhookSubscription
+	if err := s.db.Where("id IN ?", ids).Find(&subs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	subscriptions := make(map[uint]WebhookSubscription, len(subs))
+	for _, sub := range subs {
+		subscriptions[sub.ID] = sub
+	}
+	return deliveries, subscriptions, nil
+}
+
+// RecordWebhookAttempt logs the attempt and updates the delivery: succeeded
+// if the attempt has no error, otherwise retried at nextAttempt or
+// dead-lettered if nextAttempt is zero.
+func (s *DataStore) RecordWebhookAttempt(delivery *WebhookDelivery, attempt *WebhookAttempt, nextAttempt time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryID = delivery.ID
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+
+		delivery.Attempts++
+		delivery.LastError = attempt.Error
+		delivery.LockedUntil = time.Time{}
+		switch {
+		case attempt.Error == "":
+			delivery.Status = DeliverySucceeded
+		case nextAttempt.IsZero():
+			delivery.Status = DeliveryDead
+		default:
+			delivery.NextAttemptAt = nextAttempt
+		}
+		return tx.Save(delivery).Error
+	})
+}
+
+// ReleaseWebhookDelivery gives up the lease of a delivery that wasn't
+// attempted, another dispatcher may claim it right away.
+func (s *DataStore) ReleaseWebhookDelivery(id uint) error {
+	return s.db.Model(&WebhookDelivery{}).Where("id = ?", id).Update("locked_until", time.Time{}).Error
+}
+
+// ListDeliveries returns the deliveries of the subscription, newest first,
+// with the status or of all statuses if it is negative.
+func (s *DataStore) ListDeliveries(subscriptionID uint, status int, page, size int) ([]WebhookDelivery, error) {
+	query := s.db.Where("subscription_id = ?", subscriptionID)
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []WebhookDelivery
+	err := query.Order("id desc").
+		Offset((page - 1) * size).Limit(size).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (s *DataStore) ListWebhookAttempts(deliveryID uint) ([]WebhookAttempt, error) {
+	var attempts []WebhookAttempt
+	err := s.db.Where("delivery_id = ?", deliveryID).Order("id asc").Find(&attempts).Error
+	return attempts, err
+}
+
+// RedeliverWebhook queues a dead-lettered delivery again with a fresh
+// budget of attempts.
+func (s *DataStore) RedeliverWebhook(actor string, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var delivery WebhookDelivery
+		err := tx.Where("id = ? AND status = ?", id, DeliveryDead).First(&delivery).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var sub WebhookSubscription
+		err = tx.Where("id = ? AND active = ?", delivery.SubscriptionID, true).First(&sub).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&delivery).Updates(map[string]interface{}{
+			"status":          DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return writeAudit(tx, actor, "webhook.redeliver", webhookTarget(delivery.SubscriptionID), map[string]uint{"delivery": id})
+	})
+}
+
+func webhookTarget(id uint) string {
+	return "webhook:" + strconv.FormatUint(uint64(id), 10)
+}
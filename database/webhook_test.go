@@ -0,0 +1,100 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSubscriptionAccepts(t *testing.T) {
+	internal := WebhookSubscription{}
+	partner := WebhookSubscription{ProjectID: 1, Events: "project.scored,nft.minted"}
+
+	for _, tc := range []struct {
+		sub       WebhookSubscription
+		eventType string
+		projectID uint
+		accepts   bool
+	}{
+		{internal, "project.scored", 1, true},
+		{internal, "nft.minted", 0, true},
+		{partner, "project.scored", 1, true},
+		{partner, "project.scored", 2, false},
+		{partner, "nft.minted", 0, false},
+		{partner, "refer.bound", 1, false},
+	} {
+		if accepts := tc.sub.Accepts(tc.eventType, tc.projectID); accepts != tc.accepts {
+			t.Errorf("project %d subscription accepts %s of project %d: %v", tc.sub.ProjectID, tc.eventType, tc.projectID, accepts)
+		}
+	}
+}
+
+func TestClaimDueDeliveries(t *testing.T) {
+	for driver, store := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			var subs [2]WebhookSubscription
+			for i := range subs {
+				subs[i] = WebhookSubscription{URL: "https://example.com/hook", Secret: "secret"}
+				if err := store.CreateWebhook("admin", &subs[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			prefix := fmt.Sprintf("%s%d-", driver, time.Now().UnixNano())
+			for i := 0; i < 3; i++ {
+				if err := store.EnqueueWebhookEvent(prefix+strconv.Itoa(i), "nft.minted", 0, []byte("{}")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ours := func(deliveries []WebhookDelivery) map[uint]int {
+				counts := make(map[uint]int)
+				for _, delivery := range deliveries {
+					if strings.HasPrefix(delivery.EventID, prefix) {
+						counts[delivery.SubscriptionID]++
+					}
+				}
+				return counts
+			}
+
+			// at most 2 per subscription, none of the skipped one
+			first, _, err := store.ClaimDueDeliveries(100, 2, time.Minute, []uint{subs[1].ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts := ours(first); counts[subs[0].ID] != 2 || counts[subs[1].ID] != 0 {
+				t.Fatalf("first claim %v", counts)
+			}
+
+			// the claimed deliveries are leased
+			second, claimedSubs, err := store.ClaimDueDeliveries(100, 10, time.Minute, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts := ours(second); counts[subs[0].ID] != 1 || counts[subs[1].ID] != 3 {
+				t.Fatalf("second claim %v", counts)
+			}
+			if claimedSubs[subs[1].ID].URL != subs[1].URL {
+				t.Fatalf("subscription %+v", claimedSubs[subs[1].ID])
+			}
+
+			// a failed attempt releases the lease, a released delivery is
+			// claimed again
+			next := time.Now().Add(-time.Second)
+			if err := store.RecordWebhookAttempt(&first[0], &WebhookAttempt{Error: "unexpected status"}, next); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.ReleaseWebhookDelivery(first[1].ID); err != nil {
+				t.Fatal(err)
+			}
+			third, _, err := store.ClaimDueDeliveries(100, 10, time.Minute, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts := ours(third); counts[subs[0].ID] != 2 || counts[subs[1].ID] != 0 {
+				t.Fatalf("third claim %v", counts)
+			}
+		})
+	}
+}
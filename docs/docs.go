@@ -15,119 +15,80 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/v1/challenge": {
+        "/.well-known/jwks.json": {
             "get": {
-                "description": "Get the challenge message by address before you login",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the public keys verifying the tokens issued by xspace, in JSON Web Key Set format",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Login"
                 ],
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User's address (connect to xspace)",
-                        "name": "address",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "The network ID which the user's wallet is connected to",
-                        "name": "chainid",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "The frontend's domain",
-                        "name": "Origin",
-                        "in": "header",
-                        "required": true
-                    }
-                ],
                 "responses": {
                     "200": {
-                        "description": "The challenge message",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/auth.JWKS"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {}
                     }
                 }
             }
         },
-        "/v1/login": {
-            "post": {
-                "description": "Use the signMessage method to sign the challenge message. After signing, call the login interface to complete the login.\nIf the login is successful, the Login API will return an Access Token and a Refresh Token. When accessing subsequent APIs, you need to add the Authorization field in the headers with the value \"Bearer Your_Access_Token\"",
-                "consumes": [
-                    "application/json"
-                ],
+        "/healthz": {
+            "get": {
+                "description": "Liveness of the server, it answers as long as the process serves requests",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Login"
+                    "Health"
                 ],
-                "parameters": [
-                    {
-                        "description": "The challenge message",
-                        "name": "message",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    {
-                        "description": "The result after the user's private key signs the challenge message",
-                        "name": "signature",
-                        "in": "body",
-                        "required": true,
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/router.HealthRes"
                         }
                     }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Readiness of the server, whether it reaches the database and the chain",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
                 ],
                 "responses": {
                     "200": {
-                        "description": "The access token and refresh token",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/router.HealthRes"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {}
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {}
+                    "503": {
+                        "description": "A check failed",
+                        "schema": {
+                            "$ref": "#/definitions/router.HealthRes"
+                        }
                     }
                 }
             }
         },
-        "/v1/nft/data/info": {
+        "/v1/admin/audit": {
             "get": {
-                "description": "Get DataNFT content",
+                "description": "List the actions taken with the admin APIs, newest first, for admins",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/octet-stream"
+                    "application/json"
                 ],
                 "tags": [
-                    "NFT"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -139,29 +100,56 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "DataNFT's id",
-                        "name": "tokenID",
+                        "description": "Only list the action, e.g. points.adjust, user.ban, user.unban, role.set, project.create",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages",
+                        "name": "page",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The amount of data displayed on each page, at most 100",
+                        "name": "size",
                         "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "DataNFT binary content",
+                        "description": "OK",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/router.AuditLogRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/nft/list": {
+        "/v1/admin/partner/keys": {
             "get": {
-                "description": "List all NFT information belonging to the user",
+                "description": "List the partner API keys, for admins",
                 "consumes": [
                     "application/json"
                 ],
@@ -169,7 +157,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "NFT"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -181,28 +169,8 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Pages",
-                        "name": "page",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "The amount of data displayed on each page",
-                        "name": "size",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "NFT type (1 for tweetNFT, 2 for dataNFT, tweetNFT and dataNFT will be all listed by default)",
-                        "name": "type",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Order rules (date_asc for sorting by creation time from smallest to largest, date_dsc for sorting by creation time from largest to smallest)",
-                        "name": "order",
+                        "description": "Only list the keys of the project",
+                        "name": "project",
                         "in": "query"
                     }
                 ],
@@ -210,27 +178,39 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/router.ListNFTRes"
+                            "$ref": "#/definitions/router.ListPartnerKeysRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
-            }
-        },
-        "/v1/nft/mint": {
+            },
             "post": {
-                "description": "Mint user's data into NFTs",
+                "description": "Issue an API key for a cooperative project to report the activities of its users, for admins. The secret is only returned here.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "NFT"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -241,34 +221,58 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "User's data",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "The project and name of the key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/router.CreatePartnerKeyReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/router.MintRes"
+                            "$ref": "#/definitions/router.CreatePartnerKeyRes"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
-                        "schema": {}
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     },
                     "503": {
-                        "description": "Service Unavailable",
-                        "schema": {}
+                        "description": "Partner API keys are not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/nft/tweet/info": {
-            "get": {
-                "description": "Get TweetNFT content",
+        "/v1/admin/partner/keys/{id}": {
+            "delete": {
+                "description": "Revoke a partner API key, for admins",
                 "consumes": [
                     "application/json"
                 ],
@@ -276,7 +280,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "NFT"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -288,9 +292,9 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "TweetNFT's id",
-                        "name": "tokenID",
-                        "in": "query",
+                        "description": "The key id",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -298,19 +302,33 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/router.TweetNFTInfoRes"
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/point/charge": {
+        "/v1/admin/points": {
             "post": {
-                "description": "Users can charge once every 6 hours",
+                "description": "Credit or debit the points of an address, for operators and admins. The adjustment is audit logged, the wallets of the caller's own user can't be adjusted.",
                 "consumes": [
                     "application/json"
                 ],
@@ -318,7 +336,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Point"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -327,6 +345,33 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "The address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Points credited, debited if negative",
+                        "name": "points",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Why the points are adjusted",
+                        "name": "reason",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
@@ -336,16 +381,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/router.PointInfoRes"
                         }
                     },
+                    "400": {
+                        "description": "Invalid address or no reason",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "The address is a wallet of the caller",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "INSUFFICIENT_POINTS, not enough points to debit",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/point/history": {
-            "get": {
-                "description": "Get the history of the point info by address",
+        "/v1/admin/projects": {
+            "post": {
+                "description": "Add a cooperative project, for operators and admins",
                 "consumes": [
                     "application/json"
                 ],
@@ -353,7 +418,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Point"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -364,43 +429,46 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Pages",
-                        "name": "page",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "The amount of data displayed on each page",
-                        "name": "size",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Order rules (date_asc for sorting by creation time from smallest to largest, date_dsc for sorting by creation time from largest to smallest)",
-                        "name": "order",
-                        "in": "query"
+                        "description": "The project, its ProjectID is ignored",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/router.ProjectInfo"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/router.PointHistoryRes"
+                            "$ref": "#/definitions/router.ProjectInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/project/list": {
-            "get": {
-                "description": "List all projects with Xspace",
+        "/v1/admin/projects/{id}": {
+            "put": {
+                "description": "Change the name or period of a cooperative project, for operators and admins",
                 "consumes": [
                     "application/json"
                 ],
@@ -408,25 +476,197 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Rank"
+                    "Admin"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The project, its ProjectID is ignored",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/router.ProjectInfo"
+                        }
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/router.ListProjectsRes"
+                            "$ref": "#/definitions/router.ProjectInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a cooperative project, for operators and admins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/project/rank": {
+        "/v1/admin/roles": {
+            "put": {
+                "description": "Grant a role to an address, for admins. The new role is in the tokens issued after the change, a demoted address is logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "The address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "user, operator or admin",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/storage/top": {
             "get": {
-                "description": "Get the ranking of cooperative projects",
+                "description": "List the users consuming the most storage space, for operators and admins",
                 "consumes": [
                     "application/json"
                 ],
@@ -434,14 +674,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Rank"
+                    "Admin"
                 ],
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "cooperative project id",
-                        "name": "id",
-                        "in": "query",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     },
                     {
@@ -453,7 +693,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "The amount of data displayed on each page",
+                        "description": "The amount of data displayed on each page, at most 100",
                         "name": "size",
                         "in": "query",
                         "required": true
@@ -463,19 +703,33 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/router.RankRes"
+                            "$ref": "#/definitions/router.StorageReportRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/refer/bind": {
+        "/v1/admin/users/ban": {
             "post": {
-                "description": "Bind the refer code when first log in",
+                "description": "Ban the user owning the address, with all its linked wallets, and revoke its sessions. For operators and admins, who can only ban users of a lower role.",
                 "consumes": [
                     "application/json"
                 ],
@@ -483,7 +737,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Refer"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -494,8 +748,17 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Other user's refer code",
-                        "name": "code",
+                        "description": "Any wallet of the user",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Why the user is banned",
+                        "name": "reason",
                         "in": "body",
                         "required": true,
                         "schema": {
@@ -510,16 +773,36 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/refer/info": {
-            "get": {
-                "description": "Get the user's refer code",
+        "/v1/admin/users/unban": {
+            "post": {
+                "description": "Lift the ban of the user owning the address, for operators and admins, who can only unban users of a lower role.",
                 "consumes": [
                     "application/json"
                 ],
@@ -527,7 +810,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Refer"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -536,25 +819,54 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "Any wallet of the user",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "user's refer code",
+                        "description": "OK",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "The user is not found or not banned",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
         },
-        "/v1/refresh": {
-            "post": {
-                "description": "If the access token expires, you can call the refresh API to get a new access token or log in again.",
+        "/v1/admin/webhooks": {
+            "get": {
+                "description": "List the webhook subscriptions, for admins",
                 "consumes": [
                     "application/json"
                 ],
@@ -562,37 +874,52 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Login"
+                    "Admin"
                 ],
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Bearer YOUR_FRESH_TOKEN",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list the subscriptions of the project",
+                        "name": "project",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "The access token",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/router.ListWebhooksRes"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {}
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
-            }
-        },
-        "/v1/user/info": {
-            "get": {
-                "description": "Get the user basic info by address",
+            },
+            "post": {
+                "description": "Subscribe a url to xspace events, for admins: nft.minted, nft.burned, nft.transferred, mint.updated, points.credited, point.charged, refer.bound and project.scored.\nThe events are posted as json, signed with the returned secret: X-Xspace-Signature is the hex HMAC-SHA256 of X-Xspace-Timestamp, a dot and the body.\nA delivery without a 2xx response is retried with exponential backoff and dead-lettered after too many attempts.",
                 "consumes": [
                     "application/json"
                 ],
@@ -600,7 +927,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Admin"
                 ],
                 "parameters": [
                     {
@@ -609,28 +936,2284 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "The subscription",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/router.CreateWebhookReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/router.PointInfoRes"
+                            "$ref": "#/definitions/router.CreateWebhookRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "router.ListNFTRes": {
-            "type": "object",
-            "properties": {
-                "nftInfos": {
+        },
+        "/v1/admin/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "description": "Queue a dead-lettered delivery again, for admins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "The delivery is not dead or its webhook is deleted",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/webhooks/{id}": {
+            "delete": {
+                "description": "Delete a webhook subscription, for admins. Its pending deliveries are dead-lettered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "List the deliveries of a webhook subscription with their attempts, newest first, for admins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list the deliveries with the status: pending, succeeded or dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages",
+                        "name": "page",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The amount of data displayed on each page, at most 100",
+                        "name": "size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.ListWebhookDeliveriesRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/challenge": {
+            "get": {
+                "description": "Get the challenge message by address before you login\nWith the ethereum provider it is a Sign-In with Ethereum message for the address, with lens one for the owner of the profile, with solana a Sign-In with Solana message for the address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Login"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ethereum (default), lens or solana",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User's address (connect to xspace), required by ethereum and solana",
+                        "name": "address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The lens profile id, required by lens",
+                        "name": "profile",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The network ID which the user's wallet is connected to, 985 by default",
+                        "name": "chainid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The frontend's origin, its host must be an allowed domain",
+                        "name": "Origin",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The challenge message",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "SIWE_INVALID, the provider, origin, address, profile or chain id is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/login": {
+            "post": {
+                "description": "Use the signMessage method to sign the challenge message. After signing, call the login interface to complete the login.\nIf the login is successful, the Login API will return an Access Token and a Refresh Token. When accessing subsequent APIs, you need to add the Authorization field in the headers with the value \"Bearer Your_Access_Token\"\nThe message must be the unmodified challenge, signed before its expiration time, and can be used only once.\nThe identities sharing a wallet, such as a wallet and the lens profiles it owns, log in as the same user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Login"
+                ],
+                "parameters": [
+                    {
+                        "description": "The provider of the challenge, ethereum by default",
+                        "name": "provider",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The challenge message",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The result after the user's private key signs the challenge message, hex encoded, base58 for solana",
+                        "name": "signature",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The access token and refresh token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "SIWE_INVALID or SIGNATURE_INVALID",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "USER_BANNED",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "502": {
+                        "description": "CHAIN_UNAVAILABLE, the signature of a contract wallet couldn't be checked",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/logout": {
+            "post": {
+                "description": "Revoke the current session, its access token and refresh token can't be used any more",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Login"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/burn": {
+            "post": {
+                "description": "Burn the user's NFT, the content of a dataNFT is deleted and no longer counts in the user's storage space",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "NFT's id",
+                        "name": "tokenID",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/data/info": {
+            "get": {
+                "description": "Get DataNFT content, the owner can always read it while other users need to pass the DataNFT's access policy\nThe content of an encrypted DataNFT is the ciphertext: a 12-byte nonce followed by the AES-256-GCM sealed data.\nIts key is returned in the X-Wrapped-Key header (hex), encrypted to the caller's public key with ECIES on secp256k1.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "DataNFT's id",
+                        "name": "tokenID",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "DataNFT binary content",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "X-Encryption": {
+                                "type": "string",
+                                "description": "aes-256-gcm if the content is encrypted"
+                            },
+                            "X-Wrapped-Key": {
+                                "type": "string",
+                                "description": "The content key wrapped to the owner's public key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "PUBLIC_KEY_UNKNOWN, log in again to receive the key",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "502": {
+                        "description": "CHAIN_UNAVAILABLE, the holders policy couldn't be checked",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "503": {
+                        "description": "STORAGE_UNAVAILABLE",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/data/mint": {
+            "post": {
+                "description": "Mint user's data into NFTs\nIf encrypt is true, the data is encrypted with a new key which is only shared with the NFT's owner, see DataNFTInfo",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "User's data",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Encrypt the data",
+                        "name": "encrypt",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.MintRes"
+                        }
+                    },
+                    "400": {
+                        "description": "FILE_TOO_LARGE or no file",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "INSUFFICIENT_POINTS, STORAGE_CAP_EXCEEDED or PUBLIC_KEY_UNKNOWN (log in again to encrypt)",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "MINT_QUOTA_EXCEEDED if the daily mint quota is used up or RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "503": {
+                        "description": "STORAGE_UNAVAILABLE or FEATURE_DISABLED if encryption isn't enabled",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/data/policy": {
+            "get": {
+                "description": "Get the access policy of the user's DataNFT",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "DataNFT's id",
+                        "name": "tokenID",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.AccessPolicyInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Set who besides the owner can read the user's DataNFT.\nMode is one of owner (only the owner), public (every logged in user), holders (users holding at least MinBalance of the ERC-20/ERC-721 Contract) and allowlist (the users in Allowlist).\nAfter ExpireAt (unix seconds, 0 for never) the policy falls back to owner only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "The access policy",
+                        "name": "policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/router.AccessPolicyInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.AccessPolicyInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/list": {
+            "get": {
+                "description": "List all NFT information belonging to the user's linked wallets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages",
+                        "name": "page",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The amount of data displayed on each page, at most 100",
+                        "name": "size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "NFT type (1 for tweetNFT, 2 for dataNFT, tweetNFT and dataNFT will be all listed by default)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order rules (date_asc for sorting by creation time from smallest to largest, date_dsc for sorting by creation time from largest to smallest)",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.ListNFTRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/transfer": {
+            "post": {
+                "description": "Transfer the user's NFT to another address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "NFT's id",
+                        "name": "tokenID",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The receiver's address",
+                        "name": "to",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/tweet/info": {
+            "get": {
+                "description": "Get TweetNFT content",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "TweetNFT's id",
+                        "name": "tokenID",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.TweetNFTInfoRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/nft/tweet/mint": {
+            "post": {
+                "description": "Mint user's tweets into NFTs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "NFT"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "User's twtter/x name",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The time when the user posted the tweet",
+                        "name": "postTime",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The text of the tweet(including emoji), at most 280 characters",
+                        "name": "tweet",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The https urls of the tweet's images on pbs.twimg.com, at most 4",
+                        "name": "image",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.MintRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "INSUFFICIENT_POINTS",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "MINT_QUOTA_EXCEEDED if the daily mint quota is used up or RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/partner/events": {
+            "post": {
+                "description": "Report activities of users to the leaderboard of the partner's project, with a partner API key.\nThe request is signed: X-Signature is the hex HMAC-SHA256, keyed with the API key's secret, of X-Timestamp, the method, the path and the body joined by newlines.\nEvery event has an id unique within the project, an event reported again is a duplicate and changes nothing, so failed requests can be retried.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Partner"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The API key id",
+                        "name": "X-Api-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time of the request",
+                        "name": "X-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The request signature",
+                        "name": "X-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "The events",
+                        "name": "events",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/router.SubmitEventsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.SubmitEventsRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "API_KEY_INVALID, SIGNATURE_INVALID or TIMESTAMP_SKEWED",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "The project of the key is deleted",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "503": {
+                        "description": "FEATURE_DISABLED, no partner secret is configured",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/point/charge": {
+            "post": {
+                "description": "Users can charge once every 6 hours",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Point"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.PointInfoRes"
+                        }
+                    },
+                    "429": {
+                        "description": "CHARGE_TOO_FREQUENT, charged less than 6 hours ago, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/point/history": {
+            "get": {
+                "description": "Get the history of the point info of the user's linked wallets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Point"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages",
+                        "name": "page",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The amount of data displayed on each page, at most 100",
+                        "name": "size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order rules (date_asc for sorting by creation time from smallest to largest, date_dsc for sorting by creation time from largest to smallest)",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.PointHistoryRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/project/list": {
+            "get": {
+                "description": "List all projects with Xspace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rank"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.ListProjectsRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/project/rank": {
+            "get": {
+                "description": "Get the ranking of cooperative projects, by the scores the projects report",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rank"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "cooperative project id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages",
+                        "name": "page",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The amount of data displayed on each page, at most 100",
+                        "name": "size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.RankRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/refer/bind": {
+            "post": {
+                "description": "Bind the refer code when first log in, a user binds one refer code only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Refer"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Other user's refer code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "REFER_CODE_INVALID or REFER_SELF",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "REFER_BOUND, a refer code is already bound",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/refer/code": {
+            "get": {
+                "description": "Get the user's refer code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Refer"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user's refer code",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/refresh": {
+            "get": {
+                "description": "If the access token expires, you can call the refresh API to get a new access token or log in again.\nThe refresh token is rotated, use the returned refresh token next time. Using a refresh token twice revokes the whole session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Login"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_FRESH_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The access token and refresh token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "TOKEN_EXPIRED, TOKEN_INVALID, SESSION_REVOKED etc., the user needs to log in again",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "USER_BANNED",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sessions": {
+            "get": {
+                "description": "List the active sessions (logged in devices) of all the user's wallets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Login"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.ListSessionsRes"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sessions/{id}": {
+            "delete": {
+                "description": "Revoke one of the user's sessions, e.g. log out a lost device",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Login"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user": {
+            "get": {
+                "description": "Get the user's linked wallets with their points and dataNFT storage, and the totals of all wallets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.UserInfoRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/events": {
+            "get": {
+                "description": "Stream the updates of the user's linked wallets as server-sent events: \"points\" and \"charge\" (topic points), \"mint\" job status transitions (topic mint) and \"rank\" movements on the leaderboards of the projects (topic rank).\nA \"ping\" event is sent every heartbeat. A \"resync\" event tells a client that fell behind that updates were dropped and its state should be refetched.\nThe stream ends with an \"expired\" event when the access token expires, reconnect with a refreshed token. Browsers pass the token in the token query.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The access token, if the Authorization header can't be set",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated topics: points, mint and rank, points and mint by default",
+                        "name": "topics",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated ids of the projects whose rank movements are pushed",
+                        "name": "project",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, too many open streams",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/info": {
+            "get": {
+                "description": "Get the user basic info, the points and dataNFT storage are the totals of the linked wallets while charging is the state of the wallet logged in with",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.PointInfoRes"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/link": {
+            "post": {
+                "description": "Link another wallet to the user with the link message signed by both wallets, the lens profiles the wallet owns are linked with it.\nA wallet that has logged in before can only be linked if its user has no other wallet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "The link message",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The signature of the wallet the user is logged in with",
+                        "name": "signature",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "The signature of the wallet being linked",
+                        "name": "linkSignature",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/router.UserInfoRes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "SIWE_INVALID or SIGNATURE_INVALID",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "403": {
+                        "description": "USER_BANNED, the wallet belongs to a banned user",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "WALLET_LINKED, the wallet belongs to another user with other wallets",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/link/challenge": {
+            "get": {
+                "description": "Get the message linking another wallet to the user, it is signed by the wallet the user is logged in with and by the wallet being linked",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The wallet being linked, an ethereum or solana address",
+                        "name": "address",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The frontend's origin, its host must be an allowed domain",
+                        "name": "Origin",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The link message",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "SIWE_INVALID, the origin or address is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED, retry after the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/primary": {
+            "put": {
+                "description": "Select the primary wallet of the user among the linked ones",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "The linked wallet",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/wallets/{address}": {
+            "delete": {
+                "description": "Unlink a wallet from the user, it becomes a user of its own. The primary wallet can't be unlinked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer YOUR_ACCESS_TOKEN",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The linked wallet",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "PRIMARY_WALLET, the wallet is the primary one, or the address is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/router.APIError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.JWK"
+                    }
+                }
+            }
+        },
+        "leaderboard.Event": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "unique within the project, reporting an event again is a no-op",
+                    "type": "string"
+                },
+                "score": {
+                    "description": "added to the user's score in the project, negative to correct it",
+                    "type": "integer"
+                },
+                "time": {
+                    "description": "when the activity happened, the time it is reported by default",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "leaderboard.Result": {
+            "type": "object",
+            "properties": {
+                "eventID": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "router.APIError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "stable reason of the error for clients to act on",
+                    "type": "string",
+                    "example": "NOT_FOUND"
+                },
+                "details": {
+                    "description": "more about the error depending on its code, e.g. the limit and the\ncurrent usage of MINT_QUOTA_EXCEEDED or the FieldErrors of\nINVALID_REQUEST"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "NFT not found"
+                },
+                "requestId": {
+                    "description": "the X-Request-ID of the request, to report the error with",
+                    "type": "string",
+                    "example": "8f14e45fceea167a5a36dedd4bea2543"
+                }
+            }
+        },
+        "router.AccessPolicyInfo": {
+            "type": "object",
+            "properties": {
+                "allowlist": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "contract": {
+                    "type": "string"
+                },
+                "expireAt": {
+                    "type": "integer"
+                },
+                "minBalance": {
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "tokenID": {
+                    "type": "integer"
+                }
+            }
+        },
+        "router.AuditLogInfo": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "router.AuditLogRes": {
+            "type": "object",
+            "properties": {
+                "logs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.AuditLogInfo"
+                    }
+                }
+            }
+        },
+        "router.CreatePartnerKeyReq": {
+            "type": "object",
+            "required": [
+                "name",
+                "projectID"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "projectID": {
+                    "type": "integer"
+                },
+                "rateLimit": {
+                    "description": "requests per minute, the server's default if 0",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "router.CreatePartnerKeyRes": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "keyID": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "projectID": {
+                    "type": "integer"
+                },
+                "rateLimit": {
+                    "type": "integer"
+                },
+                "revoked": {
+                    "type": "boolean"
+                },
+                "secret": {
+                    "description": "the signing secret, only returned when the key is created",
+                    "type": "string"
+                }
+            }
+        },
+        "router.CreateWebhookReq": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "description": "the event types, all types if empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "projectID": {
+                    "description": "the partner's project, receiving only the events of the project, 0\nfor our own bots receiving all events",
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string",
+                    "maxLength": 512
+                }
+            }
+        },
+        "router.CreateWebhookRes": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "projectID": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "the key of the HMAC signing the payloads, only returned when the\nwebhook is created",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webhookID": {
+                    "type": "integer"
+                }
+            }
+        },
+        "router.HealthRes": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "the result of each check, ok or unavailable",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "description": "ok or unavailable",
+                    "type": "string"
+                }
+            }
+        },
+        "router.ListNFTRes": {
+            "type": "object",
+            "properties": {
+                "nftInfos": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/router.NFTInfo"
@@ -638,6 +3221,17 @@ const docTemplate = `{
                 }
             }
         },
+        "router.ListPartnerKeysRes": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.PartnerKeyInfo"
+                    }
+                }
+            }
+        },
         "router.ListProjectsRes": {
             "type": "object",
             "properties": {
@@ -649,10 +3243,50 @@ const docTemplate = `{
                 }
             }
         },
+        "router.ListSessionsRes": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.SessionInfo"
+                    }
+                }
+            }
+        },
+        "router.ListWebhookDeliveriesRes": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.WebhookDeliveryInfo"
+                    }
+                }
+            }
+        },
+        "router.ListWebhooksRes": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.WebhookInfo"
+                    }
+                }
+            }
+        },
         "router.MintRes": {
             "type": "object",
             "properties": {
+                "cost": {
+                    "type": "integer"
+                },
+                "jobID": {
+                    "type": "integer"
+                },
                 "tokenID": {
+                    "description": "the token id is assigned after the mint job is done",
                     "type": "integer"
                 }
             }
@@ -660,6 +3294,10 @@ const docTemplate = `{
         "router.NFTInfo": {
             "type": "object",
             "properties": {
+                "address": {
+                    "description": "the linked wallet owning the NFT",
+                    "type": "string"
+                },
                 "createTime": {
                     "type": "string"
                 },
@@ -671,6 +3309,29 @@ const docTemplate = `{
                 }
             }
         },
+        "router.PartnerKeyInfo": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "keyID": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "projectID": {
+                    "type": "integer"
+                },
+                "rateLimit": {
+                    "type": "integer"
+                },
+                "revoked": {
+                    "type": "boolean"
+                }
+            }
+        },
         "router.PointHistoryRes": {
             "type": "object",
             "properties": {
@@ -688,6 +3349,10 @@ const docTemplate = `{
                 "actionName": {
                     "type": "string"
                 },
+                "address": {
+                    "description": "the linked wallet earning or spending the points",
+                    "type": "string"
+                },
                 "point": {
                     "type": "integer"
                 },
@@ -706,6 +3371,7 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "godataCount": {
+                    "description": "number of dataNFT objects and their total size in bytes",
                     "type": "integer"
                 },
                 "godataSpace": {
@@ -718,12 +3384,18 @@ const docTemplate = `{
         },
         "router.ProjectInfo": {
             "type": "object",
+            "required": [
+                "end",
+                "name",
+                "start"
+            ],
             "properties": {
                 "end": {
                     "type": "string"
                 },
                 "name": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 64
                 },
                 "projectID": {
                     "type": "integer"
@@ -761,6 +3433,84 @@ const docTemplate = `{
                 }
             }
         },
+        "router.SessionInfo": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "the wallet logged in",
+                    "type": "string"
+                },
+                "chainID": {
+                    "type": "integer"
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "whether it is the session of the calling token",
+                    "type": "boolean"
+                },
+                "expireTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "refreshTime": {
+                    "type": "string"
+                },
+                "userAgent": {
+                    "type": "string"
+                }
+            }
+        },
+        "router.StorageReportRes": {
+            "type": "object",
+            "properties": {
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.StorageUsage"
+                    }
+                }
+            }
+        },
+        "router.StorageUsage": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "space": {
+                    "type": "integer"
+                }
+            }
+        },
+        "router.SubmitEventsReq": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/leaderboard.Event"
+                    }
+                }
+            }
+        },
+        "router.SubmitEventsRes": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/leaderboard.Result"
+                    }
+                }
+            }
+        },
         "router.TweetNFTInfoRes": {
             "type": "object",
             "properties": {
@@ -780,6 +3530,122 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "router.UserInfoRes": {
+            "type": "object",
+            "properties": {
+                "godataCount": {
+                    "type": "integer"
+                },
+                "godataSpace": {
+                    "type": "integer"
+                },
+                "points": {
+                    "description": "totals of the linked wallets",
+                    "type": "integer"
+                },
+                "primary": {
+                    "type": "string"
+                },
+                "userID": {
+                    "type": "string"
+                },
+                "wallets": {
+                    "description": "the linked wallets, the primary one first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.WalletInfo"
+                    }
+                }
+            }
+        },
+        "router.WalletInfo": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "godataCount": {
+                    "type": "integer"
+                },
+                "godataSpace": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                }
+            }
+        },
+        "router.WebhookAttemptInfo": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "description": "milliseconds",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "statusCode": {
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "router.WebhookDeliveryInfo": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/router.WebhookAttemptInfo"
+                    }
+                },
+                "createTime": {
+                    "type": "string"
+                },
+                "deliveryID": {
+                    "type": "integer"
+                },
+                "eventID": {
+                    "type": "string"
+                },
+                "eventType": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, succeeded or dead",
+                    "type": "string"
+                }
+            }
+        },
+        "router.WebhookInfo": {
+            "type": "object",
+            "properties": {
+                "createTime": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "projectID": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webhookID": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
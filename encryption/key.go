@@ -0,0 +1,235 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
+)
+
+const Algorithm = "aes-256-gcm"
+
+var (
+	ErrDisabled         = xerrors.New("encryption is not enabled on this server")
+	ErrPublicKeyUnknown = xerrors.New("public key of the user is unknown, please log in again")
+)
+
+// KeyManager encrypts dataNFT objects with per-object keys. Each object key
+// is kept twice: sealed with the server's master key, so that it can be
+// re-wrapped when the NFT is transferred, and wrapped to the current
+// owner's secp256k1 public key with ECIES, so that only the owner can
+// decrypt the object.
+//
+// Objects are encrypted with AES-256-GCM, the ciphertext is the 12-byte
+// nonce followed by the sealed content. Wrapped keys use go-ethereum's
+// ECIES (ECIES_AES128_SHA256 on secp256k1) without shared info.
+type KeyManager struct {
+	masterKey []byte
+	store     *database.DataStore
+}
+
+// NewKeyManager creates a key manager from a hex encoded 32-byte master
+// key. An empty master key disables encryption.
+func NewKeyManager(masterKey string, store *database.DataStore) (*KeyManager, error) {
+	m := &KeyManager{store: store}
+	if masterKey == "" {
+		return m, nil
+	}
+
+	key, err := hex.DecodeString(masterKey)
+	if err != nil {
+		return nil, xerrors.Errorf("decode master key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, xerrors.Errorf("master key must be 32 bytes, got %d", len(key))
+	}
+	m.masterKey = key
+	return m, nil
+}
+
+func (m *KeyManager) Enabled() bool {
+	return m.masterKey != nil
+}
+
+// Encrypt encrypts the object content for the owner and records its key
+// under objectKey.
+func (m *KeyManager) Encrypt(objectKey, owner string, plaintext []byte) ([]byte, error) {
+	if !m.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	publicKey, err := m.store.GetPublicKey(owner)
+	if xerrors.Is(err, database.ErrNotFound) {
+		return nil, ErrPublicKeyUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedKey, err := seal(m.masterKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := wrap(publicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.store.SaveEncryptedKey(&database.EncryptedKey{
+		ObjectKey:  objectKey,
+		SealedKey:  sealedKey,
+		Owner:      owner,
+		WrappedKey: wrappedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ciphertext, nil
+}
+
+// WrappedKey returns the object key wrapped to the owner's public key.
+func (m *KeyManager) WrappedKey(objectKey, owner string) ([]byte, error) {
+	key, err := m.store.GetEncryptedKey(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	if key.Owner != owner {
+		return nil, xerrors.Errorf("object %s is not owned by %s", objectKey, owner)
+	}
+	if len(key.WrappedKey) == 0 {
+		return nil, ErrPublicKeyUnknown
+	}
+	return key.WrappedKey, nil
+}
+
+// WrapFor wraps the object key to the public key of a reader other than the
+// owner, the caller must check that the reader is allowed to read it.
+func (m *KeyManager) WrapFor(objectKey, reader string) ([]byte, error) {
+	if !m.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	publicKey, err := m.store.GetPublicKey(reader)
+	if xerrors.Is(err, database.ErrNotFound) {
+		return nil, ErrPublicKeyUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := m.store.GetEncryptedKey(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := open(m.masterKey, sealed.SealedKey)
+	if err != nil {
+		return nil, xerrors.Errorf("unseal key of %s: %w", objectKey, err)
+	}
+	return wrap(publicKey, key)
+}
+
+// RewrapPending wraps the keys of the objects the address received to its
+// public key. It is called by the indexer after a transfer and on login,
+// keys stay pending while the public key is unknown.
+func (m *KeyManager) RewrapPending(owner string) error {
+	if !m.Enabled() {
+		return nil
+	}
+
+	publicKey, err := m.store.GetPublicKey(owner)
+	if xerrors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	keys, err := m.store.ListUnwrappedKeys(owner)
+	if err != nil {
+		return err
+	}
+
+	for i := range keys {
+		key, err := open(m.masterKey, keys[i].SealedKey)
+		if err != nil {
+			return xerrors.Errorf("unseal key of %s: %w", keys[i].ObjectKey, err)
+		}
+
+		keys[i].WrappedKey, err = wrap(publicKey, key)
+		if err != nil {
+			return err
+		}
+		if err := m.store.SaveEncryptedKey(&keys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SavePublicKey records the public key recovered at login and wraps the
+// pending object keys to it.
+func (m *KeyManager) SavePublicKey(address string, publicKey []byte) error {
+	if err := m.store.SavePublicKey(address, publicKey); err != nil {
+		return err
+	}
+	return m.RewrapPending(address)
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, xerrors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func wrap(publicKey, key []byte) ([]byte, error) {
+	pubKey, err := crypto.UnmarshalPubkey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(pubKey), key, nil, nil)
+}
@@ -0,0 +1,199 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
+)
+
+const testMasterKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func newTestManager(t *testing.T, masterKey string) (*KeyManager, *database.DataStore) {
+	t.Helper()
+	store, err := database.NewDataStore("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	m, err := NewKeyManager(masterKey, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, store
+}
+
+// newWallet returns a key whose public key is known to the manager.
+func newWallet(t *testing.T, m *KeyManager) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	if err := m.SavePublicKey(address, crypto.FromECDSAPub(&key.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+	return key, address
+}
+
+// decrypt unwraps the object key with the wallet's private key and opens
+// the ciphertext with it.
+func decrypt(t *testing.T, wallet *ecdsa.PrivateKey, wrappedKey, ciphertext []byte) []byte {
+	t.Helper()
+	key, err := ecies.ImportECDSA(wallet).Decrypt(wrappedKey, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := open(key, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plaintext
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	m, store := newTestManager(t, testMasterKey)
+	owner, ownerAddress := newWallet(t, m)
+	reader, readerAddress := newWallet(t, m)
+	plaintext := []byte("the content of a dataNFT")
+
+	ciphertext, err := m.Encrypt("object", ownerAddress, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	wrappedKey, err := m.WrappedKey("object", ownerAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decrypt(t, owner, wrappedKey, ciphertext); !bytes.Equal(got, plaintext) {
+		t.Fatalf("owner decrypted %q", got)
+	}
+	if _, err := m.WrappedKey("object", readerAddress); err == nil {
+		t.Fatal("wrapped key returned to a reader other than the owner")
+	}
+
+	readerKey, err := m.WrapFor("object", readerAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decrypt(t, reader, readerKey, ciphertext); !bytes.Equal(got, plaintext) {
+		t.Fatalf("reader decrypted %q", got)
+	}
+	if _, err := ecies.ImportECDSA(owner).Decrypt(readerKey, nil, nil); err == nil {
+		t.Fatal("key wrapped for the reader opened by the owner")
+	}
+
+	// the sealed copy opens with the master key only
+	sealed, err := store.GetEncryptedKey("object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, _ := hex.DecodeString(testMasterKey)
+	key, err := open(master, sealed.SealedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := open(key, ciphertext); err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("unsealed key decrypted %q: %v", got, err)
+	}
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	ciphertext, err := seal(key, []byte("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := open(bytes.Repeat([]byte{2}, 32), ciphertext); err == nil {
+		t.Fatal("ciphertext opened with another key")
+	}
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := open(key, tampered); err == nil {
+		t.Fatal("tampered ciphertext opened")
+	}
+	if _, err := open(key, ciphertext[:8]); err == nil {
+		t.Fatal("truncated ciphertext opened")
+	}
+}
+
+func TestWrapForWrongMasterKey(t *testing.T) {
+	m, store := newTestManager(t, testMasterKey)
+	_, ownerAddress := newWallet(t, m)
+	if _, err := m.Encrypt("object", ownerAddress, []byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewKeyManager("ff"+testMasterKey[2:], store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.WrapFor("object", ownerAddress); err == nil {
+		t.Fatal("key unsealed with another master key")
+	}
+}
+
+func TestRewrapPending(t *testing.T) {
+	m, store := newTestManager(t, testMasterKey)
+	_, ownerAddress := newWallet(t, m)
+	plaintext := []byte("transferred content")
+	ciphertext, err := m.Encrypt("object", ownerAddress, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// transferred to a wallet whose public key is not known yet
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	sealed, err := store.GetEncryptedKey("object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed.Owner, sealed.WrappedKey = receiver, nil
+	if err := store.SaveEncryptedKey(&sealed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.WrappedKey("object", receiver); !xerrors.Is(err, ErrPublicKeyUnknown) {
+		t.Fatalf("pending key: %v, expected ErrPublicKeyUnknown", err)
+	}
+
+	if err := m.SavePublicKey(receiver, crypto.FromECDSAPub(&key.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+	wrappedKey, err := m.WrappedKey("object", receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decrypt(t, key, wrappedKey, ciphertext); !bytes.Equal(got, plaintext) {
+		t.Fatalf("receiver decrypted %q", got)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	m, _ := newTestManager(t, "")
+	if _, err := m.Encrypt("object", "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4", []byte("content")); err != ErrDisabled {
+		t.Fatalf("encrypt: %v, expected ErrDisabled", err)
+	}
+
+	for _, masterKey := range []string{"not hex", "0011"} {
+		if _, err := NewKeyManager(masterKey, nil); err == nil {
+			t.Errorf("master key %q accepted", masterKey)
+		}
+	}
+}
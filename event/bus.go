@@ -0,0 +1,223 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+)
+
+// how long a dispatcher owns the outbox records it claimed
+const claimLease = 30 * time.Second
+
+// size of the queue of an async subscriber, events are dropped when it is
+// full
+const asyncQueueSize = 256
+
+// Handler handles an event, the event is a pointer to the struct of its type.
+type Handler func(ctx context.Context, msg Message) error
+
+// Outbox is where the state changes write their events, in the transaction
+// making the change.
+type Outbox interface {
+	// ClaimOutbox returns up to limit records in the order they were written,
+	// nobody else claims them for the lease.
+	ClaimOutbox(limit int, lease time.Duration) ([]Record, error)
+	// DeleteOutbox removes a dispatched record.
+	DeleteOutbox(id uint) error
+}
+
+// Bus dispatches the events of the outbox to the subscribers.
+//
+// Synchronous subscribers are called in order, one event after another, and
+// an event is only removed from the outbox once they all succeeded: it is
+// retried until then, also after a crash, so they must be idempotent.
+// Async subscribers then get the event in their own goroutine, a failure is
+// logged and the event isn't retried.
+type Bus struct {
+	outbox   Outbox
+	logger   *klog.Helper
+	interval time.Duration
+
+	mu          sync.RWMutex
+	sync        map[string][]Handler
+	async       map[string][]*subscriber
+	subscribers []*subscriber
+	// set once started
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type subscriber struct {
+	name    string
+	handler Handler
+	queue   chan Message
+}
+
+func NewBus(outbox Outbox, logger *klog.Helper) *Bus {
+	return &Bus{
+		outbox:   outbox,
+		logger:   logger,
+		interval: 500 * time.Millisecond,
+		sync:     make(map[string][]Handler),
+		async:    make(map[string][]*subscriber),
+	}
+}
+
+// Subscribe adds a synchronous subscriber of the event types.
+func (b *Bus) Subscribe(handler Handler, eventTypes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, eventType := range eventTypes {
+		b.sync[eventType] = append(b.sync[eventType], handler)
+	}
+}
+
+// SubscribeAsync adds an async subscriber of the event types. Events it
+// isn't keeping up with are dropped.
+func (b *Bus) SubscribeAsync(name string, handler Handler, eventTypes ...string) {
+	sub := &subscriber{name: name, handler: handler, queue: make(chan Message, asyncQueueSize)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, eventType := range eventTypes {
+		b.async[eventType] = append(b.async[eventType], sub)
+	}
+	b.subscribers = append(b.subscribers, sub)
+	if b.ctx != nil {
+		b.wg.Add(1)
+		go b.run(b.ctx, sub)
+	}
+}
+
+// Start dispatches the outbox and runs the async subscribers until ctx is
+// done.
+func (b *Bus) Start(ctx context.Context) {
+	b.mu.Lock()
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.ctx = ctx
+	b.wg.Add(len(b.subscribers) + 1)
+	for _, sub := range b.subscribers {
+		go b.run(ctx, sub)
+	}
+	b.mu.Unlock()
+
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		for {
+			for b.dispatch(ctx) {
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the events being handled, the events left in the outbox
+// are dispatched after the restart.
+func (b *Bus) Stop(ctx context.Context) error {
+	b.cancel()
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) run(ctx context.Context, sub *subscriber) {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-sub.queue:
+			if err := sub.handler(ctx, msg); err != nil {
+				b.logger.Errorf("%s: handle %s event %s: %s", sub.name, msg.Event.Type(), msg.ID, err)
+			}
+		}
+	}
+}
+
+// dispatch handles a batch of the outbox and returns whether the batch was
+// full.
+func (b *Bus) dispatch(ctx context.Context) bool {
+	const batch = 100
+
+	records, err := b.outbox.ClaimOutbox(batch, claimLease)
+	if err != nil {
+		b.logger.Error(err)
+		return false
+	}
+
+	for _, record := range records {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		msg, err := Decode(record)
+		if err != nil {
+			// an event of an older version, it is never dispatched
+			b.logger.Error(err)
+			if err := b.outbox.DeleteOutbox(record.ID); err != nil {
+				b.logger.Error(err)
+			}
+			continue
+		}
+
+		if !b.handle(ctx, msg) {
+			// keep the order of the events, the rest waits for the retry
+			return false
+		}
+		if err := b.outbox.DeleteOutbox(record.ID); err != nil {
+			b.logger.Error(err)
+			return false
+		}
+		b.publishAsync(msg)
+	}
+	return len(records) == batch
+}
+
+func (b *Bus) handle(ctx context.Context, msg Message) bool {
+	b.mu.RLock()
+	handlers := b.sync[msg.Event.Type()]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, msg); err != nil {
+			b.logger.Errorf("handle %s event %s: %s", msg.Event.Type(), msg.ID, err)
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bus) publishAsync(msg Message) {
+	b.mu.RLock()
+	subs := b.async[msg.Event.Type()]
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.queue <- msg:
+		default:
+			b.logger.Warnf("%s is full, drop %s event %s", sub.name, msg.Event.Type(), msg.ID)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Event types, also the types webhooks subscribe to.
+const (
+	TypeNFTMinted       = "nft.minted"
+	TypeNFTBurned       = "nft.burned"
+	TypeNFTTransferred  = "nft.transferred"
+	TypeMintJobUpdated  = "mint.updated"
+	TypePointsCredited  = "points.credited"
+	TypeChargeCompleted = "point.charged"
+	TypeReferralBound   = "refer.bound"
+	TypeProjectScored   = "project.scored"
+)
+
+// Event is a state change other parts of xspace react to. Events are written
+// to the outbox in the transaction making the change, see Bus.
+type Event interface {
+	Type() string
+}
+
+// ProjectEvent is an event of a cooperative project.
+type ProjectEvent interface {
+	Event
+	Project() uint
+}
+
+type NFTMinted struct {
+	JobID   uint   `json:"jobId"`
+	TokenID int64  `json:"tokenId"`
+	NFTType int    `json:"type"`
+	Address string `json:"address"`
+}
+
+// MintJobUpdated is a mint job created (pending), minted (done) or failed.
+type MintJobUpdated struct {
+	JobID   uint   `json:"jobId"`
+	Address string `json:"address"`
+	NFTType int    `json:"type"`
+	Status  string `json:"status"`
+	TokenID int64  `json:"tokenId,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type NFTBurned struct {
+	TokenID int64  `json:"tokenId"`
+	NFTType int    `json:"type"`
+	Address string `json:"address"`
+}
+
+type NFTTransferred struct {
+	TokenID int64  `json:"tokenId"`
+	NFTType int    `json:"type"`
+	From    string `json:"address"`
+	To      string `json:"to"`
+}
+
+// PointsCredited is a change of an address's balance by Points, which is
+// negative for a debit such as the cost of a mint.
+type PointsCredited struct {
+	Address string `json:"address"`
+	Points  int64  `json:"points"`
+	Balance int64  `json:"balance"`
+	Action  string `json:"action"`
+}
+
+type ChargeCompleted struct {
+	Address       string `json:"address"`
+	Reward        int64  `json:"reward"`
+	Balance       int64  `json:"points"`
+	ChargingCount int    `json:"chargingCount"`
+}
+
+type ReferralBound struct {
+	Address  string `json:"address"`
+	Referrer string `json:"referrer"`
+	Code     string `json:"code"`
+}
+
+// ProjectScored is an event reported by a project and added to its
+// leaderboard.
+type ProjectScored struct {
+	ProjectID uint      `json:"-"`
+	EventID   string    `json:"eventId"`
+	Address   string    `json:"address"`
+	Kind      string    `json:"type,omitempty"`
+	Score     int64     `json:"score"`
+	Time      time.Time `json:"time"`
+}
+
+func (NFTMinted) Type() string       { return TypeNFTMinted }
+func (MintJobUpdated) Type() string  { return TypeMintJobUpdated }
+func (NFTBurned) Type() string       { return TypeNFTBurned }
+func (NFTTransferred) Type() string  { return TypeNFTTransferred }
+func (PointsCredited) Type() string  { return TypePointsCredited }
+func (ChargeCompleted) Type() string { return TypeChargeCompleted }
+func (ReferralBound) Type() string   { return TypeReferralBound }
+func (ProjectScored) Type() string   { return TypeProjectScored }
+
+func (e ProjectScored) Project() uint { return e.ProjectID }
+
+var registry = map[string]func() Event{
+	TypeNFTMinted:       func() Event { return &NFTMinted{} },
+	TypeMintJobUpdated:  func() Event { return &MintJobUpdated{} },
+	TypeNFTBurned:       func() Event { return &NFTBurned{} },
+	TypeNFTTransferred:  func() Event { return &NFTTransferred{} },
+	TypePointsCredited:  func() Event { return &PointsCredited{} },
+	TypeChargeCompleted: func() Event { return &ChargeCompleted{} },
+	TypeReferralBound:   func() Event { return &ReferralBound{} },
+	TypeProjectScored:   func() Event { return &ProjectScored{} },
+}
+
+// Types are all the event types.
+var Types = []string{
+	TypeNFTMinted,
+	TypeNFTBurned,
+	TypeNFTTransferred,
+	TypeMintJobUpdated,
+	TypePointsCredited,
+	TypeChargeCompleted,
+	TypeReferralBound,
+	TypeProjectScored,
+}
+
+// Record is an event kept in the outbox.
+type Record struct {
+	ID uint
+	// the id of the event, unique across outbox records
+	EventID   string
+	Type      string
+	Project   uint
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Message is an event delivered to the subscribers, its id is unique and
+// the same every time it is delivered.
+type Message struct {
+	ID        string
+	CreatedAt time.Time
+	Event     Event
+}
+
+// Encode returns the outbox record of the event.
+func Encode(e Event) (Record, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return Record{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Record{}, err
+	}
+
+	record := Record{EventID: hex.EncodeToString(id), Type: e.Type(), Payload: payload}
+	if pe, ok := e.(ProjectEvent); ok {
+		record.Project = pe.Project()
+	}
+	return record, nil
+}
+
+// Decode returns the message of an outbox record, the event is a pointer to
+// the struct of its type.
+func Decode(record Record) (Message, error) {
+	newEvent, ok := registry[record.Type]
+	if !ok {
+		return Message{}, xerrors.Errorf("unknown event type %s", record.Type)
+	}
+
+	e := newEvent()
+	if err := json.Unmarshal(record.Payload, e); err != nil {
+		return Message{}, xerrors.Errorf("decode %s event %s: %w", record.Type, record.EventID, err)
+	}
+	if scored, ok := e.(*ProjectScored); ok {
+		scored.ProjectID = record.Project
+	}
+	return Message{ID: record.EventID, CreatedAt: record.CreatedAt, Event: e}, nil
+}
@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"context"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/contract/nft"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/encryption"
+	"github.com/memoio/xspace-server/metrics"
+)
+
+// Indexer follows the NFT transfers on chain and keeps the owners in the
+// database up to date.
+type Indexer struct {
+	store         *database.DataStore
+	keys          *encryption.KeyManager
+	nftController *nft.NFTController
+	logger        *klog.Helper
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewIndexer(store *database.DataStore, keys *encryption.KeyManager, nftController *nft.NFTController, logger *klog.Helper) *Indexer {
+	return &Indexer{
+		store:         store,
+		keys:          keys,
+		nftController: nftController,
+		logger:        logger,
+		done:          make(chan struct{}),
+	}
+}
+
+func (i *Indexer) Start(ctx context.Context) {
+	ctx, i.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(i.done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-i.nftController.Transfers():
+				if err := i.HandleTransfer(event); err != nil {
+					i.logger.Errorf("handle transfer of nft %d: %s", event.TokenID, err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop waits for the transfer being handled.
+func (i *Indexer) Stop(ctx context.Context) error {
+	i.cancel()
+	select {
+	case <-i.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HandleTransfer moves the NFT to its new owner and re-wraps the key of an
+// encrypted dataNFT to the new owner's public key.
+func (i *Indexer) HandleTransfer(event nft.TransferEvent) error {
+	if err := i.store.TransferNFT(event.TokenID, event.From, event.To); err != nil {
+		return err
+	}
+	metrics.IndexerLag.Set(time.Since(event.Time).Seconds())
+	return i.keys.RewrapPending(event.To)
+}
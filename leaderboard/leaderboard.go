@@ -0,0 +1,104 @@
+package leaderboard
+
+import (
+	"time"
+
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/database"
+)
+
+// Results of a reported event.
+const (
+	StatusAccepted  = "accepted"
+	StatusDuplicate = "duplicate"
+	StatusRejected  = "rejected"
+)
+
+// Event is an activity of a user reported by a cooperative project.
+type Event struct {
+	// unique within the project, reporting an event again is a no-op
+	ID      string
+	Address string
+	Type    string
+	// added to the user's score in the project, negative to correct it
+	Score int64
+	// when the activity happened, the time it is reported by default
+	Time time.Time
+}
+
+type Result struct {
+	EventID string
+	Status  string
+	Reason  string `json:",omitempty"`
+}
+
+// Engine ranks the users of cooperative projects by the scores of the
+// events the projects report.
+type Engine struct {
+	store *database.DataStore
+}
+
+func NewEngine(store *database.DataStore) *Engine {
+	return &Engine{store: store}
+}
+
+// Submit adds the events of the project reported with the partner key.
+// Events outside the project's period or of invalid addresses are rejected
+// one by one, the others are still added.
+func (e *Engine) Submit(projectID uint, keyID string, events []Event) ([]Result, error) {
+	project, err := e.store.GetProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(events))
+	now := time.Now()
+	for _, event := range events {
+		result := Result{EventID: event.ID}
+		if event.Time.IsZero() {
+			event.Time = now
+		}
+
+		address, err := auth.CanonicalAddress(event.Address)
+		switch {
+		case event.ID == "" || len(event.ID) > 64:
+			result.Status, result.Reason = StatusRejected, "event id must have 1 to 64 characters"
+		case err != nil:
+			result.Status, result.Reason = StatusRejected, "invalid address"
+		case len(event.Type) > 32:
+			result.Status, result.Reason = StatusRejected, "event type is longer than 32 characters"
+		case event.Score == 0:
+			result.Status, result.Reason = StatusRejected, "score is 0"
+		case event.Time.Before(project.Start) || event.Time.After(project.End):
+			result.Status, result.Reason = StatusRejected, "event is outside the project's period"
+		}
+		if result.Status != "" {
+			results = append(results, result)
+			continue
+		}
+
+		added, err := e.store.AddScoreEvent(&database.ScoreEvent{
+			ProjectID: projectID,
+			EventID:   event.ID,
+			KeyID:     keyID,
+			Address:   address,
+			Type:      event.Type,
+			Score:     event.Score,
+			Time:      event.Time,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Status = StatusAccepted
+		if !added {
+			result.Status = StatusDuplicate
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Rank returns a page of the project's leaderboard.
+func (e *Engine) Rank(projectID uint, page, size int) ([]database.RankEntry, error) {
+	return e.store.ListProjectRank(projectID, page, size)
+}
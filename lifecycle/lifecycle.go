@@ -0,0 +1,138 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/config"
+	"golang.org/x/xerrors"
+)
+
+// Component is a part of the application, such as the store or a
+// background worker. Start and Stop are optional.
+type Component struct {
+	Name string
+	// Start must return once the component runs, the long running work is
+	// done in goroutines
+	Start func(ctx context.Context) error
+	// Stop returns once the component stopped or ctx is done
+	Stop func(ctx context.Context) error
+	// how long Stop may take, unbounded if 0
+	Timeout time.Duration
+}
+
+// Manager starts the components in the order they were added and stops them
+// in the reverse order, so a component is stopped before the ones it uses.
+type Manager struct {
+	logger *klog.Helper
+
+	mu         sync.Mutex
+	components []Component
+	started    int
+	shutdown   []func()
+	reload     []func(cfg *config.Config) error
+}
+
+func NewManager(logger *klog.Helper) *Manager {
+	return &Manager{logger: logger}
+}
+
+// Add appends a component, it is started after the components added before.
+func (m *Manager) Add(c Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, c)
+}
+
+// OnShutdown registers fn to be called as soon as Stop is called, before
+// any component stops; it tells the long lived requests to end.
+func (m *Manager) OnShutdown(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shutdown = append(m.shutdown, fn)
+}
+
+// OnReload registers fn to apply a reloaded config.
+func (m *Manager) OnReload(fn func(cfg *config.Config) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reload = append(m.reload, fn)
+}
+
+// Start starts the components in order. If one fails to start, the ones
+// started are stopped.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.components[m.started:] {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				m.stop(context.Background())
+				return xerrors.Errorf("start %s: %w", c.Name, err)
+			}
+		}
+		m.started++
+		m.logger.Infof("started %s", c.Name)
+	}
+	return nil
+}
+
+// Stop stops the started components in the reverse order, each within its
+// timeout. It returns the first error but stops all of them.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, fn := range m.shutdown {
+		fn()
+	}
+	return m.stop(ctx)
+}
+
+func (m *Manager) stop(ctx context.Context) error {
+	var first error
+	for ; m.started > 0; m.started-- {
+		c := m.components[m.started-1]
+		if c.Stop == nil {
+			continue
+		}
+
+		start := time.Now()
+		err := stopWithin(ctx, c)
+		if err != nil {
+			m.logger.Errorf("stop %s: %s", c.Name, err)
+			if first == nil {
+				first = xerrors.Errorf("stop %s: %w", c.Name, err)
+			}
+			continue
+		}
+		m.logger.Infof("stopped %s in %s", c.Name, time.Since(start))
+	}
+	return first
+}
+
+func stopWithin(ctx context.Context, c Component) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	return c.Stop(ctx)
+}
+
+// Reload applies cfg to the components, the settings that can't change
+// while running are ignored.
+func (m *Manager) Reload(cfg *config.Config) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, fn := range m.reload {
+		if err := fn(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
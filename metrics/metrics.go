@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "xspace"
+
+var (
+	// RequestDuration observes the API requests by method, route and
+	// status.
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the API requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Logins counts the logins by provider and result, success or the code
+	// of the error.
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Logins by provider and result.",
+	}, []string{"provider", "result"})
+
+	// ChainRequestDuration observes the rpc requests to the chain by method
+	// and result, ok or error.
+	ChainRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the chain rpc requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+
+	// IndexerLag is how long the last indexed transfer waited to be indexed.
+	IndexerLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "lag_seconds",
+		Help:      "Seconds between the last NFT transfer and its indexing.",
+	})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RequestDuration,
+		Logins,
+		ChainRequestDuration,
+		IndexerLag,
+	)
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on every scrape,
+// for values kept elsewhere such as the size of a queue.
+func GaugeFunc(name, help string, fn func() float64) error {
+	return registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
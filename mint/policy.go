@@ -0,0 +1,135 @@
+package mint
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
+)
+
+const (
+	CodeInsufficientPoints = "INSUFFICIENT_POINTS"
+	CodeFileTooLarge       = "FILE_TOO_LARGE"
+	CodeQuotaExceeded      = "MINT_QUOTA_EXCEEDED"
+	CodeStorageCapExceeded = "STORAGE_CAP_EXCEEDED"
+)
+
+// PolicyError is returned when a mint request is rejected by the policy.
+// Status is 400 if the file is too large, 409 if the user lacks the points
+// or would exceed the storage cap, which only passes after burning some
+// dataNFTs, and 429 if the daily quota is used up and the user should retry
+// after RetryAfter. The mint endpoints first documented 502 and 503 for
+// these, but both mean a failing upstream that a client or proxy retries,
+// while a rejected mint is the request's fault; 502 and 503 are left to the
+// chain and storage failures.
+type PolicyError struct {
+	Status     int           `json:"-"`
+	RetryAfter time.Duration `json:"-"`
+
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Limit   int64  `json:"limit,omitempty"`
+	Current int64  `json:"current"`
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// Policy decides whether an address may mint an NFT and how many points
+// the mint costs.
+type Policy struct {
+	cfg        atomic.Pointer[config.MintConfig]
+	storageCfg atomic.Pointer[config.StorageConfig]
+	store      *database.DataStore
+}
+
+func NewPolicy(cfg config.MintConfig, storageCfg config.StorageConfig, store *database.DataStore) *Policy {
+	p := &Policy{store: store}
+	p.SetConfig(cfg, storageCfg)
+	return p
+}
+
+// SetConfig applies the mint limits and the storage caps of the config to
+// the next mints.
+func (p *Policy) SetConfig(cfg config.MintConfig, storageCfg config.StorageConfig) {
+	p.cfg.Store(&cfg)
+	p.storageCfg.Store(&storageCfg)
+}
+
+// CheckFileSize rejects dataNFT files bigger than the configured limit.
+func (p *Policy) CheckFileSize(size int64) error {
+	if max := p.cfg.Load().MaxFileSize; max > 0 && size > max {
+		return &PolicyError{
+			Status:  http.StatusBadRequest,
+			Code:    CodeFileTooLarge,
+			Message: fmt.Sprintf("file size %d exceeds the limit %d", size, max),
+			Limit:   max,
+			Current: size,
+		}
+	}
+	return nil
+}
+
+// CreateJob fills in the job's cost, then debits the points and creates the
+// job atomically if the owner is within quota and can afford it.
+func (p *Policy) CreateJob(job *database.MintJob) error {
+	cfg, storageCfg := p.cfg.Load(), p.storageCfg.Load()
+	var limits database.MintLimits
+	switch job.Type {
+	case database.TweetNFT:
+		job.Cost = cfg.TweetCost
+		limits.DailyQuota = cfg.TweetDailyQuota
+	case database.DataNFT:
+		if err := p.CheckFileSize(job.Size); err != nil {
+			return err
+		}
+		job.Cost = cfg.DataCost
+		limits.DailyQuota = cfg.DataDailyQuota
+		limits.MaxObjects = storageCfg.MaxUserObjects
+		limits.MaxSpace = storageCfg.MaxUserSpace
+	default:
+		return xerrors.Errorf("unsupported nft type %d", job.Type)
+	}
+
+	err := p.store.CreateMintJob(job, limits)
+	quota := limits.DailyQuota
+	if xerrors.Is(err, database.ErrMintQuotaExceeded) {
+		now := time.Now().UTC()
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return &PolicyError{
+			Status:     http.StatusTooManyRequests,
+			RetryAfter: tomorrow.Sub(now),
+			Code:       CodeQuotaExceeded,
+			Message:    fmt.Sprintf("daily quota of %d mints is used up", quota),
+			Limit:      quota,
+			Current:    quota,
+		}
+	}
+	if xerrors.Is(err, database.ErrStorageCapExceeded) {
+		usage, _ := p.store.GetUserStorage(job.Address)
+		return &PolicyError{
+			Status: http.StatusConflict,
+			Code:   CodeStorageCapExceeded,
+			Message: fmt.Sprintf("storing %d more bytes exceeds the cap of %d objects and %d bytes",
+				job.Size, storageCfg.MaxUserObjects, storageCfg.MaxUserSpace),
+			Limit:   storageCfg.MaxUserSpace,
+			Current: usage.Space,
+		}
+	}
+	if xerrors.Is(err, database.ErrInsufficientPoints) {
+		user, _ := p.store.GetUserPoint(job.Address)
+		return &PolicyError{
+			Status:  http.StatusConflict,
+			Code:    CodeInsufficientPoints,
+			Message: fmt.Sprintf("minting costs %d points, but only %d left", job.Cost, user.Points),
+			Limit:   job.Cost,
+			Current: user.Points,
+		}
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package mint
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/contract/nft"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
+)
+
+// Worker mints the pending jobs on chain one by one.
+type Worker struct {
+	store         *database.DataStore
+	nftController *nft.NFTController
+	logger        *klog.Helper
+	interval      time.Duration
+
+	// closed by Stop, no job is taken afterwards
+	stopping chan struct{}
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func NewWorker(store *database.DataStore, nftController *nft.NFTController, logger *klog.Helper) *Worker {
+	return &Worker{
+		store:         store,
+		nftController: nftController,
+		logger:        logger,
+		interval:      2 * time.Second,
+		stopping:      make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+func (w *Worker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.processPending(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.stopping:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop takes no more jobs and waits for the mint in flight. Once ctx is
+// done the mint is interrupted, its job stays pending and is minted after
+// the restart.
+func (w *Worker) Stop(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stopping) })
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return xerrors.Errorf("interrupt the mint in flight: %w", ctx.Err())
+	}
+}
+
+func (w *Worker) processPending(ctx context.Context) {
+	jobs, err := w.store.ListPendingMintJobs(100)
+	if err != nil {
+		w.logger.Error(err)
+		return
+	}
+
+	for i := range jobs {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stopping:
+			return
+		default:
+		}
+		w.process(ctx, &jobs[i])
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job *database.MintJob) {
+	tokenID, err := w.nftController.Mint(ctx, job.Address, job.Type)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		w.logger.Errorf("mint job %d: %s", job.ID, err)
+		if err := w.store.FailMintJob(job, err.Error()); err != nil {
+			w.logger.Error(err)
+		}
+		return
+	}
+
+	if err := w.store.CompleteMintJob(job, tokenID); err != nil {
+		w.logger.Error(err)
+	}
+}
@@ -0,0 +1,194 @@
+package partner
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/ratelimit"
+	"golang.org/x/xerrors"
+)
+
+const (
+	CodeKeyInvalid       = "API_KEY_INVALID"
+	CodeSignatureInvalid = "SIGNATURE_INVALID"
+	CodeTimestampSkewed  = "TIMESTAMP_SKEWED"
+	CodeRateLimited      = "RATE_LIMITED"
+)
+
+var ErrDisabled = xerrors.New("partner API keys are not enabled on this server")
+
+// Error is returned when a partner request is rejected, Status is the http
+// status replied and a rate limited request can be retried after
+// RetryAfter.
+type Error struct {
+	Status     int           `json:"-"`
+	RetryAfter time.Duration `json:"-"`
+
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Authenticator issues partner API keys and verifies the requests signed
+// with them.
+//
+// The secret of a key is HMAC-SHA256(server secret, key id), so it is never
+// stored; the key keeps the SHA-256 of the secret, which also tells if the
+// server secret has changed since. A request carries the key id in the
+// X-Api-Key header, the unix time in X-Timestamp and in X-Signature the hex
+// HMAC-SHA256, keyed with the secret, of
+//
+//	timestamp + "\n" + method + "\n" + path + "\n" + body
+//
+// The requests of a key are limited by a token bucket in the rate limit
+// store, so a store shared by the replicas limits the key across them.
+type Authenticator struct {
+	cfg     config.PartnerConfig
+	secret  []byte
+	store   *database.DataStore
+	buckets ratelimit.Store
+}
+
+func NewAuthenticator(cfg config.PartnerConfig, store *database.DataStore, buckets ratelimit.Store) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg, store: store, buckets: buckets}
+	if cfg.Secret == "" {
+		return a, nil
+	}
+
+	secret, err := hex.DecodeString(cfg.Secret)
+	if err != nil {
+		return nil, xerrors.Errorf("decode partner secret: %w", err)
+	}
+	if len(secret) != 32 {
+		return nil, xerrors.Errorf("partner secret must be 32 bytes, got %d", len(secret))
+	}
+	a.secret = secret
+	return a, nil
+}
+
+// NewKey issues a key of the project, the returned secret is only shown
+// once. A rateLimit of 0 uses the configured default.
+func (a *Authenticator) NewKey(actor string, projectID uint, name string, rateLimit int) (database.PartnerKey, string, error) {
+	if a.secret == nil {
+		return database.PartnerKey{}, "", ErrDisabled
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return database.PartnerKey{}, "", err
+	}
+	if rateLimit <= 0 {
+		rateLimit = a.cfg.RateLimit
+	}
+
+	key := database.PartnerKey{
+		ID:        hex.EncodeToString(b),
+		ProjectID: projectID,
+		Name:      name,
+		RateLimit: rateLimit,
+	}
+	secret := a.keySecret(key.ID)
+	key.SecretHash = hashSecret(secret)
+	if err := a.store.CreatePartnerKey(actor, &key); err != nil {
+		return database.PartnerKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// Verify checks the key, the signature and the rate limit of a request and
+// returns the key.
+func (a *Authenticator) Verify(keyID, timestamp, signature, method, path string, body []byte) (database.PartnerKey, error) {
+	if a.secret == nil {
+		return database.PartnerKey{}, ErrDisabled
+	}
+
+	key, err := a.store.GetPartnerKey(keyID)
+	if xerrors.Is(err, database.ErrNotFound) {
+		return key, rejected(CodeKeyInvalid, "unknown API key")
+	}
+	if err != nil {
+		return key, err
+	}
+	secret := a.keySecret(key.ID)
+	if key.Revoked || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return key, rejected(CodeKeyInvalid, "API key is revoked")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return key, rejected(CodeTimestampSkewed, "invalid X-Timestamp")
+	}
+	// in seconds, a duration overflows for timestamps centuries away
+	skew := time.Now().Unix() - ts
+	if skew < -a.cfg.SignatureTTL || skew > a.cfg.SignatureTTL {
+		return key, rejected(CodeTimestampSkewed, "X-Timestamp is too far from the server's time")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", timestamp, method, path)
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return key, rejected(CodeSignatureInvalid, "invalid X-Signature")
+	}
+
+	retryAfter, err := a.allow(key)
+	if err != nil {
+		return key, err
+	}
+	if retryAfter > 0 {
+		return key, &Error{
+			Status:     http.StatusTooManyRequests,
+			RetryAfter: retryAfter,
+			Code:       CodeRateLimited,
+			Message:    fmt.Sprintf("API key is limited to %d requests per minute", key.RateLimit),
+		}
+	}
+	return key, nil
+}
+
+// allow takes a token for the request and returns how long the key has to
+// wait if it is over its limit or its bucket is contended. A key without a
+// limit is always allowed.
+func (a *Authenticator) allow(key database.PartnerKey) (time.Duration, error) {
+	if key.RateLimit <= 0 {
+		return 0, nil
+	}
+
+	rule := ratelimit.Rule{Rate: key.RateLimit, Burst: key.RateLimit}
+	res, err := a.buckets.TakeToken("partner/key/"+key.ID, rule, time.Now())
+	if xerrors.Is(err, ratelimit.ErrContended) {
+		return time.Minute / time.Duration(key.RateLimit), nil
+	}
+	if err != nil || res.Allowed {
+		return 0, err
+	}
+	return res.RetryAfter, nil
+}
+
+func (a *Authenticator) keySecret(keyID string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(keyID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func rejected(code, message string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: code, Message: message}
+}
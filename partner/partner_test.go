@@ -0,0 +1,179 @@
+package partner
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/ratelimit"
+	"golang.org/x/xerrors"
+)
+
+const testSecret = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func newTestAuthenticator(t *testing.T, secret string, rateLimit int) (*Authenticator, *database.DataStore) {
+	t.Helper()
+	store, err := database.NewDataStore("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.CreateProject("test", &database.Project{Name: "project"}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAuthenticator(config.PartnerConfig{Secret: secret, RateLimit: rateLimit, SignatureTTL: 300}, store, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, store
+}
+
+func sign(secret string, timestamp, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", timestamp, method, path)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func code(err error) string {
+	var partnerErr *Error
+	if xerrors.As(err, &partnerErr) {
+		return partnerErr.Code
+	}
+	return ""
+}
+
+func TestVerify(t *testing.T) {
+	a, store := newTestAuthenticator(t, testSecret, 0)
+	key, secret, err := a.NewKey("test", 1, "key", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedSecret, err := a.NewKey("test", 1, "revoked", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokePartnerKey("test", revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	const method, path = http.MethodPost, "/v1/partner/events"
+	body := []byte(`{"events":[]}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	next := strconv.FormatInt(time.Now().Unix()+1, 10)
+
+	for _, tc := range []struct {
+		name                        string
+		keyID, timestamp, signature string
+		method, path                string
+		body                        []byte
+		code                        string
+	}{
+		{"valid", key.ID, now, sign(secret, now, method, path, body), method, path, body, ""},
+		{"tampered body", key.ID, now, sign(secret, now, method, path, body), method, path, []byte(`{"events":[{}]}`), CodeSignatureInvalid},
+		{"tampered path", key.ID, now, sign(secret, now, method, path, body), method, "/v1/partner/other", body, CodeSignatureInvalid},
+		{"tampered method", key.ID, now, sign(secret, now, method, path, body), http.MethodPut, path, body, CodeSignatureInvalid},
+		{"tampered timestamp", key.ID, next, sign(secret, now, method, path, body), method, path, body, CodeSignatureInvalid},
+		{"far future", key.ID, now + "0", sign(secret, now+"0", method, path, body), method, path, body, CodeTimestampSkewed},
+		{"wrong secret", key.ID, now, sign(revokedSecret, now, method, path, body), method, path, body, CodeSignatureInvalid},
+		{"revoked key", revoked.ID, now, sign(revokedSecret, now, method, path, body), method, path, body, CodeKeyInvalid},
+		{"unknown key", "unknown", now, sign(secret, now, method, path, body), method, path, body, CodeKeyInvalid},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := a.Verify(tc.keyID, tc.timestamp, tc.signature, tc.method, tc.path, tc.body)
+			if code(err) != tc.code || (tc.code == "" && err != nil) {
+				t.Fatalf("%v, expected code %q", err, tc.code)
+			}
+		})
+	}
+}
+
+func TestVerifyTimestamp(t *testing.T) {
+	a, _ := newTestAuthenticator(t, testSecret, 0)
+	key, secret, err := a.NewKey("test", 1, "key", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for skew, valid := range map[time.Duration]bool{
+		0:                  true,
+		-299 * time.Second: true,
+		299 * time.Second:  true,
+		-301 * time.Second: false,
+		301 * time.Second:  false,
+		-time.Hour:         false,
+	} {
+		ts := strconv.FormatInt(time.Now().Add(skew).Unix(), 10)
+		_, err := a.Verify(key.ID, ts, sign(secret, ts, http.MethodPost, "/", nil), http.MethodPost, "/", nil)
+		if valid && err != nil {
+			t.Errorf("timestamp %s off: %v", skew, err)
+		}
+		if !valid && code(err) != CodeTimestampSkewed {
+			t.Errorf("timestamp %s off: %v, expected %s", skew, err, CodeTimestampSkewed)
+		}
+	}
+
+	_, err = a.Verify(key.ID, "yesterday", sign(secret, "yesterday", http.MethodPost, "/", nil), http.MethodPost, "/", nil)
+	if code(err) != CodeTimestampSkewed {
+		t.Errorf("invalid timestamp: %v, expected %s", err, CodeTimestampSkewed)
+	}
+}
+
+func TestVerifySecretChanged(t *testing.T) {
+	a, store := newTestAuthenticator(t, testSecret, 0)
+	key, _, err := a.NewKey("test", 1, "key", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the keys issued with another server secret are no longer valid
+	other, err := NewAuthenticator(config.PartnerConfig{Secret: "ff" + testSecret[2:], SignatureTTL: 300}, store, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	secret := other.keySecret(key.ID)
+	if _, err := other.Verify(key.ID, ts, sign(secret, ts, http.MethodPost, "/", nil), http.MethodPost, "/", nil); code(err) != CodeKeyInvalid {
+		t.Fatalf("key of another server secret: %v, expected %s", err, CodeKeyInvalid)
+	}
+}
+
+func TestVerifyRateLimit(t *testing.T) {
+	a, _ := newTestAuthenticator(t, testSecret, 2)
+	key, secret, err := a.NewKey("test", 1, "key", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := sign(secret, ts, http.MethodPost, "/", nil)
+	for i := 0; i < 2; i++ {
+		if _, err := a.Verify(key.ID, ts, signature, http.MethodPost, "/", nil); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	_, err = a.Verify(key.ID, ts, signature, http.MethodPost, "/", nil)
+	var partnerErr *Error
+	if !xerrors.As(err, &partnerErr) || partnerErr.Status != http.StatusTooManyRequests || partnerErr.RetryAfter <= 0 {
+		t.Fatalf("request over the limit: %v", err)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	a, _ := newTestAuthenticator(t, "", 0)
+	if _, _, err := a.NewKey("test", 1, "key", 0); err != ErrDisabled {
+		t.Fatalf("new key: %v, expected ErrDisabled", err)
+	}
+	if _, err := a.Verify("key", "0", "", http.MethodPost, "/", nil); err != ErrDisabled {
+		t.Fatalf("verify: %v, expected ErrDisabled", err)
+	}
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/memoio/xspace-server/config"
+	"golang.org/x/xerrors"
+)
+
+// Groups of routes limited together.
+const (
+	// every API request
+	GroupGlobal = "global"
+	// requests issuing challenge nonces
+	GroupChallenge = "challenge"
+	// requests verifying signatures: login, refresh and wallet links
+	GroupLogin = "login"
+	// mint requests
+	GroupMint = "mint"
+)
+
+// Who a bucket limits.
+const (
+	ByIP      = "ip"
+	ByAddress = "address"
+)
+
+// Rule is a token bucket holding up to Burst tokens and refilled with Rate
+// tokens per minute, a request takes one token.
+type Rule struct {
+	Rate  int
+	Burst int
+}
+
+// Bucket is the state of a token bucket.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// the burst of the bucket, 0 if it is unlimited
+	Limit     int
+	Remaining int
+	// until the bucket is full again
+	Reset time.Duration
+	// until a token is available, if not allowed
+	RetryAfter time.Duration
+}
+
+// Take refills the bucket up to now and takes a token from it, a new bucket
+// is full.
+func (r Rule) Take(b Bucket, now time.Time) (Bucket, Result) {
+	rate := float64(r.Rate) / 60
+	if b.Updated.IsZero() {
+		b.Tokens = float64(r.Burst)
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(r.Burst), b.Tokens+elapsed*rate)
+	}
+	b.Updated = now
+
+	res := Result{Limit: r.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+	res.Remaining = int(b.Tokens)
+	res.Reset = seconds((float64(r.Burst) - b.Tokens) / rate)
+	return b, res
+}
+
+// Full returns when the bucket is full again, a full bucket is the same as
+// a new one and can be dropped.
+func (r Rule) Full(b Bucket) time.Time {
+	return b.Updated.Add(seconds((float64(r.Burst) - b.Tokens) / (float64(r.Rate) / 60)))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// ErrContended is returned by a store whose bucket is too busy with
+// concurrent requests to take a token in time.
+var ErrContended = xerrors.New("rate bucket is contended")
+
+// Store keeps the buckets. A store shared by all replicas enforces the
+// limits across them, a memory store limits each replica on its own.
+type Store interface {
+	// TakeToken takes a token from the bucket of the key.
+	TakeToken(key string, rule Rule, now time.Time) (Result, error)
+}
+
+// Limiter limits the requests of the route groups by the rules of the
+// config.
+type Limiter struct {
+	store Store
+	rules atomic.Pointer[map[string]config.RateLimitRule]
+}
+
+func NewLimiter(cfg config.RateLimitConfig, store Store) *Limiter {
+	l := &Limiter{store: store}
+	l.SetConfig(cfg)
+	return l
+}
+
+// SetConfig applies the rules of the groups, the buckets keep their tokens.
+func (l *Limiter) SetConfig(cfg config.RateLimitConfig) {
+	l.rules.Store(&cfg.Groups)
+}
+
+// Allow takes a token for a request of the group made by the IP or the
+// address. Groups and clients without a limit are always allowed, a request
+// to a contended bucket is denied: it is most likely part of a burst.
+func (l *Limiter) Allow(group, by, id string) (Result, error) {
+	cfg := (*l.rules.Load())[group]
+	rule := Rule{Rate: cfg.IP, Burst: cfg.Burst}
+	if by == ByAddress {
+		rule.Rate = cfg.Address
+	}
+	if rule.Rate <= 0 || id == "" {
+		return Result{Allowed: true}, nil
+	}
+	if rule.Burst <= 0 {
+		rule.Burst = rule.Rate
+	}
+
+	res, err := l.store.TakeToken(group+"/"+by+"/"+id, rule, time.Now())
+	if xerrors.Is(err, ErrContended) {
+		wait := seconds(60 / float64(rule.Rate))
+		return Result{Limit: rule.Burst, Reset: wait, RetryAfter: wait}, nil
+	}
+	return res, err
+}
+
+// MemoryStore keeps the buckets in process memory.
+type MemoryStore struct {
+	lock      sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	full time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) TakeToken(key string, rule Rule, now time.Time) (Result, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sweep(now)
+	b, res := rule.Take(s.buckets[key].Bucket, now)
+	s.buckets[key] = memoryBucket{Bucket: b, full: rule.Full(b)}
+	return res, nil
+}
+
+// sweep drops the buckets that are full again, which are the same as new
+// ones, at most once a minute. The caller must hold the lock.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/memoio/xspace-server/config"
+	"golang.org/x/xerrors"
+)
+
+func TestMemoryStoreBurstRefill(t *testing.T) {
+	store := NewMemoryStore()
+	rule := Rule{Rate: 60, Burst: 3}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		res, err := store.TakeToken("key", rule, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Remaining != 2-i || res.Limit != 3 {
+			t.Fatalf("request %d of the burst: %+v", i, res)
+		}
+	}
+	res, _ := store.TakeToken("key", rule, now)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("request after the burst: %+v", res)
+	}
+
+	// one token per second comes back
+	res, _ = store.TakeToken("key", rule, now.Add(time.Second))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("request after a refill: %+v", res)
+	}
+	res, _ = store.TakeToken("key", rule, now.Add(time.Second))
+	if res.Allowed {
+		t.Fatalf("second request after a refill of one token: %+v", res)
+	}
+
+	// the bucket fills up to its burst only
+	res, _ = store.TakeToken("key", rule, now.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("request after a long wait: %+v", res)
+	}
+
+	res, _ = store.TakeToken("other", rule, now)
+	if !res.Allowed {
+		t.Fatalf("request of another key: %+v", res)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	rule := Rule{Rate: 60, Burst: 3}
+	now := time.Now()
+
+	store.TakeToken("key", rule, now)
+	store.TakeToken("full", rule, now.Add(-time.Hour))
+	store.TakeToken("sweep", rule, now.Add(time.Minute))
+	if _, ok := store.buckets["full"]; ok {
+		t.Fatal("full bucket not swept")
+	}
+	if _, ok := store.buckets["key"]; ok {
+		t.Fatal("bucket full again not swept")
+	}
+	if _, ok := store.buckets["sweep"]; !ok {
+		t.Fatal("bucket in use swept")
+	}
+}
+
+// contendedStore fails to take any token in time.
+type contendedStore struct{}
+
+func (contendedStore) TakeToken(key string, rule Rule, now time.Time) (Result, error) {
+	return Result{}, xerrors.Errorf("rate bucket %s: %w", key, ErrContended)
+}
+
+func TestLimiter(t *testing.T) {
+	cfg := config.RateLimitConfig{Groups: map[string]config.RateLimitRule{
+		GroupLogin: {IP: 60, Address: 6},
+	}}
+
+	l := NewLimiter(cfg, NewMemoryStore())
+	for _, tc := range []struct {
+		group, by, id string
+		limit         int
+	}{
+		{GroupLogin, ByIP, "127.0.0.1", 60},
+		{GroupLogin, ByAddress, "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4", 6},
+		{GroupLogin, ByAddress, "", 0},
+		{GroupMint, ByIP, "127.0.0.1", 0},
+	} {
+		res, err := l.Allow(tc.group, tc.by, tc.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Limit != tc.limit {
+			t.Errorf("%s by %s %q: %+v, expected a limit of %d", tc.group, tc.by, tc.id, res, tc.limit)
+		}
+	}
+
+	l = NewLimiter(cfg, contendedStore{})
+	res, err := l.Allow(GroupLogin, ByAddress, "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed || res.RetryAfter != 10*time.Second {
+		t.Fatalf("request to a contended bucket: %+v", res)
+	}
+}
@@ -0,0 +1,437 @@
+package realtime
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/event"
+	"golang.org/x/xerrors"
+)
+
+// Topics a stream subscribes to.
+const (
+	// point changes and charges
+	TopicPoints = "points"
+	// mint job status transitions
+	TopicMint = "mint"
+	// rank movements on the leaderboards of the subscribed projects
+	TopicRank = "rank"
+)
+
+// Names of the updates.
+const (
+	UpdatePoints = "points"
+	UpdateCharge = "charge"
+	UpdateMint   = "mint"
+	UpdateRank   = "rank"
+)
+
+var ErrTooManyStreams = xerrors.New("too many open event streams")
+
+// Update is pushed to the streams of the users it concerns.
+type Update struct {
+	Name string
+	Data interface{}
+}
+
+type RankUpdate struct {
+	ProjectID uint   `json:"projectId"`
+	Address   string `json:"address"`
+	Rank      int64  `json:"rank"`
+	// the previous rank, 0 if the stream didn't know it
+	Previous int64 `json:"previous"`
+	Score    int64 `json:"score"`
+}
+
+// Subscription is what a stream is interested in.
+type Subscription struct {
+	Topics   []string
+	Projects []uint
+}
+
+// Fanout passes the events to the hubs of all replicas.
+type Fanout interface {
+	PublishRealtime(record event.Record) error
+	// ListRealtime returns up to limit events published after the event with
+	// the id after, oldest first.
+	ListRealtime(after uint, limit int) ([]event.Record, error)
+	LastRealtimeID() (uint, error)
+}
+
+// how often the hub reads the events of the fanout
+const fanoutInterval = 200 * time.Millisecond
+
+// Hub pushes the events of the bus to the open streams of the users they
+// concern. An event reaches the hub of the replica dispatching it; without
+// a fanout the streams must be served by that replica, so the server must
+// run as a single instance. With a fanout the hub publishes the event and
+// the hubs of all replicas push it to their streams.
+type Hub struct {
+	cfg    atomic.Pointer[config.RealtimeConfig]
+	store  *database.DataStore
+	fanout Fanout
+	logger *klog.Helper
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+
+	mu      sync.Mutex
+	streams map[string]map[*Stream]struct{}
+	users   map[string]int
+	// closed by Shutdown
+	done     chan struct{}
+	shutdown sync.Once
+}
+
+// NewHub returns a hub, fanout is nil for a single instance.
+func NewHub(cfg config.RealtimeConfig, store *database.DataStore, fanout Fanout, logger *klog.Helper) *Hub {
+	h := &Hub{
+		store:   store,
+		fanout:  fanout,
+		logger:  logger,
+		stopped: make(chan struct{}),
+		streams: make(map[string]map[*Stream]struct{}),
+		users:   make(map[string]int),
+		done:    make(chan struct{}),
+	}
+	h.SetConfig(cfg)
+	return h
+}
+
+// SetConfig applies cfg, the open streams keep their queue size.
+func (h *Hub) SetConfig(cfg config.RealtimeConfig) {
+	h.cfg.Store(&cfg)
+}
+
+// Subscribe receives the events of the bus.
+func (h *Hub) Subscribe(bus *event.Bus) {
+	bus.SubscribeAsync("realtime", h.handle,
+		event.TypePointsCredited,
+		event.TypeChargeCompleted,
+		event.TypeMintJobUpdated,
+		event.TypeProjectScored,
+	)
+}
+
+// Start reads the events of the fanout published from now on, if any.
+func (h *Hub) Start(ctx context.Context) error {
+	if h.fanout == nil {
+		close(h.stopped)
+		return nil
+	}
+	cursor, err := h.fanout.LastRealtimeID()
+	if err != nil {
+		return err
+	}
+
+	ctx, h.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(h.stopped)
+		ticker := time.NewTicker(fanoutInterval)
+		defer ticker.Stop()
+
+		for {
+			cursor = h.readFanout(ctx, cursor)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops reading the events of the fanout.
+func (h *Hub) Stop(ctx context.Context) error {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	select {
+	case <-h.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readFanout pushes the events published after cursor and returns the new
+// cursor.
+func (h *Hub) readFanout(ctx context.Context, cursor uint) uint {
+	for ctx.Err() == nil {
+		records, err := h.fanout.ListRealtime(cursor, 100)
+		if err != nil {
+			h.logger.Error(err)
+			return cursor
+		}
+
+		for _, record := range records {
+			cursor = record.ID
+			msg, err := event.Decode(record)
+			if err == nil {
+				err = h.push(msg)
+			}
+			if err != nil {
+				h.logger.Error(err)
+			}
+		}
+		if len(records) < 100 {
+			break
+		}
+	}
+	return cursor
+}
+
+// Shutdown ends the open streams and the ones opened afterwards, so the
+// server doesn't wait for them to shut down.
+func (h *Hub) Shutdown() {
+	h.shutdown.Do(func() { close(h.done) })
+}
+
+// Open starts a stream of the updates of the user's wallets, it starts with
+// the ranks of the wallets on the subscribed leaderboards.
+func (h *Hub) Open(userID string, addresses []string, sub Subscription) (*Stream, error) {
+	s, err := h.open(userID, addresses, sub)
+	if err != nil || !s.topics[TopicRank] {
+		return s, err
+	}
+
+	for project := range s.projects {
+		if err := h.pushRank(project, s.addresses, []*Stream{s}); err != nil {
+			h.Close(s)
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (h *Hub) open(userID string, addresses []string, sub Subscription) (*Stream, error) {
+	s := &Stream{
+		addresses: addresses,
+		topics:    make(map[string]bool),
+		projects:  make(map[uint]bool),
+		ranks:     make(map[string]int64),
+		updates:   make(chan Update, h.cfg.Load().QueueSize),
+		lagged:    make(chan struct{}, 1),
+		userID:    userID,
+	}
+	for _, topic := range sub.Topics {
+		s.topics[topic] = true
+	}
+	for _, project := range sub.Projects {
+		s.projects[project] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if max := h.cfg.Load().MaxStreams; max > 0 && h.users[userID] >= max {
+		return nil, ErrTooManyStreams
+	}
+	h.users[userID]++
+	for _, address := range addresses {
+		if h.streams[address] == nil {
+			h.streams[address] = make(map[*Stream]struct{})
+		}
+		h.streams[address][s] = struct{}{}
+	}
+	s.done = h.done
+	return s, nil
+}
+
+// Close stops pushing updates to the stream.
+func (h *Hub) Close(s *Stream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, address := range s.addresses {
+		delete(h.streams[address], s)
+		if len(h.streams[address]) == 0 {
+			delete(h.streams, address)
+		}
+	}
+	h.users[s.userID]--
+	if h.users[s.userID] <= 0 {
+		delete(h.users, s.userID)
+	}
+}
+
+func (h *Hub) handle(ctx context.Context, msg event.Message) error {
+	if h.fanout == nil {
+		return h.push(msg)
+	}
+
+	record, err := event.Encode(msg.Event)
+	if err != nil {
+		return err
+	}
+	record.EventID = msg.ID
+	return h.fanout.PublishRealtime(record)
+}
+
+// push pushes the event to the streams of this replica.
+func (h *Hub) push(msg event.Message) error {
+	switch e := msg.Event.(type) {
+	case *event.PointsCredited:
+		h.pushUpdate(e.Address, TopicPoints, Update{Name: UpdatePoints, Data: e})
+	case *event.ChargeCompleted:
+		h.pushUpdate(e.Address, TopicPoints, Update{Name: UpdateCharge, Data: e})
+	case *event.MintJobUpdated:
+		h.pushUpdate(e.Address, TopicMint, Update{Name: UpdateMint, Data: e})
+	case *event.ProjectScored:
+		return h.pushRanks(e.ProjectID)
+	}
+	return nil
+}
+
+func (h *Hub) pushUpdate(address, topic string, update Update) {
+	for _, s := range h.subscribers(address) {
+		if s.topics[topic] {
+			s.send(update)
+		}
+	}
+}
+
+// pushRanks pushes the rank of every streamed address on the leaderboard of
+// the project that moved since the stream last heard of it.
+func (h *Hub) pushRanks(projectID uint) error {
+	h.mu.Lock()
+	streams := make(map[string][]*Stream)
+	for address, subs := range h.streams {
+		for s := range subs {
+			if s.topics[TopicRank] && s.projects[projectID] {
+				streams[address] = append(streams[address], s)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	for address, subs := range streams {
+		if err := h.pushRank(projectID, []string{address}, subs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushRank pushes the ranks of the addresses to the streams they moved for.
+func (h *Hub) pushRank(projectID uint, addresses []string, streams []*Stream) error {
+	for _, address := range addresses {
+		rank, score, err := h.store.GetProjectRank(projectID, address)
+		if xerrors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, s := range streams {
+			previous, moved := s.moveRank(projectID, address, rank)
+			if moved {
+				s.send(Update{Name: UpdateRank, Data: RankUpdate{
+					ProjectID: projectID,
+					Address:   address,
+					Rank:      rank,
+					Previous:  previous,
+					Score:     score,
+				}})
+			}
+		}
+	}
+	return nil
+}
+
+func (h *Hub) subscribers(address string) []*Stream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := make([]*Stream, 0, len(h.streams[address]))
+	for s := range h.streams[address] {
+		subs = append(subs, s)
+	}
+	return subs
+}
+
+// Heartbeat is the interval of the heartbeats of the streams.
+func (h *Hub) Heartbeat() time.Duration {
+	heartbeat := h.cfg.Load().Heartbeat
+	if heartbeat <= 0 {
+		return 15 * time.Second
+	}
+	return time.Duration(heartbeat) * time.Second
+}
+
+// Stream is an open stream of a user.
+type Stream struct {
+	userID    string
+	addresses []string
+	topics    map[string]bool
+	projects  map[uint]bool
+
+	updates chan Update
+	lagged  chan struct{}
+	done    <-chan struct{}
+
+	mu sync.Mutex
+	// the last rank pushed, by project and address
+	ranks map[string]int64
+}
+
+// Updates returns the updates to write to the stream.
+func (s *Stream) Updates() <-chan Update {
+	return s.updates
+}
+
+// Lagged is signaled when updates were dropped because the stream didn't
+// keep up, the client should refetch its state.
+func (s *Stream) Lagged() <-chan struct{} {
+	return s.lagged
+}
+
+// Done is closed when the server shuts down.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Discard drops the buffered updates, after a resync they are stale.
+func (s *Stream) Discard() {
+	for {
+		select {
+		case <-s.updates:
+		default:
+			return
+		}
+	}
+}
+
+func (s *Stream) send(update Update) {
+	select {
+	case s.updates <- update:
+	default:
+		select {
+		case s.lagged <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Stream) moveRank(projectID uint, address string, rank int64) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := rankKey(projectID, address)
+	previous := s.ranks[key]
+	s.ranks[key] = rank
+	return previous, previous != rank
+}
+
+func rankKey(projectID uint, address string) string {
+	return address + "/" + strconv.FormatUint(uint64(projectID), 10)
+}
@@ -0,0 +1,56 @@
+package realtime
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/event"
+)
+
+func TestHubFanout(t *testing.T) {
+	store, err := database.NewDataStore("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	cfg := config.RealtimeConfig{QueueSize: 8}
+	logger := klog.NewHelper(klog.DefaultLogger)
+	// the bus of the replica dispatching dispatches the event, the stream is
+	// served by the other one
+	dispatching := NewHub(cfg, store, store, logger)
+	serving := NewHub(cfg, store, store, logger)
+	for _, hub := range []*Hub{dispatching, serving} {
+		if err := hub.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer hub.Stop(context.Background())
+	}
+
+	address := "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+	stream, err := serving.Open("user", []string{address}, Subscription{Topics: []string{TopicPoints}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serving.Close(stream)
+
+	msg := event.Message{ID: "event", Event: &event.PointsCredited{Address: address, Points: 10, Balance: 10}}
+	if err := dispatching.handle(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case update := <-stream.Updates():
+		credited, ok := update.Data.(*event.PointsCredited)
+		if update.Name != UpdatePoints || !ok || credited.Points != 10 {
+			t.Fatalf("update %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event didn't reach the other replica")
+	}
+}
@@ -0,0 +1,555 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
+)
+
+func LoadAdminModule(r *gin.RouterGroup, h *handler) {
+	r.Use(h.VerifyIdentityHandler, h.RequireRole(auth.RoleOperator))
+
+	r.GET("/storage/top", h.storageReport)
+
+	r.POST("/points", h.adjustPoints)
+
+	r.POST("/users/ban", h.banUser)
+	r.POST("/users/unban", h.unbanUser)
+
+	r.POST("/projects", h.createProject)
+	r.PUT("/projects/:id", h.updateProject)
+	r.DELETE("/projects/:id", h.deleteProject)
+
+	r.PUT("/roles", h.RequireRole(auth.RoleAdmin), h.setRole)
+	r.GET("/audit", h.RequireRole(auth.RoleAdmin), h.listAuditLogs)
+
+	r.POST("/partner/keys", h.RequireRole(auth.RoleAdmin), h.createPartnerKey)
+	r.GET("/partner/keys", h.RequireRole(auth.RoleAdmin), h.listPartnerKeys)
+	r.DELETE("/partner/keys/:id", h.RequireRole(auth.RoleAdmin), h.revokePartnerKey)
+
+	r.POST("/webhooks", h.RequireRole(auth.RoleAdmin), h.createWebhook)
+	r.GET("/webhooks", h.RequireRole(auth.RoleAdmin), h.listWebhooks)
+	r.DELETE("/webhooks/:id", h.RequireRole(auth.RoleAdmin), h.deleteWebhook)
+	r.GET("/webhooks/:id/deliveries", h.RequireRole(auth.RoleAdmin), h.listWebhookDeliveries)
+	r.POST("/webhooks/deliveries/:id/redeliver", h.RequireRole(auth.RoleAdmin), h.redeliverWebhook)
+}
+
+// @ Summary StorageReport
+//
+//	@Description	List the users consuming the most storage space, for operators and admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			page			query		string	true	"Pages"
+//	@Param			size			query		string	true	"The amount of data displayed on each page, at most 100"
+//	@Success		200				{object}	StorageReportRes
+//	@Router			/v1/admin/storage/top [get]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) storageReport(c *gin.Context) {
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	usages, err := h.store.ListTopStorageUsers(query.Page, query.Size)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	users := make([]StorageUsage, 0, len(usages))
+	for _, usage := range usages {
+		users = append(users, StorageUsage{Address: usage.Address, Count: usage.Count, Space: usage.Space})
+	}
+	c.JSON(200, StorageReportRes{Users: users})
+}
+
+// @ Summary AdjustPoints
+//
+//	@Description	Credit or debit the points of an address, for operators and admins. The adjustment is audit logged, the wallets of the caller's own user can't be adjusted.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			address			body		string	true	"The address"
+//	@Param			points			body		int		true	"Points credited, debited if negative"
+//	@Param			reason			body		string	true	"Why the points are adjusted"
+//	@Success		200				{object}	PointInfoRes
+//	@Router			/v1/admin/points [post]
+//	@Failure		400	{object}	APIError	"Invalid address or no reason"
+//	@Failure		403	{object}	APIError	"The address is a wallet of the caller"
+//	@Failure		409	{object}	APIError	"INSUFFICIENT_POINTS, not enough points to debit"
+//	@Failure		500	{object}	APIError
+func (h *handler) adjustPoints(c *gin.Context) {
+	var req AdjustPointsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+	address, ok := canonicalAddress(c, req.Address)
+	if !ok {
+		return
+	}
+	own, err := h.ownWallet(c, address)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+	if own {
+		abortWithMessage(c, 403, "The points of your own wallets can't be adjusted")
+		return
+	}
+
+	user, err := h.store.AdjustPoints(c.GetString("address"), address, req.Points, req.Reason)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, h.toPointInfoRes(user))
+}
+
+// ownWallet reports whether the address is a wallet of the caller's user.
+func (h *handler) ownWallet(c *gin.Context, address string) (bool, error) {
+	if address == c.GetString("address") {
+		return true, nil
+	}
+	userID, err := h.store.GetUserIDByAddress(address)
+	if xerrors.Is(err, database.ErrNotFound) {
+		return false, nil
+	}
+	return userID != "" && userID == c.GetString("user"), err
+}
+
+// @ Summary BanUser
+//
+//	@Description	Ban the user owning the address, with all its linked wallets, and revoke its sessions. For operators and admins, who can only ban users of a lower role.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			address			body		string	true	"Any wallet of the user"
+//	@Param			reason			body		string	true	"Why the user is banned"
+//	@Success		200				{string}	string
+//	@Router			/v1/admin/users/ban [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) banUser(c *gin.Context) {
+	var req BanUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+	address, ok := canonicalAddress(c, req.Address)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.lowerRankedUser(c, address)
+	if !ok {
+		return
+	}
+
+	err := h.store.BanUser(c.GetString("address"), userID, req.Reason)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "User not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+// @ Summary UnbanUser
+//
+//	@Description	Lift the ban of the user owning the address, for operators and admins, who can only unban users of a lower role.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			address			body		string	true	"Any wallet of the user"
+//	@Success		200				{string}	string
+//	@Router			/v1/admin/users/unban [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError	"The user is not found or not banned"
+//	@Failure		500	{object}	APIError
+func (h *handler) unbanUser(c *gin.Context) {
+	var req BanUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+	address, ok := canonicalAddress(c, req.Address)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.lowerRankedUser(c, address)
+	if !ok {
+		return
+	}
+
+	err := h.store.UnbanUser(c.GetString("address"), userID)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "User not banned")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+// lowerRankedUser returns the user of the wallet if all its wallets have a
+// lower role than the caller, the user has the highest role of its wallets.
+// Otherwise it replies 403, or 404 if the wallet has no user.
+func (h *handler) lowerRankedUser(c *gin.Context, address string) (string, bool) {
+	userID, err := h.store.GetUserIDByAddress(address)
+	var wallets []string
+	if err == nil {
+		wallets, err = h.store.ListUserWallets(userID)
+	}
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "User not found")
+		return "", false
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return "", false
+	}
+
+	for _, wallet := range wallets {
+		role, err := h.store.GetRole(wallet)
+		if err != nil {
+			h.abortWithError(c, err)
+			return "", false
+		}
+		if auth.HasRole(role, c.GetString("role")) {
+			abortWithMessage(c, 403, "Permission denied")
+			return "", false
+		}
+	}
+	return userID, true
+}
+
+// @ Summary CreateProject
+//
+//	@Description	Add a cooperative project, for operators and admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			project			body		ProjectInfo	true	"The project, its ProjectID is ignored"
+//	@Success		200				{object}	ProjectInfo
+//	@Router			/v1/admin/projects [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) createProject(c *gin.Context) {
+	project, ok := bindProject(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.CreateProject(c.GetString("address"), project); err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, ProjectInfo{ProjectID: int(project.ID), Name: project.Name, Start: project.Start, End: project.End})
+}
+
+// @ Summary UpdateProject
+//
+//	@Description	Change the name or period of a cooperative project, for operators and admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string		true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			id				path		string		true	"Project id"
+//	@Param			project			body		ProjectInfo	true	"The project, its ProjectID is ignored"
+//	@Success		200				{object}	ProjectInfo
+//	@Router			/v1/admin/projects/{id} [put]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) updateProject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid project id")
+		return
+	}
+	project, ok := bindProject(c)
+	if !ok {
+		return
+	}
+	project.ID = uint(id)
+
+	err = h.store.UpdateProject(c.GetString("address"), project)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Project not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, ProjectInfo{ProjectID: int(project.ID), Name: project.Name, Start: project.Start, End: project.End})
+}
+
+// @ Summary DeleteProject
+//
+//	@Description	Remove a cooperative project, for operators and admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			id				path		string	true	"Project id"
+//	@Success		200				{string}	string
+//	@Router			/v1/admin/projects/{id} [delete]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) deleteProject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid project id")
+		return
+	}
+
+	err = h.store.DeleteProject(c.GetString("address"), uint(id))
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Project not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+// @ Summary SetRole
+//
+//	@Description	Grant a role to an address, for admins. The new role is in the tokens issued after the change, a demoted address is logged out.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			address			body		string	true	"The address"
+//	@Param			role			body		string	true	"user, operator or admin"
+//	@Success		200				{string}	string
+//	@Router			/v1/admin/roles [put]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) setRole(c *gin.Context) {
+	var req SetRoleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+	address, ok := canonicalAddress(c, req.Address)
+	if !ok {
+		return
+	}
+	if address == c.GetString("address") {
+		abortWithMessage(c, 400, "Admins can't change their own role")
+		return
+	}
+
+	if err := h.store.SetRole(c.GetString("address"), address, req.Role); err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+// @ Summary AuditLog
+//
+//	@Description	List the actions taken with the admin APIs, newest first, for admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			action			query		string	false	"Only list the action, e.g. points.adjust, user.ban, user.unban, role.set, project.create"
+//	@Param			page			query		string	true	"Pages"
+//	@Param			size			query		string	true	"The amount of data displayed on each page, at most 100"
+//	@Success		200				{object}	AuditLogRes
+//	@Router			/v1/admin/audit [get]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) listAuditLogs(c *gin.Context) {
+	var query AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	logs, err := h.store.ListAuditLogs(query.Action, query.Page, query.Size)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	infos := make([]AuditLogInfo, 0, len(logs))
+	for _, log := range logs {
+		infos = append(infos, AuditLogInfo{
+			ID:     log.ID,
+			Actor:  log.Actor,
+			Action: log.Action,
+			Target: log.Target,
+			Detail: log.Detail,
+			Time:   log.CreatedAt,
+		})
+	}
+	c.JSON(200, AuditLogRes{Logs: infos})
+}
+
+// @ Summary CreatePartnerKey
+//
+//	@Description	Issue an API key for a cooperative project to report the activities of its users, for admins. The secret is only returned here.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string				true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			key				body		CreatePartnerKeyReq	true	"The project and name of the key"
+//	@Success		200				{object}	CreatePartnerKeyRes
+//	@Router			/v1/admin/partner/keys [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError	"Project not found"
+//	@Failure		500	{object}	APIError
+//	@Failure		503	{object}	APIError	"Partner API keys are not enabled"
+func (h *handler) createPartnerKey(c *gin.Context) {
+	var req CreatePartnerKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	key, secret, err := h.partners.NewKey(c.GetString("address"), req.ProjectID, req.Name, req.RateLimit)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Project not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, CreatePartnerKeyRes{PartnerKeyInfo: toPartnerKeyInfo(key), Secret: secret})
+}
+
+// @ Summary ListPartnerKeys
+//
+//	@Description	List the partner API keys, for admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			project			query		string	false	"Only list the keys of the project"
+//	@Success		200				{object}	ListPartnerKeysRes
+//	@Router			/v1/admin/partner/keys [get]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) listPartnerKeys(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.DefaultQuery("project", "0"), 10, 32)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid project id")
+		return
+	}
+
+	keys, err := h.store.ListPartnerKeys(uint(projectID))
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	infos := make([]PartnerKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, toPartnerKeyInfo(key))
+	}
+	c.JSON(200, ListPartnerKeysRes{Keys: infos})
+}
+
+// @ Summary RevokePartnerKey
+//
+//	@Description	Revoke a partner API key, for admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			id				path		string	true	"The key id"
+//	@Success		200				{string}	string
+//	@Router			/v1/admin/partner/keys/{id} [delete]
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) revokePartnerKey(c *gin.Context) {
+	err := h.store.RevokePartnerKey(c.GetString("address"), c.Param("id"))
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Key not found or revoked")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+func toPartnerKeyInfo(key database.PartnerKey) PartnerKeyInfo {
+	return PartnerKeyInfo{
+		KeyID:      key.ID,
+		ProjectID:  key.ProjectID,
+		Name:       key.Name,
+		RateLimit:  key.RateLimit,
+		Revoked:    key.Revoked,
+		CreateTime: key.CreatedAt,
+	}
+}
+
+func bindProject(c *gin.Context) (*database.Project, bool) {
+	var req ProjectInfo
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return nil, false
+	}
+	return &database.Project{Name: req.Name, Start: req.Start, End: req.End}, true
+}
+
+// canonicalAddress replies 400 if the address is neither an ethereum nor a
+// solana address.
+func canonicalAddress(c *gin.Context, address string) (string, bool) {
+	address, err := auth.CanonicalAddress(address)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid address")
+		return "", false
+	}
+	return address, true
+}
@@ -0,0 +1,151 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	klog "github.com/go-kratos/kratos/v2/log"
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+)
+
+const (
+	adminAddress    = "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+	operatorAddress = "0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2"
+	// a wallet linked to the operator's user
+	operatorWallet = "0x4B20993Bc481177ec7E8f571ceCaE8A9e22C02db"
+	userAddress    = "0x78731D3Ca6b7E34aC0F824c42a7cC18A495cabaB"
+)
+
+// newAdminRouter serves the admin module to the wallet in the X-Address
+// header, as if it were logged in.
+func newAdminRouter(t *testing.T) (*gin.Engine, *database.DataStore) {
+	t.Helper()
+	store, err := database.NewDataStore("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	for _, address := range []string{adminAddress, operatorAddress, userAddress} {
+		identity := auth.Identity{Provider: auth.ProviderEthereum, Subject: address, Address: address}
+		if _, err := store.ResolveUser(identity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	operatorID, _ := store.GetUserIDByAddress(operatorAddress)
+	err = store.LinkIdentity(operatorID, auth.Identity{Provider: auth.ProviderEthereum, Subject: operatorWallet, Address: operatorWallet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRole("test", adminAddress, auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRole("test", operatorAddress, auth.RoleOperator); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	if err := RegisterValidators(func() config.MintConfig { return config.MintConfig{} }); err != nil {
+		t.Fatal(err)
+	}
+	h := &handler{logger: klog.NewHelper(klog.DefaultLogger), store: store}
+	h.cfg.Store(config.DefaultConfig())
+	r := gin.New()
+	g := r.Group("/admin", func(c *gin.Context) {
+		address := c.GetHeader("X-Address")
+		userID, _ := store.GetUserIDByAddress(address)
+		role, _ := store.GetRole(address)
+		c.Set("address", address)
+		c.Set("user", userID)
+		c.Set("role", role)
+	}, h.RequireRole(auth.RoleOperator))
+	g.POST("/points", h.adjustPoints)
+	g.POST("/users/ban", h.banUser)
+	g.POST("/users/unban", h.unbanUser)
+	g.PUT("/roles", h.RequireRole(auth.RoleAdmin), h.setRole)
+	return r, store
+}
+
+func call(r *gin.Engine, caller, method, path, body string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-Address", caller)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRequireRole(t *testing.T) {
+	r, _ := newAdminRouter(t)
+	adjust := fmt.Sprintf(`{"address":%q,"points":10,"reason":"test"}`, userAddress)
+	role := fmt.Sprintf(`{"address":%q,"role":"operator"}`, userAddress)
+
+	for _, tc := range []struct {
+		caller, method, path, body string
+		status                     int
+	}{
+		{userAddress, http.MethodPost, "/admin/points", adjust, http.StatusForbidden},
+		{operatorAddress, http.MethodPost, "/admin/points", adjust, http.StatusOK},
+		{operatorAddress, http.MethodPut, "/admin/roles", role, http.StatusForbidden},
+		{adminAddress, http.MethodPut, "/admin/roles", role, http.StatusOK},
+	} {
+		if status := call(r, tc.caller, tc.method, tc.path, tc.body); status != tc.status {
+			t.Errorf("%s %s by %s: %d, expected %d", tc.method, tc.path, tc.caller, status, tc.status)
+		}
+	}
+}
+
+func TestAdjustOwnPoints(t *testing.T) {
+	r, _ := newAdminRouter(t)
+	for _, address := range []string{operatorAddress, operatorWallet, strings.ToLower(operatorWallet)} {
+		body := fmt.Sprintf(`{"address":%q,"points":1000,"reason":"test"}`, address)
+		if status := call(r, operatorAddress, http.MethodPost, "/admin/points", body); status != http.StatusForbidden {
+			t.Errorf("operator crediting %s: %d, expected 403", address, status)
+		}
+	}
+}
+
+func TestBanUnbanRanks(t *testing.T) {
+	r, store := newAdminRouter(t)
+	banOperator := fmt.Sprintf(`{"address":%q,"reason":"test"}`, operatorWallet)
+	unbanOperator := fmt.Sprintf(`{"address":%q}`, operatorWallet)
+
+	// the user has the role of its operator wallet
+	if status := call(r, operatorAddress, http.MethodPost, "/admin/users/ban", banOperator); status != http.StatusForbidden {
+		t.Fatalf("operator banning an operator: %d, expected 403", status)
+	}
+	if status := call(r, adminAddress, http.MethodPost, "/admin/users/ban", banOperator); status != http.StatusOK {
+		t.Fatalf("admin banning an operator: %d", status)
+	}
+
+	// a ban put by an admin on an operator is lifted by an admin only, the
+	// banned operator's own role doesn't count
+	other := "0x617F2E2fD72FD9D5503197092aC168c91465E7f2"
+	if _, err := store.ResolveUser(auth.Identity{Provider: auth.ProviderEthereum, Subject: other, Address: other}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRole("test", other, auth.RoleOperator); err != nil {
+		t.Fatal(err)
+	}
+	if status := call(r, other, http.MethodPost, "/admin/users/unban", unbanOperator); status != http.StatusForbidden {
+		t.Fatalf("operator unbanning an operator: %d, expected 403", status)
+	}
+	if status := call(r, adminAddress, http.MethodPost, "/admin/users/unban", unbanOperator); status != http.StatusOK {
+		t.Fatalf("admin unbanning an operator: %d", status)
+	}
+
+	banUser := fmt.Sprintf(`{"address":%q,"reason":"test"}`, userAddress)
+	unbanUser := fmt.Sprintf(`{"address":%q}`, userAddress)
+	if status := call(r, operatorAddress, http.MethodPost, "/admin/users/ban", banUser); status != http.StatusOK {
+		t.Fatalf("operator banning a user: %d", status)
+	}
+	if status := call(r, operatorAddress, http.MethodPost, "/admin/users/unban", unbanUser); status != http.StatusOK {
+		t.Fatalf("operator unbanning a user: %d", status)
+	}
+}
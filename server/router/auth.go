@@ -2,24 +2,39 @@ package router
 
 import (
 	"net/http"
-	"net/url"
 	"strconv"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/metrics"
+	"github.com/memoio/xspace-server/ratelimit"
+	"golang.org/x/xerrors"
 )
 
+func LoadWellKnownModule(g *gin.RouterGroup, h *handler) {
+	g.GET("/jwks.json", h.JWKSHandler())
+}
+
 func LoadAuthModule(g *gin.RouterGroup, h *handler) {
-	g.GET("/challenge", h.ChallengeHandler())
+	g.GET("/challenge", h.rateLimit(ratelimit.GroupChallenge, queryAddress), h.ChallengeHandler())
+
+	g.POST("/login", h.rateLimit(ratelimit.GroupLogin, loginAddress), h.LoginHandler())
 
-	g.POST("/login", h.LoginHandler())
+	g.GET("/refresh", h.rateLimit(ratelimit.GroupLogin, noAddress), h.RefreshHandler())
 
-	g.GET("/refresh", h.RefreshHandler())
+	g.POST("/logout", h.VerifyIdentityHandler, h.LogoutHandler())
+
+	g.GET("/sessions", h.VerifyIdentityHandler, h.ListSessionsHandler())
+	g.DELETE("/sessions/:id", h.VerifyIdentityHandler, h.RevokeSessionHandler())
 
 	g.GET("/identity", h.VerifyIdentityHandler, func(c *gin.Context) {
 		c.JSON(200, gin.H{
-			"address": c.GetString("address"),
-			"chainid": c.GetInt("chainid"),
+			"address":  c.GetString("address"),
+			"chainid":  c.GetInt("chainid"),
+			"user":     c.GetString("user"),
+			"provider": c.GetString("provider"),
+			"role":     c.GetString("role"),
 		})
 	})
 }
@@ -27,39 +42,43 @@ func LoadAuthModule(g *gin.RouterGroup, h *handler) {
 // @ Summary Challenge
 //
 //	@Description	Get the challenge message by address before you login
+//	@Description	With the ethereum provider it is a Sign-In with Ethereum message for the address, with lens one for the owner of the profile, with solana a Sign-In with Solana message for the address.
 //	@Tags			Login
 //	@Accept			json
 //	@Produce		json
-//	@Param			address	query		string	true	"User's address (connect to xspace)"
-//	@Param			chainid	query		string	true	"The network ID which the user's wallet is connected to"
-//	@Param			Origin	header		string	true	"The frontend's domain"
-//	@Success		200		{string}	string	"The challenge message"
+//	@Param			provider	query		string	false	"ethereum (default), lens or solana"
+//	@Param			address		query		string	false	"User's address (connect to xspace), required by ethereum and solana"
+//	@Param			profile		query		string	false	"The lens profile id, required by lens"
+//	@Param			chainid		query		string	false	"The network ID which the user's wallet is connected to, 985 by default"
+//	@Param			Origin		header		string	true	"The frontend's origin, its host must be an allowed domain"
+//	@Success		200			{string}	string	"The challenge message"
 //	@Router			/v1/challenge [get]
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	APIError	"SIWE_INVALID, the provider, origin, address, profile or chain id is invalid"
+//	@Failure		429	{object}	APIError	"RATE_LIMITED, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
 func (h *handler) ChallengeHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		address := c.Query("address")
-		uri, err := url.Parse(c.GetHeader("Origin"))
-		if err != nil {
-			c.JSON(500, err)
-			return
-		}
-		domain := uri.Host
-
+		var err error
 		var chainID int
 		if c.Query("chainid") != "" {
 			chainID, err = strconv.Atoi(c.Query("chainid"))
 			if err != nil {
-				c.JSON(500, err)
+				abortWithMessage(c, 400, "Invalid chain id")
 				return
 			}
 		} else {
 			chainID = 985
 		}
 
-		challenge, err := h.authController.Challenge(domain, address, uri.String(), chainID)
+		challenge, err := h.authController.Challenge(c.Request.Context(), auth.ChallengeRequest{
+			Provider:  c.Query("provider"),
+			Origin:    c.GetHeader("Origin"),
+			Address:   c.Query("address"),
+			ProfileID: c.Query("profile"),
+			ChainID:   chainID,
+		})
 		if err != nil {
-			c.JSON(500, err)
+			h.abortWithChallengeError(c, err)
 			return
 		}
 		c.String(http.StatusOK, challenge)
@@ -70,26 +89,34 @@ func (h *handler) ChallengeHandler() gin.HandlerFunc {
 //
 //	@Description	Use the signMessage method to sign the challenge message. After signing, call the login interface to complete the login.
 //	@Description	If the login is successful, the Login API will return an Access Token and a Refresh Token. When accessing subsequent APIs, you need to add the Authorization field in the headers with the value "Bearer Your_Access_Token"
+//	@Description	The message must be the unmodified challenge, signed before its expiration time, and can be used only once.
+//	@Description	The identities sharing a wallet, such as a wallet and the lens profiles it owns, log in as the same user.
 //	@Tags			Login
 //	@Accept			json
 //	@Produce		json
+//	@Param			provider	body		string				false	"The provider of the challenge, ethereum by default"
 //	@Param			message		body		string				true	"The challenge message"
-//	@Param			signature	body		string				true	"The result after the user's private key signs the challenge message"
+//	@Param			signature	body		string				true	"The result after the user's private key signs the challenge message, hex encoded, base58 for solana"
 //	@Success		200			{object}	map[string]string	"The access token and refresh token"
 //	@Router			/v1/login [post]
-//	@Failure		500	{object}	error
-//	@Failure		401	{object}	error
+//	@Failure		400	{object}	APIError
+//	@Failure		401	{object}	APIError	"SIWE_INVALID or SIGNATURE_INVALID"
+//	@Failure		403	{object}	APIError	"USER_BANNED"
+//	@Failure		429	{object}	APIError	"RATE_LIMITED, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
+//	@Failure		502	{object}	APIError	"CHAIN_UNAVAILABLE, the signature of a contract wallet couldn't be checked"
 func (h *handler) LoginHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var request auth.EIP4361Request
-		err := c.BindJSON(&request)
+		var request auth.LoginRequest
+		err := c.ShouldBindJSON(&request)
 		if err != nil {
-			c.JSON(500, err)
+			abortWithBindError(c, err)
 			return
 		}
-		accessToken, refreshToken, err := h.authController.Login(request)
+		accessToken, refreshToken, err := h.authController.Login(c.Request.Context(), request, c.Request.UserAgent())
+		countLogin(request.Provider, err)
 		if err != nil {
-			c.JSON(401, err)
+			h.abortWithError(c, err)
 			return
 		}
 
@@ -100,43 +127,204 @@ func (h *handler) LoginHandler() gin.HandlerFunc {
 	}
 }
 
+// countLogin counts the login by provider and result, the code of the
+// error if it failed.
+func countLogin(provider string, err error) {
+	switch provider {
+	case "":
+		provider = auth.ProviderEthereum
+	case auth.ProviderEthereum, auth.ProviderLens, auth.ProviderSolana:
+	default:
+		provider = "unknown"
+	}
+
+	result := "success"
+	if err != nil {
+		result = CodeInternal
+		if apiErr := toAPIError(err); apiErr != nil {
+			result = apiErr.Code
+		}
+	}
+	metrics.Logins.WithLabelValues(provider, result).Inc()
+}
+
 // @ Summary Refresh
 //
 //	@Description	If the access token expires, you can call the refresh API to get a new access token or log in again.
+//	@Description	The refresh token is rotated, use the returned refresh token next time. Using a refresh token twice revokes the whole session.
 //	@Tags			Login
 //	@Accept			json
 //	@Produce		json
 //	@Param			Authorization	header		string				true	"Bearer YOUR_FRESH_TOKEN"
-//	@Success		200				{object}	map[string]string	"The access token"
-//	@Router			/v1/refresh [post]
-//	@Failure		401	{object}	error
+//	@Success		200				{object}	map[string]string	"The access token and refresh token"
+//	@Router			/v1/refresh [get]
+//	@Failure		401	{object}	APIError	"TOKEN_EXPIRED, TOKEN_INVALID, SESSION_REVOKED etc., the user needs to log in again"
+//	@Failure		403	{object}	APIError	"USER_BANNED"
+//	@Failure		429	{object}	APIError	"RATE_LIMITED, retry after the Retry-After header"
 func (h *handler) RefreshHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
-		accessToken, err := auth.VerifyRefreshToken(tokenString)
+		accessToken, refreshToken, err := h.authController.Refresh(tokenString)
 		if err != nil {
-			c.String(http.StatusUnauthorized, "Illegal refresh token")
+			h.abortWithError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, map[string]string{
-			"accessToken": accessToken,
+		c.JSON(http.StatusOK, gin.H{
+			"accessToken":  accessToken,
+			"refreshToken": refreshToken,
 		})
 	}
 }
 
+// @ Summary Logout
+//
+//	@Description	Revoke the current session, its access token and refresh token can't be used any more
+//	@Tags			Login
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Success		200				{string}	string
+//	@Router			/v1/logout [post]
+//	@Failure		401	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) LogoutHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := h.authController.Logout(c.GetString("session"))
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, "success")
+	}
+}
+
+// @ Summary ListSessions
+//
+//	@Description	List the active sessions (logged in devices) of all the user's wallets
+//	@Tags			Login
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Success		200				{object}	ListSessionsRes
+//	@Router			/v1/sessions [get]
+//	@Failure		401	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) ListSessionsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessions, err := h.authController.ListSessions(c.GetString("user"), c.GetString("address"))
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+
+		current := c.GetString("session")
+		infos := make([]SessionInfo, 0, len(sessions))
+		for _, session := range sessions {
+			infos = append(infos, SessionInfo{
+				ID:          session.ID,
+				Address:     session.Address,
+				ChainID:     session.ChainID,
+				UserAgent:   session.UserAgent,
+				CreateTime:  session.CreatedAt,
+				RefreshTime: session.RefreshedAt,
+				ExpireTime:  session.ExpireAt,
+				Current:     session.ID == current,
+			})
+		}
+
+		c.JSON(http.StatusOK, ListSessionsRes{Sessions: infos})
+	}
+}
+
+// @ Summary RevokeSession
+//
+//	@Description	Revoke one of the user's sessions, e.g. log out a lost device
+//	@Tags			Login
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			id				path		string	true	"Session id"
+//	@Success		200				{string}	string
+//	@Router			/v1/sessions/{id} [delete]
+//	@Failure		401	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) RevokeSessionHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := h.authController.RevokeSession(c.GetString("user"), c.GetString("address"), c.Param("id"))
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, "success")
+	}
+}
+
+// @ Summary JWKS
+//
+//	@Description	Get the public keys verifying the tokens issued by xspace, in JSON Web Key Set format
+//	@Tags			Login
+//	@Produce		json
+//	@Success		200	{object}	auth.JWKS
+//	@Router			/.well-known/jwks.json [get]
+func (h *handler) JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, h.authController.JWKS())
+	}
+}
+
 func (h *handler) VerifyIdentityHandler(c *gin.Context) {
 	tokenString := c.GetHeader("Authorization")
 	if tokenString == "" {
 		tokenString = "Bearer " + c.Query("token")
 	}
 
-	address, chainid, err := auth.VerifyAccessToken(tokenString)
+	claims, err := h.authController.VerifyAccessToken(tokenString)
 	if err != nil {
-		c.AbortWithStatusJSON(401, err)
+		h.abortWithError(c, err)
 		return
 	}
 
-	c.Set("address", address)
-	c.Set("chainid", chainid)
+	c.Set("address", claims.Subject)
+	c.Set("chainid", claims.ChainID)
+	c.Set("session", claims.SessionID)
+	c.Set("user", claims.UserID)
+	c.Set("provider", claims.Provider)
+	c.Set("role", claims.Role)
+	if claims.ExpiresAt != nil {
+		c.Set("expire", claims.ExpiresAt.Time)
+	}
+}
+
+// RequireRole must run after VerifyIdentityHandler, it rejects the tokens
+// issued to addresses without the role or a higher one.
+func (h *handler) RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.HasRole(c.GetString("role"), role) {
+			abortWithMessage(c, 403, "Permission denied")
+		}
+	}
+}
+
+// RequireEVMWalletHandler rejects users logged in with a wallet that can't
+// hold xspace NFTs, such as a Solana wallet.
+func (h *handler) RequireEVMWalletHandler(c *gin.Context) {
+	if !common.IsHexAddress(c.GetString("address")) {
+		abortWithMessage(c, 403, "NFTs are minted on an EVM chain, log in with an Ethereum wallet or lens profile")
+	}
+}
+
+// abortWithChallengeError replies 400 if the challenge can't be issued, the
+// TokenError code tells why.
+func (h *handler) abortWithChallengeError(c *gin.Context, err error) {
+	var tokenErr *auth.TokenError
+	if xerrors.As(err, &tokenErr) {
+		abortWithAPIError(c, &APIError{Status: 400, Code: tokenErr.Code, Message: tokenErr.Message})
+		return
+	}
+	h.abortWithError(c, err)
 }
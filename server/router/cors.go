@@ -2,24 +2,163 @@ package router
 
 import (
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/config"
+	"golang.org/x/xerrors"
 )
 
-func Cors() gin.HandlerFunc {
+const (
+	corsMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	// response headers the frontends may read
+	corsExposed = "Content-Length, Content-Type, Content-Disposition, Cache-Control, X-Request-ID, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, X-Encryption, X-Wrapped-Key"
+)
+
+// corsPolicy is who may call the routes under a path prefix.
+type corsPolicy struct {
+	prefix string
+	// any origin, without credentials
+	any     bool
+	origins []originPattern
+}
+
+// originPattern matches an origin, a host starting with *. matches the
+// subdomains and port * matches any port.
+type originPattern struct {
+	scheme string
+	host   string
+	port   string
+}
+
+func parseOrigin(origin string) (originPattern, error) {
+	trimmed, anyPort := strings.CutSuffix(strings.TrimSuffix(origin, "/"), ":*")
+	uri, err := url.Parse(trimmed)
+	if err != nil || uri.Host == "" || (uri.Scheme != "https" && uri.Scheme != "http") || uri.Path != "" {
+		return originPattern{}, xerrors.Errorf("invalid origin %q", origin)
+	}
+
+	pattern := originPattern{scheme: uri.Scheme, host: strings.ToLower(uri.Hostname()), port: uri.Port()}
+	if anyPort {
+		pattern.port = "*"
+	}
+	return pattern, nil
+}
+
+func (p originPattern) match(origin originPattern) bool {
+	if p.scheme != origin.scheme || (p.port != "*" && p.port != origin.port) {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(p.host, "*."); ok {
+		return strings.HasSuffix(origin.host, "."+suffix)
+	}
+	return p.host == origin.host
+}
+
+func (p *corsPolicy) allow(origin string) bool {
+	// the origin of a request has no wildcards
+	o, err := parseOrigin(origin)
+	if err != nil || o.port == "*" || strings.HasPrefix(o.host, "*") {
+		return false
+	}
+	for _, pattern := range p.origins {
+		if pattern.match(o) {
+			return true
+		}
+	}
+	return false
+}
+
+func newCorsPolicy(prefix string, origins []string) (*corsPolicy, error) {
+	policy := &corsPolicy{prefix: prefix}
+	for _, origin := range origins {
+		if origin == "*" {
+			policy.any = true
+			continue
+		}
+		pattern, err := parseOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		policy.origins = append(policy.origins, pattern)
+	}
+	return policy, nil
+}
+
+// Cors answers the preflight requests and tells browsers which origins may
+// call the API. The frontends signing in with SIWE on the allowed domains
+// are allowed with credentials, the routes of cfg.Routes have their own
+// origins.
+func Cors(cfg config.CorsConfig, domains []string) (gin.HandlerFunc, error) {
+	origins := cfg.Origins
+	if len(origins) == 0 {
+		for _, domain := range domains {
+			origins = append(origins, "https://"+domain)
+			if host := strings.Split(domain, ":")[0]; host == "localhost" || host == "127.0.0.1" {
+				origins = append(origins, "http://"+domain)
+			}
+		}
+	}
+	def, err := newCorsPolicy("", origins)
+	if err != nil {
+		return nil, err
+	}
+	// a frontend that can sign in must be able to call the API
+	for _, domain := range domains {
+		if !def.allow("https://"+domain) && !def.allow("http://"+domain) {
+			return nil, xerrors.Errorf("auth allowed domain %s isn't a CORS origin", domain)
+		}
+	}
+
+	policies := []*corsPolicy{def}
+	for _, route := range cfg.Routes {
+		policy, err := newCorsPolicy(route.Prefix, route.Origins)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	headers := strings.Join(cfg.Headers, ", ")
+	maxAge := strconv.FormatInt(cfg.MaxAge, 10)
 	return func(c *gin.Context) {
-		method := c.Request.Method
+		policy := def
+		for _, p := range policies {
+			if strings.HasPrefix(c.Request.URL.Path, p.prefix) && len(p.prefix) > len(policy.prefix) {
+				policy = p
+			}
+		}
+
 		origin := c.Request.Header.Get("Origin")
-		if origin != "" {
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		// caches must not serve the response of one origin to another,
+		// whatever the policy of the path is
+		c.Writer.Header().Add("Vary", "Origin")
+		switch {
+		case origin == "":
+		case policy.any:
 			c.Header("Access-Control-Allow-Origin", "*")
-			c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
-			c.Header("Access-Control-Allow-Headers", "Content-Type,AccessToken,X-CSRF-Token, Authorization, Token")
-			c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type")
+		case policy.allow(origin):
+			c.Header("Access-Control-Allow-Origin", origin)
 			c.Header("Access-Control-Allow-Credentials", "true")
+		default:
+			// without the headers the browser blocks the response
+			origin = ""
 		}
-		if method == "OPTIONS" {
+
+		if origin != "" {
+			if preflight {
+				c.Header("Access-Control-Allow-Methods", corsMethods)
+				c.Header("Access-Control-Allow-Headers", headers)
+				c.Header("Access-Control-Max-Age", maxAge)
+			} else {
+				c.Header("Access-Control-Expose-Headers", corsExposed)
+			}
+		}
+		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(http.StatusNoContent)
 		}
-		c.Next()
-	}
+	}, nil
 }
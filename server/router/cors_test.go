@@ -0,0 +1,157 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/config"
+)
+
+func TestOriginMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, origin string
+		allowed         bool
+	}{
+		{"https://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com", "https://app.example.com/", true},
+		{"https://app.example.com", "https://APP.Example.com", true},
+		{"https://app.example.com", "http://app.example.com", false},
+		{"https://app.example.com", "https://app.example.com:8443", false},
+		{"https://app.example.com", "https://app.example.com.evil.com", false},
+		{"https://app.example.com", "null", false},
+		{"https://*.example.com", "https://app.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evil-example.com", false},
+		{"https://*.example.com", "https://app.evil-example.com", false},
+		{"https://*.example.com", "https://example.com.evil.com", false},
+		{"https://*.example.com", "http://app.example.com", false},
+		{"https://*.example.com", "https://app.example.com:8443", false},
+		// an origin is no pattern
+		{"https://*.example.com", "https://*.example.com", false},
+		{"http://localhost:*", "http://localhost:3000", true},
+		{"http://localhost:*", "http://localhost", true},
+		{"http://localhost:*", "https://localhost:3000", false},
+		{"http://localhost:*", "http://localhost.evil.com:3000", false},
+		{"http://localhost:*", "http://localhost:*", false},
+		{"http://localhost:3000", "http://localhost:3001", false},
+	} {
+		policy, err := newCorsPolicy("", []string{tc.pattern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed := policy.allow(tc.origin); allowed != tc.allowed {
+			t.Errorf("%s allows %s: %t, expected %t", tc.pattern, tc.origin, allowed, tc.allowed)
+		}
+	}
+
+	for _, origin := range []string{"app.example.com", "ftp://app.example.com", "https://app.example.com/path", "https://"} {
+		if _, err := newCorsPolicy("", []string{origin}); err == nil {
+			t.Errorf("invalid origin %q accepted", origin)
+		}
+	}
+}
+
+func TestCors(t *testing.T) {
+	cors, err := Cors(config.CorsConfig{
+		Origins: []string{"https://*.xspace.io", "http://localhost:*"},
+		Headers: []string{"Content-Type", "Authorization"},
+		MaxAge:  600,
+		Routes: []config.CorsRouteConfig{
+			{Prefix: "/v1/project/", Origins: []string{"*"}},
+			{Prefix: "/v1/project/private/", Origins: []string{"https://partner.com"}},
+			{Prefix: "/v1/partner/"},
+		},
+	}, []string{"app.xspace.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(cors)
+	r.Any("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, tc := range []struct {
+		method, path, origin string
+		// the expected Access-Control-Allow-Origin, empty if rejected
+		allowed     string
+		credentials bool
+	}{
+		{http.MethodGet, "/v1/user", "https://app.xspace.io", "https://app.xspace.io", true},
+		{http.MethodGet, "/v1/user", "https://a.b.xspace.io", "https://a.b.xspace.io", true},
+		{http.MethodGet, "/v1/user", "http://localhost:5173", "http://localhost:5173", true},
+		{http.MethodGet, "/v1/user", "https://evil-xspace.io", "", false},
+		{http.MethodGet, "/v1/user", "", "", false},
+		{http.MethodGet, "/v1/project/1", "https://evil.com", "*", false},
+		// the longest prefix wins, the policies aren't merged
+		{http.MethodGet, "/v1/project/private/1", "https://partner.com", "https://partner.com", true},
+		{http.MethodGet, "/v1/project/private/1", "https://app.xspace.io", "", false},
+		{http.MethodGet, "/v1/project/private/1", "https://evil.com", "", false},
+		{http.MethodPost, "/v1/partner/events", "https://app.xspace.io", "", false},
+		{http.MethodOptions, "/v1/user", "https://app.xspace.io", "https://app.xspace.io", true},
+		{http.MethodOptions, "/v1/partner/events", "https://app.xspace.io", "", false},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		preflight := tc.method == http.MethodOptions
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		header := w.Header()
+		name := tc.method + " " + tc.path + " from " + tc.origin
+		if got := header.Get("Access-Control-Allow-Origin"); got != tc.allowed {
+			t.Errorf("%s: allowed origin %q, expected %q", name, got, tc.allowed)
+		}
+		if got := header.Get("Access-Control-Allow-Credentials") == "true"; got != tc.credentials {
+			t.Errorf("%s: credentials %t, expected %t", name, got, tc.credentials)
+		}
+		if header.Get("Vary") != "Origin" {
+			t.Errorf("%s: Vary %q", name, header.Values("Vary"))
+		}
+		if preflight {
+			if w.Code != http.StatusNoContent {
+				t.Errorf("%s: status %d", name, w.Code)
+			}
+			if allowed := header.Get("Access-Control-Allow-Methods") != ""; allowed != (tc.allowed != "") {
+				t.Errorf("%s: allowed methods %q", name, header.Get("Access-Control-Allow-Methods"))
+			}
+		} else if exposed := header.Get("Access-Control-Expose-Headers") != ""; exposed != (tc.allowed != "") {
+			t.Errorf("%s: exposed headers %q", name, header.Get("Access-Control-Expose-Headers"))
+		}
+	}
+}
+
+func TestCorsAllowedDomains(t *testing.T) {
+	// a frontend allowed to sign in must be a CORS origin
+	_, err := Cors(config.CorsConfig{Origins: []string{"https://*.xspace.io"}}, []string{"xspace.io"})
+	if err == nil {
+		t.Fatal("auth allowed domain outside of the CORS origins")
+	}
+
+	cors, err := Cors(config.CorsConfig{}, []string{"xspace.io", "localhost:3000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for origin, allowed := range map[string]bool{
+		"https://xspace.io":     true,
+		"http://xspace.io":      false,
+		"https://app.xspace.io": false,
+		"http://localhost:3000": true,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/v1/user", nil)
+		c.Request.Header.Set("Origin", origin)
+		cors(c)
+		if got := w.Header().Get("Access-Control-Allow-Origin") == origin; got != allowed {
+			t.Errorf("origin %s of the allowed domains: %t, expected %t", origin, got, allowed)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package router
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/access"
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/contract"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/encryption"
+	"github.com/memoio/xspace-server/mint"
+	"github.com/memoio/xspace-server/partner"
+	"github.com/memoio/xspace-server/realtime"
+	"github.com/memoio/xspace-server/storage"
+	"golang.org/x/xerrors"
+)
+
+// Codes of the API errors, the domain packages define more specific ones
+// such as the TokenError codes of auth and the PolicyError codes of mint.
+const (
+	CodeInvalidRequest     = "INVALID_REQUEST"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeConflict           = "CONFLICT"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeChainUnavailable   = "CHAIN_UNAVAILABLE"
+	CodeStorageUnavailable = "STORAGE_UNAVAILABLE"
+	CodeUnavailable        = "UNAVAILABLE"
+	CodeInternal           = "INTERNAL"
+
+	CodeChargeTooFrequent = "CHARGE_TOO_FREQUENT"
+	CodePrimaryWallet     = "PRIMARY_WALLET"
+	CodeWalletLinked      = "WALLET_LINKED"
+	CodeReferCodeInvalid  = "REFER_CODE_INVALID"
+	CodeReferSelf         = "REFER_SELF"
+	CodeReferBound        = "REFER_BOUND"
+	CodePublicKeyUnknown  = "PUBLIC_KEY_UNKNOWN"
+	CodeDisabled          = "FEATURE_DISABLED"
+)
+
+// APIError is the body of every error response.
+type APIError struct {
+	Status     int           `json:"-"`
+	RetryAfter time.Duration `json:"-"`
+
+	// stable reason of the error for clients to act on
+	Code    string `json:"code" example:"NOT_FOUND"`
+	Message string `json:"message" example:"NFT not found"`
+	// more about the error depending on its code, e.g. the limit and the
+	// current usage of MINT_QUOTA_EXCEEDED or the FieldErrors of
+	// INVALID_REQUEST
+	Details interface{} `json:"details,omitempty"`
+	// the X-Request-ID of the request, to report the error with
+	RequestID string `json:"requestId,omitempty" example:"8f14e45fceea167a5a36dedd4bea2543"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// LimitDetails are the details of an error exceeding a limit.
+type LimitDetails struct {
+	Limit   int64 `json:"limit"`
+	Current int64 `json:"current"`
+}
+
+// sentinel errors of the domain packages and how they are replied
+var knownErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{database.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{storage.ErrObjectNotFound, http.StatusNotFound, CodeNotFound},
+	{auth.ErrProfileNotFound, http.StatusNotFound, CodeNotFound},
+	{auth.ErrSessionNotFound, http.StatusNotFound, CodeNotFound},
+	{database.ErrChargeTooFrequent, http.StatusTooManyRequests, CodeChargeTooFrequent},
+	{database.ErrInsufficientPoints, http.StatusConflict, mint.CodeInsufficientPoints},
+	{database.ErrPrimaryWallet, http.StatusBadRequest, CodePrimaryWallet},
+	{auth.ErrWalletLinked, http.StatusConflict, CodeWalletLinked},
+	{database.ErrReferCodeInvalid, http.StatusBadRequest, CodeReferCodeInvalid},
+	{database.ErrReferSelf, http.StatusBadRequest, CodeReferSelf},
+	{database.ErrReferBound, http.StatusConflict, CodeReferBound},
+	{access.ErrInvalidPolicy, http.StatusBadRequest, CodeInvalidRequest},
+	{encryption.ErrPublicKeyUnknown, http.StatusConflict, CodePublicKeyUnknown},
+	{encryption.ErrDisabled, http.StatusServiceUnavailable, CodeDisabled},
+	{partner.ErrDisabled, http.StatusServiceUnavailable, CodeDisabled},
+	{realtime.ErrTooManyStreams, http.StatusTooManyRequests, CodeRateLimited},
+	{contract.ErrChain, http.StatusBadGateway, CodeChainUnavailable},
+}
+
+// toAPIError returns the reply of an error, nil if it isn't a known one.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if xerrors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var tokenErr *auth.TokenError
+	if xerrors.As(err, &tokenErr) {
+		status := http.StatusUnauthorized
+		if tokenErr == auth.ErrUserBanned {
+			status = http.StatusForbidden
+		}
+		return &APIError{Status: status, Code: tokenErr.Code, Message: tokenErr.Message}
+	}
+
+	var policyErr *mint.PolicyError
+	if xerrors.As(err, &policyErr) {
+		return &APIError{
+			Status:     policyErr.Status,
+			RetryAfter: policyErr.RetryAfter,
+			Code:       policyErr.Code,
+			Message:    policyErr.Message,
+			Details:    LimitDetails{Limit: policyErr.Limit, Current: policyErr.Current},
+		}
+	}
+
+	var partnerErr *partner.Error
+	if xerrors.As(err, &partnerErr) {
+		return &APIError{
+			Status:     partnerErr.Status,
+			RetryAfter: partnerErr.RetryAfter,
+			Code:       partnerErr.Code,
+			Message:    partnerErr.Message,
+		}
+	}
+
+	for _, known := range knownErrors {
+		if xerrors.Is(err, known.err) {
+			return &APIError{Status: known.status, Code: known.code, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// abortWithError replies the error, an unknown error is logged and replied
+// as an internal error without its message.
+func (h *handler) abortWithError(c *gin.Context, err error) {
+	apiErr := toAPIError(err)
+	if apiErr == nil {
+		h.log(c).Errorf("%s %s: %s", c.Request.Method, c.FullPath(), err)
+		apiErr = &APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal server error"}
+	}
+	abortWithAPIError(c, apiErr)
+}
+
+// abortWithStorageError replies a failure of the storage backend.
+func (h *handler) abortWithStorageError(c *gin.Context, err error) {
+	if xerrors.Is(err, storage.ErrObjectNotFound) {
+		h.abortWithError(c, err)
+		return
+	}
+
+	h.log(c).Errorf("%s %s: %s", c.Request.Method, c.FullPath(), err)
+	abortWithAPIError(c, &APIError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    CodeStorageUnavailable,
+		Message: "storage is unavailable, retry later",
+	})
+}
+
+// NoRoute replies the requests of unknown paths.
+func NoRoute(c *gin.Context) {
+	abortWithMessage(c, http.StatusNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path)
+}
+
+// abortWithMessage replies the message with the generic code of the status.
+func abortWithMessage(c *gin.Context, status int, message string) {
+	abortWithAPIError(c, &APIError{Status: status, Code: statusCode(status), Message: message})
+}
+
+func abortWithAPIError(c *gin.Context, apiErr *APIError) {
+	reply := *apiErr
+	reply.RequestID = c.GetString("requestID")
+	if reply.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(reply.RetryAfter.Seconds())), 10))
+	}
+	c.AbortWithStatusJSON(reply.Status, &reply)
+}
+
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeChainUnavailable
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	return CodeInternal
+}
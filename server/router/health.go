@@ -0,0 +1,75 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/metrics"
+)
+
+func LoadHealthModule(r *gin.RouterGroup, h *handler) {
+	r.GET("/healthz", h.healthz)
+	r.GET("/readyz", h.readyz)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
+
+// Metrics observes the latency and status of the requests by route.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		metrics.RequestDuration.WithLabelValues(c.Request.Method, routeOf(c), strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// @ Summary Healthz
+//
+//	@Description	Liveness of the server, it answers as long as the process serves requests
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	HealthRes
+//	@Router			/healthz [get]
+func (h *handler) healthz(c *gin.Context) {
+	c.JSON(200, HealthRes{Status: "ok"})
+}
+
+// @ Summary Readyz
+//
+//	@Description	Readiness of the server, whether it reaches the database and the chain
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	HealthRes
+//	@Failure		503	{object}	HealthRes	"A check failed"
+//	@Router			/readyz [get]
+func (h *handler) readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	res := HealthRes{Status: "ok", Checks: make(map[string]string)}
+	checks := map[string]func(context.Context) error{
+		"store": h.store.Ping,
+		"chain": func(ctx context.Context) error {
+			_, err := h.chain.BlockNumber(ctx)
+			return err
+		},
+	}
+	for name, check := range checks {
+		res.Checks[name] = "ok"
+		if err := check(ctx); err != nil {
+			h.log(c).Errorf("readiness check %s: %s", name, err)
+			res.Status = "unavailable"
+			res.Checks[name] = "unavailable"
+		}
+	}
+
+	status := http.StatusOK
+	if res.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, res)
+}
@@ -0,0 +1,139 @@
+package router
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/xerrors"
+)
+
+// routes logged at debug level, the probes and scrapes would flood the logs
+var quietRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// the level of the logs written, SetLogLevel changes it while running
+var logLevel atomic.Int32
+
+// NewLogger returns the logger of the server, writing key=value lines to
+// stdout from cfg.Level up.
+func NewLogger(cfg config.LogConfig) (*klog.Helper, error) {
+	if err := SetLogLevel(cfg.Level); err != nil {
+		return nil, err
+	}
+
+	filter := klog.FilterFunc(func(level klog.Level, _ ...interface{}) bool {
+		return level < klog.Level(logLevel.Load())
+	})
+	logger := klog.With(klog.NewFilter(klog.NewStdLogger(os.Stdout), klog.FilterLevel(klog.LevelDebug), filter),
+		"ts", klog.DefaultTimestamp,
+		"caller", klog.DefaultCaller,
+	)
+	return klog.NewHelper(logger), nil
+}
+
+// SetLogLevel sets the level of the logs written, info if level is empty.
+func SetLogLevel(level string) error {
+	parsed := klog.ParseLevel(level)
+	if level != "" && !strings.EqualFold(parsed.String(), level) {
+		return xerrors.Errorf("unsupported log level %s", level)
+	}
+	logLevel.Store(int32(parsed))
+	return nil
+}
+
+// log is the logger of the request c.
+func (h *handler) log(c *gin.Context) *klog.Helper {
+	return requestLogger(h.logger, c)
+}
+
+// requestLogger adds the request id and the trace id to the logs of the
+// request.
+func requestLogger(logger *klog.Helper, c *gin.Context) *klog.Helper {
+	kv := []interface{}{"request_id", c.GetString("requestID")}
+	if span := trace.SpanContextFromContext(c.Request.Context()); span.HasTraceID() {
+		kv = append(kv, "trace_id", span.TraceID().String())
+	}
+	return klog.NewHelper(klog.With(logger.Logger(), kv...))
+}
+
+func routeOf(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
+
+// Logger logs every request once it is served, with its route, status,
+// latency and the authenticated address.
+func Logger(logger *klog.Helper) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := klog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = klog.LevelError
+		case quietRoutes[c.FullPath()]:
+			level = klog.LevelDebug
+		}
+		requestLogger(logger, c).Log(level,
+			"msg", "request",
+			"method", c.Request.Method,
+			"route", routeOf(c),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency", time.Since(start).String(),
+			"size", c.Writer.Size(),
+			"ip", c.ClientIP(),
+			"address", c.GetString("address"),
+		)
+	}
+}
+
+// Tracing starts the span of the request, a child of the caller's span if
+// it sent a W3C traceparent header. The chain calls made with the context of
+// the request are children of the span.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := routeOf(c)
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("request.id", c.GetString("requestID")),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if address := c.GetString("address"); address != "" {
+			span.SetAttributes(attribute.String("user.address", address))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
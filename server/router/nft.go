@@ -2,17 +2,35 @@ package router
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/access"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/encryption"
+	"github.com/memoio/xspace-server/ratelimit"
+	"golang.org/x/xerrors"
 )
 
 func LoadNFTModule(r *gin.RouterGroup, h *handler) {
-	r.POST("/tweet/mint", h.VerifyIdentityHandler, h.mintTweet)
-	r.POST("/data/mint", h.VerifyIdentityHandler, h.mintData)
+	r.POST("/tweet/mint", h.VerifyIdentityHandler, h.RequireEVMWalletHandler, h.rateLimit(ratelimit.GroupMint, authenticatedAddress), h.mintTweet)
+	r.POST("/data/mint", h.VerifyIdentityHandler, h.RequireEVMWalletHandler, h.rateLimit(ratelimit.GroupMint, authenticatedAddress), h.mintData)
+	r.POST("/burn", h.VerifyIdentityHandler, h.RequireEVMWalletHandler, h.burnNFT)
+	r.POST("/transfer", h.VerifyIdentityHandler, h.RequireEVMWalletHandler, h.transferNFT)
 	r.GET("/list", h.VerifyIdentityHandler, h.listNFT)
 	r.GET("/tweet/info", h.VerifyIdentityHandler, h.twitterNFTInfo)
 	r.GET("/data/info", h.VerifyIdentityHandler, h.dataNFTInfo)
+	r.GET("/data/policy", h.VerifyIdentityHandler, h.getAccessPolicy)
+	r.POST("/data/policy", h.VerifyIdentityHandler, h.RequireEVMWalletHandler, h.setAccessPolicy)
 }
 
 // @ Summary MintTweet
@@ -24,48 +42,256 @@ func LoadNFTModule(r *gin.RouterGroup, h *handler) {
 //	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Param			name			body		string	true	"User's twtter/x name"
 //	@Param			postTime		body		string	true	"The time when the user posted the tweet"
-//	@Param			tweet			body		string	true	"The text of the tweet(including emoji)"
-//	@Param			image			body		string	true	"The image url of the tweet"
+//	@Param			tweet			body		string	true	"The text of the tweet(including emoji), at most 280 characters"
+//	@Param			image			body		string	true	"The https urls of the tweet's images on pbs.twimg.com, at most 4"
 //	@Success		200				{object}	MintRes
-//	@Router			/v1/nft/mint [post]
-//	@Failure		502	{object}	error
-//	@Failure		503	{object}	error
+//	@Router			/v1/nft/tweet/mint [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		409	{object}	APIError	"INSUFFICIENT_POINTS"
+//	@Failure		429	{object}	APIError	"MINT_QUOTA_EXCEEDED if the daily mint quota is used up or RATE_LIMITED, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
 func (h *handler) mintTweet(c *gin.Context) {
-	c.JSON(200, MintRes{TokenID: 100})
+	var req MintTweetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	images, err := json.Marshal(req.Images)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid images")
+		return
+	}
+
+	job := &database.MintJob{
+		Address:  c.GetString("address"),
+		Type:     database.TweetNFT,
+		Name:     req.Name,
+		PostTime: req.PostTime,
+		Tweet:    req.Tweet,
+		Images:   string(images),
+	}
+	if err := h.mintPolicy.CreateJob(job); err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, MintRes{JobID: job.ID, Cost: job.Cost})
 }
 
 // @ Summary MintData
 //
 //	@Description	Mint user's data into NFTs
+//	@Description	If encrypt is true, the data is encrypted with a new key which is only shared with the NFT's owner, see DataNFTInfo
 //	@Tags			NFT
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Param			file			formData	file	true	"User's data"
+//	@Param			encrypt			formData	bool	false	"Encrypt the data"
 //	@Success		200				{object}	MintRes
-//	@Router			/v1/nft/mint [post]
-//	@Failure		502	{object}	error
-//	@Failure		503	{object}	error
+//	@Router			/v1/nft/data/mint [post]
+//	@Failure		400	{object}	APIError	"FILE_TOO_LARGE or no file"
+//	@Failure		409	{object}	APIError	"INSUFFICIENT_POINTS, STORAGE_CAP_EXCEEDED or PUBLIC_KEY_UNKNOWN (log in again to encrypt)"
+//	@Failure		429	{object}	APIError	"MINT_QUOTA_EXCEEDED if the daily mint quota is used up or RATE_LIMITED, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
+//	@Failure		503	{object}	APIError	"STORAGE_UNAVAILABLE or FEATURE_DISABLED if encryption isn't enabled"
 func (h *handler) mintData(c *gin.Context) {
-	c.JSON(200, MintRes{TokenID: 100})
+	file, err := c.FormFile("file")
+	if err != nil {
+		abortWithMessage(c, 400, "A file is required")
+		return
+	}
+
+	// reject oversized files before storing them
+	if err := h.mintPolicy.CheckFileSize(file.Size); err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	address := c.GetString("address")
+	key, err := newObjectKey(address)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+	defer src.Close()
+
+	encrypted := c.PostForm("encrypt") == "true"
+	var content io.Reader = src
+	size := file.Size
+	if encrypted {
+		plaintext, err := io.ReadAll(src)
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+
+		ciphertext, err := h.keyManager.Encrypt(key, address, plaintext)
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+		content = bytes.NewReader(ciphertext)
+		size = int64(len(ciphertext))
+	}
+
+	if err := h.storage.Put(c.Request.Context(), key, content, size); err != nil {
+		h.abortWithStorageError(c, err)
+		return
+	}
+
+	job := &database.MintJob{
+		Address:   address,
+		Type:      database.DataNFT,
+		ObjectKey: key,
+		FileName:  file.Filename,
+		Size:      size,
+		Encrypted: encrypted,
+	}
+	if err := h.mintPolicy.CreateJob(job); err != nil {
+		if err := h.storage.Delete(c.Request.Context(), key); err != nil {
+			h.log(c).Error(err)
+		}
+		if encrypted {
+			if err := h.store.DeleteEncryptedKey(key); err != nil {
+				h.log(c).Error(err)
+			}
+		}
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, MintRes{JobID: job.ID, Cost: job.Cost})
+}
+
+// @ Summary BurnNFT
+//
+//	@Description	Burn the user's NFT, the content of a dataNFT is deleted and no longer counts in the user's storage space
+//	@Tags			NFT
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			tokenID			body		string	true	"NFT's id"
+//	@Success		200				{string}	string
+//	@Router			/v1/nft/burn [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) burnNFT(c *gin.Context) {
+	var req BurnNFTReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	address := c.GetString("address")
+	if err := h.nftController.Burn(c.Request.Context(), address, req.TokenID); err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	job, err := h.store.BurnNFT(req.TokenID, address)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	if job.Type == database.DataNFT {
+		if err := h.storage.Delete(c.Request.Context(), job.ObjectKey); err != nil {
+			h.log(c).Error(err)
+		}
+	}
+
+	c.JSON(200, "success")
+}
+
+// @ Summary TransferNFT
+//
+//	@Description	Transfer the user's NFT to another address
+//	@Tags			NFT
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			tokenID			body		string	true	"NFT's id"
+//	@Param			to				body		string	true	"The receiver's address"
+//	@Success		200				{string}	string
+//	@Router			/v1/nft/transfer [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) transferNFT(c *gin.Context) {
+	var req TransferNFTReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	address := c.GetString("address")
+	nft, _, err := h.store.GetNFT(req.TokenID)
+	if xerrors.Is(err, database.ErrNotFound) || (err == nil && nft.Address != address) {
+		abortWithMessage(c, 404, "NFT not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	err = h.nftController.Transfer(c.Request.Context(), address, common.HexToAddress(req.To).Hex(), req.TokenID)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, "success")
 }
 
 // @ Summary ListNFT
 //
-//	@Description	List all NFT information belonging to the user
+//	@Description	List all NFT information belonging to the user's linked wallets
 //	@Tags			NFT
 //	@Accept			json
 //	@Produce		json
 //	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Param			page			query		string	true	"Pages"
-//	@Param			size			query		string	true	"The amount of data displayed on each page"
+//	@Param			size			query		string	true	"The amount of data displayed on each page, at most 100"
 //	@Param			type			query		string	false	"NFT type (1 for tweetNFT, 2 for dataNFT, tweetNFT and dataNFT will be all listed by default)"
 //	@Param			order			query		string	false	"Order rules (date_asc for sorting by creation time from smallest to largest, date_dsc for sorting by creation time from largest to smallest)"
 //	@Success		200				{object}	ListNFTRes
 //	@Router			/v1/nft/list [get]
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	APIError
+//	@Failure		500	{object}	APIError
 func (h *handler) listNFT(c *gin.Context) {
-	c.JSON(200, ListNFTRes{[]NFTInfo{NFTInfo{TokenID: 100, Type: 1, CreateTime: time.Now()}}})
+	var query ListNFTQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	wallets, err := h.userWallets(c)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	nfts, err := h.store.ListNFTs(wallets, query.Type, query.Page, query.Size, query.Order == "date_asc")
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	infos := make([]NFTInfo, 0, len(nfts))
+	for _, nft := range nfts {
+		infos = append(infos, NFTInfo{TokenID: nft.TokenID, Address: nft.Address, Type: nft.Type, CreateTime: nft.CreateTime})
+	}
+	c.JSON(200, ListNFTRes{NftInfos: infos})
 }
 
 // @ Summary TwitterNFTInfo
@@ -78,27 +304,244 @@ func (h *handler) listNFT(c *gin.Context) {
 //	@Param			tokenID			query		string	true	"TweetNFT's id"
 //	@Success		200				{object}	TweetNFTInfoRes
 //	@Router			/v1/nft/tweet/info [get]
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
 func (h *handler) twitterNFTInfo(c *gin.Context) {
-	c.JSON(200, TweetNFTInfoRes{Name: "test", PostTime: time.Now().Unix(), Tweet: "hello, twitter.😀", Images: []string{}})
+	tokenID, err := strconv.ParseInt(c.Query("tokenID"), 10, 64)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid tokenID")
+		return
+	}
+
+	nft, job, err := h.store.GetNFT(tokenID)
+	if xerrors.Is(err, database.ErrNotFound) || (err == nil && nft.Type != database.TweetNFT) {
+		abortWithMessage(c, 404, "TweetNFT not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	images := []string{}
+	if job.Images != "" {
+		if err := json.Unmarshal([]byte(job.Images), &images); err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+	}
+
+	c.JSON(200, TweetNFTInfoRes{Name: job.Name, PostTime: job.PostTime, Tweet: job.Tweet, Images: images})
 }
 
 // @ Summary DataNFTInfo
 //
-//	@Description	Get DataNFT content
+//	@Description	Get DataNFT content, the owner can always read it while other users need to pass the DataNFT's access policy
+//	@Description	The content of an encrypted DataNFT is the ciphertext: a 12-byte nonce followed by the AES-256-GCM sealed data.
+//	@Description	Its key is returned in the X-Wrapped-Key header (hex), encrypted to the caller's public key with ECIES on secp256k1.
 //	@Tags			NFT
 //	@Accept			json
 //	@Produce		octet-stream
 //	@Param			Authorization	header	string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Param			tokenID			query	string	true	"DataNFT's id"
 //	@Success		200				{file}	binary	"DataNFT binary content"
+//	@Header			200				{string}	X-Encryption	"aes-256-gcm if the content is encrypted"
+//	@Header			200				{string}	X-Wrapped-Key	"The content key wrapped to the owner's public key"
 //	@Router			/v1/nft/data/info [get]
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		409	{object}	APIError	"PUBLIC_KEY_UNKNOWN, log in again to receive the key"
+//	@Failure		500	{object}	APIError
+//	@Failure		502	{object}	APIError	"CHAIN_UNAVAILABLE, the holders policy couldn't be checked"
+//	@Failure		503	{object}	APIError	"STORAGE_UNAVAILABLE"
 func (h *handler) dataNFTInfo(c *gin.Context) {
-	var w bytes.Buffer
-	w.WriteString("hello,world\n")
+	tokenID, err := strconv.ParseInt(c.Query("tokenID"), 10, 64)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid tokenID")
+		return
+	}
+
+	address := c.GetString("address")
+	nft, job, err := h.store.GetNFT(tokenID)
+	if xerrors.Is(err, database.ErrNotFound) || (err == nil && nft.Type != database.DataNFT) {
+		abortWithMessage(c, 404, "DataNFT not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	wallets, err := h.userWallets(c)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+	allowed, err := h.accessPolicy.CanRead(c.Request.Context(), nft, wallets)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+	if !allowed {
+		abortWithMessage(c, 403, "Permission denied")
+		return
+	}
+
 	extraHeaders := map[string]string{
-		"Content-Disposition": "attachment; filename=\"test\"",
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": job.FileName}),
+	}
+	if job.Encrypted {
+		var wrappedKey []byte
+		if nft.Address == address {
+			wrappedKey, err = h.keyManager.WrappedKey(job.ObjectKey, address)
+		} else {
+			wrappedKey, err = h.keyManager.WrapFor(job.ObjectKey, address)
+		}
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+		extraHeaders["X-Encryption"] = encryption.Algorithm
+		extraHeaders["X-Wrapped-Key"] = hex.EncodeToString(wrappedKey)
+	}
+
+	content, size, err := h.storage.Get(c.Request.Context(), job.ObjectKey)
+	if err != nil {
+		h.abortWithStorageError(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(200, size, "application/octet-stream", content, extraHeaders)
+}
+
+func newObjectKey(address string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(address) + "/" + hex.EncodeToString(b), nil
+}
+
+// @ Summary GetAccessPolicy
+//
+//	@Description	Get the access policy of the user's DataNFT
+//	@Tags			NFT
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			tokenID			query		string	true	"DataNFT's id"
+//	@Success		200				{object}	AccessPolicyInfo
+//	@Router			/v1/nft/data/policy [get]
+//	@Failure		400	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) getAccessPolicy(c *gin.Context) {
+	tokenID, err := strconv.ParseInt(c.Query("tokenID"), 10, 64)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid tokenID")
+		return
+	}
+
+	if !h.ownsDataNFT(c, tokenID) {
+		return
+	}
+
+	policy, err := h.store.GetAccessPolicy(tokenID)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, toAccessPolicyInfo(policy))
+}
+
+// @ Summary SetAccessPolicy
+//
+//	@Description	Set who besides the owner can read the user's DataNFT.
+//	@Description	Mode is one of owner (only the owner), public (every logged in user), holders (users holding at least MinBalance of the ERC-20/ERC-721 Contract) and allowlist (the users in Allowlist).
+//	@Description	After ExpireAt (unix seconds, 0 for never) the policy falls back to owner only.
+//	@Tags			NFT
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string				true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			policy			body		AccessPolicyInfo	true	"The access policy"
+//	@Success		200				{object}	AccessPolicyInfo
+//	@Router			/v1/nft/data/policy [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) setAccessPolicy(c *gin.Context) {
+	var req AccessPolicyInfo
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	if !h.ownsDataNFT(c, req.TokenID) {
+		return
+	}
+
+	policy := database.AccessPolicy{
+		TokenID:    req.TokenID,
+		Mode:       req.Mode,
+		Contract:   req.Contract,
+		MinBalance: req.MinBalance,
+		Allowlist:  strings.Join(req.Allowlist, ","),
+	}
+	if req.ExpireAt > 0 {
+		expireAt := time.Unix(req.ExpireAt, 0)
+		policy.ExpireAt = &expireAt
+	}
+	if err := access.Validate(&policy); err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	if err := h.store.SaveAccessPolicy(&policy); err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, toAccessPolicyInfo(policy))
+}
+
+// ownsDataNFT replies 404 and returns false unless one of the caller's
+// wallets owns the dataNFT.
+func (h *handler) ownsDataNFT(c *gin.Context, tokenID int64) bool {
+	wallets, err := h.userWallets(c)
+	if err != nil {
+		h.abortWithError(c, err)
+		return false
+	}
+
+	nft, _, err := h.store.GetNFT(tokenID)
+	if xerrors.Is(err, database.ErrNotFound) || (err == nil && (!slices.Contains(wallets, nft.Address) || nft.Type != database.DataNFT)) {
+		abortWithMessage(c, 404, "DataNFT not found")
+		return false
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return false
+	}
+	return true
+}
+
+func toAccessPolicyInfo(policy database.AccessPolicy) AccessPolicyInfo {
+	info := AccessPolicyInfo{
+		TokenID:    policy.TokenID,
+		Mode:       policy.Mode,
+		Contract:   policy.Contract,
+		MinBalance: policy.MinBalance,
+		Allowlist:  []string{},
+	}
+	if policy.Allowlist != "" {
+		info.Allowlist = strings.Split(policy.Allowlist, ",")
+	}
+	if policy.ExpireAt != nil {
+		info.ExpireAt = policy.ExpireAt.Unix()
 	}
-	c.DataFromReader(200, int64(w.Len()), "txt", &w, extraHeaders)
+	return info
 }
@@ -0,0 +1,84 @@
+package router
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
+)
+
+// max size of a partner request body
+const maxPartnerBody = 1 << 20
+
+func LoadPartnerModule(r *gin.RouterGroup, h *handler) {
+	r.POST("/events", h.VerifyPartnerHandler, h.submitEvents)
+}
+
+// @ Summary SubmitEvents
+//
+//	@Description	Report activities of users to the leaderboard of the partner's project, with a partner API key.
+//	@Description	The request is signed: X-Signature is the hex HMAC-SHA256, keyed with the API key's secret, of X-Timestamp, the method, the path and the body joined by newlines.
+//	@Description	Every event has an id unique within the project, an event reported again is a duplicate and changes nothing, so failed requests can be retried.
+//	@Tags			Partner
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Api-Key	header		string			true	"The API key id"
+//	@Param			X-Timestamp	header		string			true	"Unix time of the request"
+//	@Param			X-Signature	header		string			true	"The request signature"
+//	@Param			events		body		SubmitEventsReq	true	"The events"
+//	@Success		200			{object}	SubmitEventsRes
+//	@Router			/v1/partner/events [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		401	{object}	APIError	"API_KEY_INVALID, SIGNATURE_INVALID or TIMESTAMP_SKEWED"
+//	@Failure		404	{object}	APIError			"The project of the key is deleted"
+//	@Failure		429	{object}	APIError	"RATE_LIMITED, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
+//	@Failure		503	{object}	APIError	"FEATURE_DISABLED, no partner secret is configured"
+func (h *handler) submitEvents(c *gin.Context) {
+	var req SubmitEventsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+	if max := h.cfg.Load().Partner.MaxEvents; len(req.Events) == 0 || len(req.Events) > max {
+		abortWithMessage(c, 400, "A request has 1 to "+strconv.Itoa(max)+" events")
+		return
+	}
+
+	results, err := h.leaderboard.Submit(c.GetUint("project"), c.GetString("partnerKey"), req.Events)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Project not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, SubmitEventsRes{Results: results})
+}
+
+// VerifyPartnerHandler authenticates requests signed with a partner API key
+// and sets the key's id and project.
+func (h *handler) VerifyPartnerHandler(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPartnerBody))
+	if err != nil {
+		abortWithMessage(c, 400, "The body is too large")
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	key, err := h.partners.Verify(c.GetHeader("X-Api-Key"), c.GetHeader("X-Timestamp"), c.GetHeader("X-Signature"),
+		c.Request.Method, c.Request.URL.Path, body)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.Set("partnerKey", key.ID)
+	c.Set("project", key.ProjectID)
+}
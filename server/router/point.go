@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/database"
+	"golang.org/x/xerrors"
 )
 
 func LoadPointModules(r *gin.RouterGroup, h *handler) {
@@ -18,16 +20,45 @@ func LoadPointModules(r *gin.RouterGroup, h *handler) {
 
 // @ Summary UserInfo
 //
-//	@Description	Get the user basic info by address
+//	@Description	Get the user basic info, the points and dataNFT storage are the totals of the linked wallets while charging is the state of the wallet logged in with
 //	@Tags			User
 //	@Accept			json
 //	@Produce		json
 //	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Success		200				{object}	PointInfoRes
 //	@Router			/v1/user/info [get]
-//	@Failure		500	{object}	error
+//	@Failure		500	{object}	APIError
 func (h *handler) pointInfo(c *gin.Context) {
-	c.JSON(200, PointInfoRes{Points: 3000, GodataCount: 5, GodataSpace: 97, ChargingCount: 2, Charging: true})
+	user, err := h.store.GetUserPoint(c.GetString("address"))
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	wallets, err := h.userWallets(c)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	res := h.toPointInfoRes(user)
+	res.Points = 0
+	for _, address := range wallets {
+		point, err := h.store.GetUserPoint(address)
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+		usage, err := h.store.GetUserStorage(address)
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+		res.Points += point.Points
+		res.GodataCount += usage.Count
+		res.GodataSpace += usage.Space
+	}
+	c.JSON(200, res)
 }
 
 // @ Summary Charge
@@ -39,26 +70,66 @@ func (h *handler) pointInfo(c *gin.Context) {
 //	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Success		200				{object}	PointInfoRes
 //	@Router			/v1/point/charge [post]
-//	@Failure		500	{object}	error
+//	@Failure		429	{object}	APIError	"CHARGE_TOO_FREQUENT, charged less than 6 hours ago, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
 func (h *handler) charge(c *gin.Context) {
-	c.JSON(200, PointInfoRes{Points: 3000, GodataCount: 5, ChargingCount: 2})
+	cfg := h.cfg.Load().Point
+	interval := time.Duration(cfg.ChargeInterval) * time.Second
+	user, err := h.store.Charge(c.GetString("address"), cfg.ChargeReward, interval)
+	if xerrors.Is(err, database.ErrChargeTooFrequent) {
+		apiErr := &APIError{Status: 429, Code: CodeChargeTooFrequent, Message: "Charged less than " + interval.String() + " ago"}
+		if last, err := h.store.GetUserPoint(c.GetString("address")); err == nil {
+			apiErr.RetryAfter = time.Until(last.LastCharge.Add(interval))
+		}
+		abortWithAPIError(c, apiErr)
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, h.toPointInfoRes(user))
 }
 
 // @ Summary PointHistory
 //
-//	@Description	Get the history of the point info by address
+//	@Description	Get the history of the point info of the user's linked wallets
 //	@Tags			Point
 //	@Accept			json
 //	@Produce		json
 //	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Param			page			query		string	true	"Pages"
-//	@Param			size			query		string	true	"The amount of data displayed on each page"
+//	@Param			size			query		string	true	"The amount of data displayed on each page, at most 100"
 //	@Param			order			query		string	false	"Order rules (date_asc for sorting by creation time from smallest to largest, date_dsc for sorting by creation time from largest to smallest)"
 //	@Success		200				{object}	PointHistoryRes
 //	@Router			/v1/point/history [get]
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	APIError
+//	@Failure		500	{object}	APIError
 func (h *handler) pointHistory(c *gin.Context) {
-	c.JSON(200, PointHistoryRes{[]PointInfo{PointInfo{Point: 50, Time: time.Now(), ActionName: "daily sign-in"}}})
+	var query PointHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	wallets, err := h.userWallets(c)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	records, err := h.store.ListPointRecords(wallets, query.Page, query.Size, query.Order == "date_asc")
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	history := make([]PointInfo, 0, len(records))
+	for _, record := range records {
+		history = append(history, PointInfo{Address: record.Address, Point: record.Point, Time: record.CreatedAt, ActionName: record.ActionName})
+	}
+	c.JSON(200, PointHistoryRes{History: history})
 }
 
 // @ Summary ListProjects
@@ -69,23 +140,64 @@ func (h *handler) pointHistory(c *gin.Context) {
 //	@Produce		json
 //	@Success		200	{object}	ListProjectsRes
 //	@Router			/v1/project/list [get]
-//	@Failure		500	{object}	error
+//	@Failure		500	{object}	APIError
 func (h *handler) listProjects(c *gin.Context) {
-	c.JSON(200, ListProjectsRes{[]ProjectInfo{ProjectInfo{Name: "Data-Did", ProjectID: 1, Start: time.Now(), End: time.Now().Add(96 * time.Hour)}}})
+	projects, err := h.store.ListProjects()
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	infos := make([]ProjectInfo, 0, len(projects))
+	for _, project := range projects {
+		infos = append(infos, ProjectInfo{ProjectID: int(project.ID), Name: project.Name, Start: project.Start, End: project.End})
+	}
+	c.JSON(200, ListProjectsRes{Projects: infos})
 }
 
 // @ Summary Rank
 //
-//	@Description	Get the ranking of cooperative projects
+//	@Description	Get the ranking of cooperative projects, by the scores the projects report
 //	@Tags			Rank
 //	@Accept			json
 //	@Produce		json
 //	@Param			id		query		string	true	"cooperative project id"
 //	@Param			page	query		string	true	"Pages"
-//	@Param			size	query		string	true	"The amount of data displayed on each page"
+//	@Param			size	query		string	true	"The amount of data displayed on each page, at most 100"
 //	@Success		200		{object}	RankRes
 //	@Router			/v1/project/rank [get]
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	APIError
+//	@Failure		500	{object}	APIError
 func (h *handler) rank(c *gin.Context) {
-	c.JSON(200, RankRes{[]RankInfo{RankInfo{Rank: 1, Address: "0xcFA4816BE86B7b56A5373A36bE5B9c53c0f157f8", Scores: 100000, Points: 10000}}})
+	var query RankQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	entries, err := h.leaderboard.Rank(query.ID, query.Page, query.Size)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	infos := make([]RankInfo, 0, len(entries))
+	for i, entry := range entries {
+		infos = append(infos, RankInfo{
+			Rank:    (query.Page-1)*query.Size + i + 1,
+			Address: entry.Address,
+			Scores:  entry.Score,
+			Points:  entry.Points,
+		})
+	}
+	c.JSON(200, RankRes{RnakInfo: infos})
+}
+
+func (h *handler) toPointInfoRes(user database.UserPoint) PointInfoRes {
+	interval := time.Duration(h.cfg.Load().Point.ChargeInterval) * time.Second
+	return PointInfoRes{
+		Points:        user.Points,
+		ChargingCount: user.ChargingCount,
+		Charging:      time.Since(user.LastCharge) < interval,
+	}
 }
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/ratelimit"
+)
+
+// maxPeekBody is how much of a login body is read for its address.
+const maxPeekBody = 64 << 10
+
+// addressOf returns the address a request is limited by, "" to limit it by
+// client IP only.
+type addressOf func(c *gin.Context) string
+
+// authenticatedAddress is the address set by VerifyIdentityHandler.
+func authenticatedAddress(c *gin.Context) string {
+	return c.GetString("address")
+}
+
+// noAddress limits a request by client IP only, the address of a refresh
+// token is only known once it is verified.
+func noAddress(*gin.Context) string {
+	return ""
+}
+
+// queryAddress is the address a challenge is requested for.
+func queryAddress(c *gin.Context) string {
+	address, err := auth.CanonicalAddress(c.Query("address"))
+	if err != nil {
+		return ""
+	}
+	return address
+}
+
+// loginAddress is the address claiming to sign the message of a login
+// body, the body is left for the handler to bind.
+func loginAddress(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	peek, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBody))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), c.Request.Body), c.Request.Body}
+	if err != nil {
+		return ""
+	}
+
+	var request auth.LoginRequest
+	if json.Unmarshal(peek, &request) != nil {
+		return ""
+	}
+	return auth.MessageAddress(request.Message)
+}
+
+// rateLimit limits the requests of the group by client IP and by the
+// address returned by address. The RateLimit headers describe the tighter
+// of the two limits.
+func (h *handler) rateLimit(group string, address addressOf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tightest *ratelimit.Result
+		for _, by := range []string{ratelimit.ByIP, ratelimit.ByAddress} {
+			id := c.ClientIP()
+			if by == ratelimit.ByAddress {
+				id = address(c)
+			}
+
+			res, err := h.limiter.Allow(group, by, id)
+			if err != nil {
+				// an unavailable store doesn't take the API down, a
+				// contended bucket is denied by the limiter
+				h.log(c).Errorf("rate limit %s by %s: %s", group, by, err)
+				continue
+			}
+			if res.Limit == 0 {
+				continue
+			}
+			if tightest == nil || !res.Allowed || (tightest.Allowed && res.Remaining < tightest.Remaining) {
+				tightest = &res
+			}
+			if !res.Allowed {
+				break
+			}
+		}
+		if tightest == nil {
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Header("RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(tightest.Reset.Seconds())), 10))
+		if !tightest.Allowed {
+			abortWithAPIError(c, &APIError{
+				Status:     http.StatusTooManyRequests,
+				RetryAfter: tightest.RetryAfter,
+				Code:       CodeRateLimited,
+				Message:    "Too many requests, retry after the Retry-After header",
+			})
+		}
+	}
+}
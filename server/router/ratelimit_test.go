@@ -0,0 +1,113 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/ratelimit"
+	"golang.org/x/xerrors"
+)
+
+// failingStore takes tokens from no bucket.
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) TakeToken(key string, rule ratelimit.Rule, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, s.err
+}
+
+func newLimitedRouter(store ratelimit.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := &handler{
+		logger: klog.NewHelper(klog.DefaultLogger),
+		limiter: ratelimit.NewLimiter(config.RateLimitConfig{Groups: map[string]config.RateLimitRule{
+			ratelimit.GroupChallenge: {IP: 100, Address: 2},
+			ratelimit.GroupLogin:     {IP: 100, Address: 2},
+		}}, store),
+	}
+
+	r := gin.New()
+	r.GET("/challenge", h.rateLimit(ratelimit.GroupChallenge, queryAddress), func(c *gin.Context) {
+		c.String(http.StatusOK, "challenge")
+	})
+	r.POST("/login", h.rateLimit(ratelimit.GroupLogin, loginAddress), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, "%s", body)
+	})
+	return r
+}
+
+func loginBody(address string) string {
+	return fmt.Sprintf(`{"message":"xspace.io wants you to sign in with your Ethereum account:\n%s\n\n"}`, address)
+}
+
+func TestRateLimitAddress(t *testing.T) {
+	const address = "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+	r := newLimitedRouter(ratelimit.NewMemoryStore())
+
+	for _, tc := range []struct {
+		name string
+		req  func(ip int, address string) *http.Request
+	}{
+		{"challenge", func(ip int, address string) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/challenge?address="+address, nil)
+			req.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", ip)
+			return req
+		}},
+		{"login", func(ip int, address string) *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(loginBody(address)))
+			req.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", ip)
+			return req
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// the address is limited whichever IP it comes from, in any
+			// case
+			for i, addr := range []string{address, strings.ToLower(address)} {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, tc.req(i, addr))
+				if w.Code != http.StatusOK {
+					t.Fatalf("request %d: %d %s", i, w.Code, w.Body)
+				}
+				if tc.name == "login" && w.Body.String() != loginBody(addr) {
+					t.Fatalf("login handler read %q", w.Body)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tc.req(3, address))
+			if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Limit") != "2" {
+				t.Fatalf("request over the address limit: %d %v", w.Code, w.Header())
+			}
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, tc.req(3, "0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2"))
+			if w.Code != http.StatusOK {
+				t.Fatalf("request of another address: %d %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestRateLimitStoreErrors(t *testing.T) {
+	for err, status := range map[error]int{
+		xerrors.New("database is down"):                           http.StatusOK,
+		xerrors.Errorf("rate bucket: %w", ratelimit.ErrContended): http.StatusTooManyRequests,
+	} {
+		r := newLimitedRouter(failingStore{err})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/challenge", nil))
+		if w.Code != status {
+			t.Errorf("store failing with %q: %d, expected %d", err, w.Code, status)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/realtime"
+	"golang.org/x/xerrors"
+)
+
+// @ Summary UserEvents
+//
+//	@Description	Stream the updates of the user's linked wallets as server-sent events: "points" and "charge" (topic points), "mint" job status transitions (topic mint) and "rank" movements on the leaderboards of the projects (topic rank).
+//	@Description	A "ping" event is sent every heartbeat. A "resync" event tells a client that fell behind that updates were dropped and its state should be refetched.
+//	@Description	The stream ends with an "expired" event when the access token expires, reconnect with a refreshed token. Browsers pass the token in the token query.
+//	@Tags			User
+//	@Produce		text/event-stream
+//	@Param			Authorization	header		string	false	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			token			query		string	false	"The access token, if the Authorization header can't be set"
+//	@Param			topics			query		string	false	"Comma separated topics: points, mint and rank, points and mint by default"
+//	@Param			project			query		string	false	"Comma separated ids of the projects whose rank movements are pushed"
+//	@Success		200				{string}	string	"The event stream"
+//	@Router			/v1/user/events [get]
+//	@Failure		400	{object}	APIError
+//	@Failure		429	{object}	APIError	"RATE_LIMITED, too many open streams"
+//	@Failure		500	{object}	APIError
+func (h *handler) userEvents(c *gin.Context) {
+	sub, err := parseSubscription(c.DefaultQuery("topics", "points,mint"), c.Query("project"))
+	if err != nil {
+		abortWithMessage(c, 400, err.Error())
+		return
+	}
+
+	wallets, err := h.userWallets(c)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	stream, err := h.realtime.Open(c.GetString("user"), wallets, sub)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+	defer h.realtime.Close(stream)
+
+	heartbeat := time.NewTicker(h.realtime.Heartbeat())
+	defer heartbeat.Stop()
+
+	var expired <-chan time.Time
+	if expire, ok := c.Get("expire"); ok {
+		timer := time.NewTimer(time.Until(expire.(time.Time)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ping", time.Now().Unix())
+	c.Writer.Flush()
+
+	// a client not reading its stream is dropped instead of blocking
+	writer := http.NewResponseController(c.Writer)
+	defer writer.SetWriteDeadline(time.Time{})
+	c.Stream(func(w io.Writer) bool {
+		writer.SetWriteDeadline(time.Now().Add(2 * h.realtime.Heartbeat()))
+
+		select {
+		case <-stream.Done():
+			return false
+		case <-expired:
+			c.SSEvent("expired", "the access token expired")
+			return false
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+		case <-stream.Lagged():
+			stream.Discard()
+			c.SSEvent("resync", "updates were dropped")
+		case update := <-stream.Updates():
+			c.SSEvent(update.Name, update.Data)
+		}
+		return !c.IsAborted()
+	})
+}
+
+func parseSubscription(topics, projects string) (realtime.Subscription, error) {
+	var sub realtime.Subscription
+	for _, topic := range strings.Split(topics, ",") {
+		switch topic = strings.TrimSpace(topic); topic {
+		case realtime.TopicPoints, realtime.TopicMint, realtime.TopicRank:
+			sub.Topics = append(sub.Topics, topic)
+		case "":
+		default:
+			return sub, xerrors.Errorf("unknown topic %q", topic)
+		}
+	}
+
+	for _, project := range strings.Split(projects, ",") {
+		if project = strings.TrimSpace(project); project == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(project, 10, 32)
+		if err != nil {
+			return sub, xerrors.Errorf("invalid project id %q", project)
+		}
+		sub.Projects = append(sub.Projects, uint(id))
+	}
+	return sub, nil
+}
@@ -1,6 +1,8 @@
 package router
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+)
 
 func LoadReferModule(r *gin.RouterGroup, h *handler) {
 	r.GET("/code", h.VerifyIdentityHandler, h.getReferCode)
@@ -15,15 +17,21 @@ func LoadReferModule(r *gin.RouterGroup, h *handler) {
 //	@Produce		json
 //	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
 //	@Success		200				{string}	string	"user's refer code"
-//	@Router			/v1/refer/info [get]
-//	@Failure		500	{object}	error
+//	@Router			/v1/refer/code [get]
+//	@Failure		500	{object}	APIError
 func (h *handler) getReferCode(c *gin.Context) {
-	c.JSON(200, "6YD8F9")
+	code, err := h.store.GetReferCode(c.GetString("address"))
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, code)
 }
 
 // @ Summary BindReferCode
 //
-//	@Description	Bind the refer code when first log in
+//	@Description	Bind the refer code when first log in, a user binds one refer code only
 //	@Tags			Refer
 //	@Accept			json
 //	@Produce		json
@@ -31,7 +39,21 @@ func (h *handler) getReferCode(c *gin.Context) {
 //	@Param			code			body		string	true	"Other user's refer code"
 //	@Success		200				{string}	string
 //	@Router			/v1/refer/bind [post]
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	APIError	"REFER_CODE_INVALID or REFER_SELF"
+//	@Failure		409	{object}	APIError	"REFER_BOUND, a refer code is already bound"
+//	@Failure		500	{object}	APIError
 func (h *handler) bindReferCode(c *gin.Context) {
+	var req BindReferCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	_, err := h.store.BindReferCode(c.GetString("address"), req.Code)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
 	c.JSON(200, "success")
 }
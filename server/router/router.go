@@ -1,44 +1,294 @@
 package router
 
 import (
-	"os"
+	"context"
+	"sync/atomic"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
 	klog "github.com/go-kratos/kratos/v2/log"
 
+	"github.com/memoio/xspace-server/access"
+
 	// "github.com/memoio/xspace-server/auth"
 	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/contract"
+	"github.com/memoio/xspace-server/contract/lens"
 	"github.com/memoio/xspace-server/contract/nft"
+	"github.com/memoio/xspace-server/contract/token"
+	"github.com/memoio/xspace-server/contract/wallet"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/encryption"
+	"github.com/memoio/xspace-server/event"
+	"github.com/memoio/xspace-server/indexer"
+	"github.com/memoio/xspace-server/leaderboard"
+	"github.com/memoio/xspace-server/lifecycle"
+	"github.com/memoio/xspace-server/metrics"
+	"github.com/memoio/xspace-server/mint"
+	"github.com/memoio/xspace-server/partner"
+	"github.com/memoio/xspace-server/ratelimit"
+	"github.com/memoio/xspace-server/realtime"
+	"github.com/memoio/xspace-server/storage"
+	"github.com/memoio/xspace-server/webhook"
+	"golang.org/x/xerrors"
 )
 
 type handler struct {
 	logger *klog.Helper
+	// swapped by a reload
+	cfg atomic.Pointer[config.Config]
 	// store
+	store          *database.DataStore
+	storage        storage.Storage
+	mintPolicy     *mint.Policy
+	keyManager     *encryption.KeyManager
+	accessPolicy   *access.Evaluator
 	authController *auth.AuthController
 	nftController  *nft.NFTController
+	partners       *partner.Authenticator
+	leaderboard    *leaderboard.Engine
+	webhooks       *webhook.Dispatcher
+	realtime       *realtime.Hub
+	limiter        *ratelimit.Limiter
+	chain          *ethclient.Client
 }
 
-func NewRouter(chain string, r *gin.RouterGroup) error {
-	logger := klog.With(klog.NewStdLogger(os.Stdout),
-		"ts", klog.DefaultTimestamp,
-		"caller", klog.DefaultCaller,
-	)
+// NewRouter registers the routes on r and the components they use on app,
+// app starts them.
+func NewRouter(app *lifecycle.Manager, chain string, cfg *config.Config, r *gin.RouterGroup, loggers *klog.Helper) error {
+	store, err := database.NewDataStore(cfg.Database.Driver, cfg.Database.DSN)
+	if err != nil {
+		return err
+	}
+
+	dataStorage, err := storage.NewStorage(cfg.Storage)
+	if err != nil {
+		return err
+	}
 
-	loggers := klog.NewHelper(logger)
+	lastTokenID, err := store.LastTokenID()
+	if err != nil {
+		return err
+	}
+
+	client, err := contract.NewClient(chain, cfg.Chain.RPC)
+	if err != nil {
+		return err
+	}
 
-	nftController, err := nft.NewNFTController()
+	nftController, err := nft.NewNFTController(lastTokenID)
 	if err != nil {
 		return err
 	}
 
+	keyManager, err := encryption.NewKeyManager(cfg.Storage.EncryptionKey, store)
+	if err != nil {
+		return err
+	}
+
+	var nonces auth.NonceStore
+	switch cfg.Auth.NonceStore {
+	case "memory", "":
+		nonces = auth.NewMemoryNonceStore()
+	case "database":
+		nonces = store
+	default:
+		return xerrors.Errorf("unsupported nonce store %s", cfg.Auth.NonceStore)
+	}
+
+	var buckets ratelimit.Store
+	switch cfg.RateLimit.Store {
+	case "memory", "":
+		buckets = ratelimit.NewMemoryStore()
+	case "database":
+		buckets = store
+	default:
+		return xerrors.Errorf("unsupported rate limit store %s", cfg.RateLimit.Store)
+	}
+
+	authController, err := auth.NewAuthController(cfg.Auth, nonces, keyManager, store, store, wallet.NewVerifier(client))
+	if err != nil {
+		return err
+	}
+
+	if cfg.Lens.RPC != "" {
+		lensClient, err := ethclient.Dial(cfg.Lens.RPC)
+		if err != nil {
+			return err
+		}
+		hub := lens.NewHubController(lensClient, common.HexToAddress(cfg.Lens.Hub))
+		authController.RegisterProvider(auth.ProviderLens, auth.NewLensProvider(authController, hub))
+	}
+
+	partners, err := partner.NewAuthenticator(cfg.Partner, store, buckets)
+	if err != nil {
+		return err
+	}
+
+	for _, admin := range cfg.Admins {
+		address, err := auth.CanonicalAddress(admin)
+		if err != nil {
+			return xerrors.Errorf("invalid admin %s: %w", admin, err)
+		}
+		if err := store.SetRole("config", address, auth.RoleAdmin); err != nil {
+			return err
+		}
+	}
+
+	bus := event.NewBus(store, loggers)
+	webhooks := webhook.NewDispatcher(cfg.Webhook, store, loggers)
+	webhooks.Subscribe(bus)
+	var fanout realtime.Fanout
+	switch cfg.Realtime.Fanout {
+	case "memory", "":
+	case "database":
+		fanout = store
+	default:
+		return xerrors.Errorf("unsupported realtime fanout %s", cfg.Realtime.Fanout)
+	}
+	hub := realtime.NewHub(cfg.Realtime, store, fanout, loggers)
+	hub.Subscribe(bus)
+	worker := mint.NewWorker(store, nftController, loggers)
+	nftIndexer := indexer.NewIndexer(store, keyManager, nftController, loggers)
+
+	timeout := time.Duration(cfg.Shutdown.Timeout) * time.Second
+	app.Add(lifecycle.Component{
+		Name:  "store",
+		Start: store.Ping,
+		Stop: func(context.Context) error {
+			return store.Close()
+		},
+	})
+	app.Add(lifecycle.Component{
+		Name: "chain client",
+		Stop: func(context.Context) error {
+			client.Close()
+			return nil
+		},
+	})
+	app.Add(lifecycle.Component{
+		Name: "indexer",
+		Start: func(ctx context.Context) error {
+			nftIndexer.Start(ctx)
+			return nil
+		},
+		Stop:    nftIndexer.Stop,
+		Timeout: timeout,
+	})
+	app.Add(lifecycle.Component{
+		Name: "event bus",
+		Start: func(ctx context.Context) error {
+			bus.Start(ctx)
+			return nil
+		},
+		Stop:    bus.Stop,
+		Timeout: timeout,
+	})
+	app.Add(lifecycle.Component{
+		Name:    "realtime hub",
+		Start:   hub.Start,
+		Stop:    hub.Stop,
+		Timeout: timeout,
+	})
+	app.Add(lifecycle.Component{
+		Name: "webhook dispatcher",
+		Start: func(ctx context.Context) error {
+			webhooks.Start(ctx)
+			return nil
+		},
+		Stop:    webhooks.Stop,
+		Timeout: timeout,
+	})
+	app.Add(lifecycle.Component{
+		Name: "mint worker",
+		Start: func(ctx context.Context) error {
+			worker.Start(ctx)
+			return nil
+		},
+		Stop:    worker.Stop,
+		Timeout: time.Duration(cfg.Shutdown.MintTimeout) * time.Second,
+	})
+	// the event streams would keep the http server from shutting down
+	app.OnShutdown(hub.Shutdown)
+
 	h := &handler{
-		nftController: nftController,
-		logger:        loggers,
+		store:          store,
+		storage:        dataStorage,
+		mintPolicy:     mint.NewPolicy(cfg.Mint, cfg.Storage, store),
+		keyManager:     keyManager,
+		accessPolicy:   access.NewEvaluator(store, token.NewTokenController(client)),
+		authController: authController,
+		nftController:  nftController,
+		partners:       partners,
+		leaderboard:    leaderboard.NewEngine(store),
+		webhooks:       webhooks,
+		realtime:       hub,
+		limiter:        ratelimit.NewLimiter(cfg.RateLimit, buckets),
+		chain:          client,
+		logger:         loggers,
+	}
+	h.cfg.Store(cfg)
+	app.OnReload(h.reload)
+
+	err = RegisterValidators(func() config.MintConfig { return h.cfg.Load().Mint })
+	if err != nil {
+		return err
+	}
+
+	err = metrics.GaugeFunc("nonces", "Challenge nonces kept, including expired ones not swept yet.", func() float64 {
+		if memory, ok := nonces.(*auth.MemoryNonceStore); ok {
+			return float64(memory.Len())
+		}
+		count, err := store.CountNonces()
+		if err != nil {
+			loggers.Error(err)
+		}
+		return float64(count)
+	})
+	if err != nil {
+		return err
 	}
+	err = metrics.GaugeFunc("mint_jobs_pending", "Mint jobs waiting to be minted.", func() float64 {
+		count, err := store.CountPendingMintJobs()
+		if err != nil {
+			loggers.Error(err)
+		}
+		return float64(count)
+	})
+	if err != nil {
+		return err
+	}
+
+	// probes and scrapes aren't rate limited
+	LoadHealthModule(r, h)
 
-	LoadNFTModule(r.Group("/nft"), h)
-	LoadReferModule(r.Group("/refer"), h)
-	LoadPointModules(r.Group("/"), h)
-	LoadAuthModule(r.Group("/"), h)
+	r.Use(h.rateLimit(ratelimit.GroupGlobal, authenticatedAddress))
+
+	LoadWellKnownModule(r.Group("/.well-known"), h)
+
+	v1 := r.Group("/v1")
+	LoadNFTModule(v1.Group("/nft"), h)
+	LoadReferModule(v1.Group("/refer"), h)
+	LoadPointModules(v1.Group("/"), h)
+	LoadAuthModule(v1.Group("/"), h)
+	LoadUserModule(v1.Group("/user"), h)
+	LoadAdminModule(v1.Group("/admin"), h)
+	LoadPartnerModule(v1.Group("/partner"), h)
+	return nil
+}
+
+// reload applies the settings of cfg that can change while running.
+func (h *handler) reload(cfg *config.Config) error {
+	if err := SetLogLevel(cfg.Log.Level); err != nil {
+		return err
+	}
+	h.limiter.SetConfig(cfg.RateLimit)
+	h.mintPolicy.SetConfig(cfg.Mint, cfg.Storage)
+	h.webhooks.SetConfig(cfg.Webhook)
+	h.realtime.SetConfig(cfg.Realtime)
+	h.cfg.Store(cfg)
 	return nil
 }
@@ -1,18 +1,72 @@
 package router
 
-import "time"
+import (
+	"time"
+
+	"github.com/memoio/xspace-server/leaderboard"
+)
+
+// query of the listing APIs, pages start at 1
+type PageQuery struct {
+	Page int `form:"page,default=1" binding:"min=1"`
+	Size int `form:"size,default=10" binding:"min=1,max=100"`
+}
+
+// auth types
+type SessionInfo struct {
+	ID string
+	// the wallet logged in
+	Address     string
+	ChainID     int
+	UserAgent   string
+	CreateTime  time.Time
+	RefreshTime time.Time
+	ExpireTime  time.Time
+	// whether it is the session of the calling token
+	Current bool
+}
+
+type ListSessionsRes struct {
+	Sessions []SessionInfo
+}
+
+// user types
+type WalletInfo struct {
+	Address     string
+	Points      int64
+	GodataCount int64
+	GodataSpace int64
+}
+
+type UserInfoRes struct {
+	UserID  string
+	Primary string
+	// the linked wallets, the primary one first
+	Wallets []WalletInfo
+	// totals of the linked wallets
+	Points      int64
+	GodataCount int64
+	GodataSpace int64
+}
+
+type SetPrimaryWalletReq struct {
+	Address string `binding:"required,wallet"`
+}
 
 // NFT types
 type MintTweetReq struct {
 	Address  string
-	Name     string
-	PostTime int64
-	Tweet    string
-	Images   []string
+	Name     string   `binding:"required,max=64"`
+	PostTime int64    `binding:"required,gt=0"`
+	Tweet    string   `binding:"required,tweet"`
+	Images   []string `binding:"max=4,dive,image_url"`
 }
 
 type MintRes struct {
+	// the token id is assigned after the mint job is done
 	TokenID int64
+	JobID   uint
+	Cost    int64
 }
 
 type ListNFTRes struct {
@@ -20,7 +74,9 @@ type ListNFTRes struct {
 }
 
 type NFTInfo struct {
-	TokenID    int64
+	TokenID int64
+	// the linked wallet owning the NFT
+	Address    string
 	Type       int
 	CreateTime time.Time
 }
@@ -32,16 +88,48 @@ type TweetNFTInfoRes struct {
 	Images   []string
 }
 
+type BurnNFTReq struct {
+	TokenID int64 `binding:"required"`
+}
+
+type TransferNFTReq struct {
+	TokenID int64  `binding:"required"`
+	To      string `binding:"required,evm_address"`
+}
+
+type ListNFTQuery struct {
+	PageQuery
+	Type  int    `form:"type" binding:"omitempty,oneof=1 2"`
+	Order string `form:"order" binding:"omitempty,oneof=date_asc date_dsc"`
+}
+
+type AccessPolicyInfo struct {
+	TokenID    int64
+	Mode       string
+	Contract   string
+	MinBalance string
+	Allowlist  []string
+	ExpireAt   int64
+}
+
+// refer types
+type BindReferCodeReq struct {
+	Code string `binding:"required,len=6,alphanum"`
+}
+
 // point types
 type PointInfoRes struct {
-	Points        int64
-	GodataCount   int
-	GodataSpace   int
+	Points int64
+	// number of dataNFT objects and their total size in bytes
+	GodataCount   int64
+	GodataSpace   int64
 	ChargingCount int
 	Charging      bool
 }
 
 type PointInfo struct {
+	// the linked wallet earning or spending the points
+	Address    string
 	Point      int64
 	Time       time.Time
 	ActionName string
@@ -51,11 +139,16 @@ type PointHistoryRes struct {
 	History []PointInfo
 }
 
+type PointHistoryQuery struct {
+	PageQuery
+	Order string `form:"order" binding:"omitempty,oneof=date_asc date_dsc"`
+}
+
 type ProjectInfo struct {
 	ProjectID int
-	Name      string
-	Start     time.Time
-	End       time.Time
+	Name      string    `binding:"required,max=64"`
+	Start     time.Time `binding:"required"`
+	End       time.Time `binding:"required,gtfield=Start"`
 }
 
 type ListProjectsRes struct {
@@ -72,3 +165,157 @@ type RankInfo struct {
 type RankRes struct {
 	RnakInfo []RankInfo
 }
+
+type RankQuery struct {
+	PageQuery
+	// the project
+	ID uint `form:"id" binding:"required"`
+}
+
+// admin types
+type StorageUsage struct {
+	Address string
+	Count   int64
+	Space   int64
+}
+
+type StorageReportRes struct {
+	Users []StorageUsage
+}
+
+type AdjustPointsReq struct {
+	Address string `binding:"required,wallet"`
+	// credited if positive, debited if negative
+	Points int64  `binding:"required"`
+	Reason string `binding:"required,max=256"`
+}
+
+type BanUserReq struct {
+	// any wallet of the user
+	Address string `binding:"required,wallet"`
+	Reason  string `binding:"max=256"`
+}
+
+type SetRoleReq struct {
+	Address string `binding:"required,wallet"`
+	// user, operator or admin
+	Role string `binding:"required,oneof=user operator admin"`
+}
+
+type AuditLogInfo struct {
+	ID     uint
+	Actor  string
+	Action string
+	Target string
+	Detail string
+	Time   time.Time
+}
+
+type AuditLogRes struct {
+	Logs []AuditLogInfo
+}
+
+type AuditLogQuery struct {
+	PageQuery
+	Action string `form:"action" binding:"max=64"`
+}
+
+type CreatePartnerKeyReq struct {
+	ProjectID uint   `binding:"required"`
+	Name      string `binding:"required,max=64"`
+	// requests per minute, the server's default if 0
+	RateLimit int `binding:"min=0"`
+}
+
+type PartnerKeyInfo struct {
+	KeyID      string
+	ProjectID  uint
+	Name       string
+	RateLimit  int
+	Revoked    bool
+	CreateTime time.Time
+}
+
+type CreatePartnerKeyRes struct {
+	PartnerKeyInfo
+	// the signing secret, only returned when the key is created
+	Secret string
+}
+
+type ListPartnerKeysRes struct {
+	Keys []PartnerKeyInfo
+}
+
+type CreateWebhookReq struct {
+	// the partner's project, receiving only the events of the project, 0
+	// for our own bots receiving all events
+	ProjectID uint
+	URL       string `binding:"required,http_url,max=512"`
+	// the event types, all types if empty
+	Events []string `binding:"dive,event_type"`
+}
+
+type WebhookInfo struct {
+	WebhookID  uint
+	ProjectID  uint
+	URL        string
+	Events     []string
+	CreateTime time.Time
+}
+
+type CreateWebhookRes struct {
+	WebhookInfo
+	// the key of the HMAC signing the payloads, only returned when the
+	// webhook is created
+	Secret string
+}
+
+type ListWebhooksRes struct {
+	Webhooks []WebhookInfo
+}
+
+type WebhookAttemptInfo struct {
+	StatusCode int
+	Error      string
+	// milliseconds
+	Duration int64
+	Time     time.Time
+}
+
+type WebhookDeliveryInfo struct {
+	DeliveryID uint
+	EventID    string
+	EventType  string
+	// pending, succeeded or dead
+	Status        string
+	Attempts      []WebhookAttemptInfo
+	NextAttemptAt time.Time
+	CreateTime    time.Time
+}
+
+type ListWebhookDeliveriesRes struct {
+	Deliveries []WebhookDeliveryInfo
+}
+
+type WebhookDeliveriesQuery struct {
+	PageQuery
+	// pending, succeeded or dead, all deliveries if empty
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+}
+
+// health types
+type HealthRes struct {
+	// ok or unavailable
+	Status string
+	// the result of each check, ok or unavailable
+	Checks map[string]string `json:",omitempty"`
+}
+
+// partner types
+type SubmitEventsReq struct {
+	Events []leaderboard.Event
+}
+
+type SubmitEventsRes struct {
+	Results []leaderboard.Result
+}
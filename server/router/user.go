@@ -0,0 +1,207 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/ratelimit"
+	"golang.org/x/xerrors"
+)
+
+func LoadUserModule(r *gin.RouterGroup, h *handler) {
+	r.GET("", h.VerifyIdentityHandler, h.userInfo)
+	r.GET("/link/challenge", h.VerifyIdentityHandler, h.rateLimit(ratelimit.GroupChallenge, authenticatedAddress), h.linkChallenge)
+	r.POST("/link", h.VerifyIdentityHandler, h.rateLimit(ratelimit.GroupLogin, authenticatedAddress), h.linkWallet)
+	r.DELETE("/wallets/:address", h.VerifyIdentityHandler, h.unlinkWallet)
+	r.PUT("/primary", h.VerifyIdentityHandler, h.setPrimaryWallet)
+	r.GET("/events", h.VerifyIdentityHandler, h.userEvents)
+}
+
+// @ Summary User
+//
+//	@Description	Get the user's linked wallets with their points and dataNFT storage, and the totals of all wallets
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Success		200				{object}	UserInfoRes
+//	@Router			/v1/user [get]
+//	@Failure		500	{object}	APIError
+func (h *handler) userInfo(c *gin.Context) {
+	wallets, err := h.userWallets(c)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	res := UserInfoRes{UserID: c.GetString("user"), Primary: wallets[0]}
+	for _, address := range wallets {
+		point, err := h.store.GetUserPoint(address)
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+		usage, err := h.store.GetUserStorage(address)
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+
+		res.Wallets = append(res.Wallets, WalletInfo{
+			Address:     address,
+			Points:      point.Points,
+			GodataCount: usage.Count,
+			GodataSpace: usage.Space,
+		})
+		res.Points += point.Points
+		res.GodataCount += usage.Count
+		res.GodataSpace += usage.Space
+	}
+	c.JSON(200, res)
+}
+
+// @ Summary LinkChallenge
+//
+//	@Description	Get the message linking another wallet to the user, it is signed by the wallet the user is logged in with and by the wallet being linked
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			address			query		string	true	"The wallet being linked, an ethereum or solana address"
+//	@Param			Origin			header		string	true	"The frontend's origin, its host must be an allowed domain"
+//	@Success		200				{string}	string	"The link message"
+//	@Router			/v1/user/link/challenge [get]
+//	@Failure		400	{object}	APIError	"SIWE_INVALID, the origin or address is invalid"
+//	@Failure		429	{object}	APIError	"RATE_LIMITED, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
+func (h *handler) linkChallenge(c *gin.Context) {
+	message, err := h.authController.LinkChallenge(c.GetHeader("Origin"), c.GetString("address"), c.Query("address"))
+	if err != nil {
+		h.abortWithChallengeError(c, err)
+		return
+	}
+	c.String(http.StatusOK, message)
+}
+
+// @ Summary LinkWallet
+//
+//	@Description	Link another wallet to the user with the link message signed by both wallets, the lens profiles the wallet owns are linked with it.
+//	@Description	A wallet that has logged in before can only be linked if its user has no other wallet.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			message			body		string	true	"The link message"
+//	@Param			signature		body		string	true	"The signature of the wallet the user is logged in with"
+//	@Param			linkSignature	body		string	true	"The signature of the wallet being linked"
+//	@Success		200				{object}	UserInfoRes
+//	@Router			/v1/user/link [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		401	{object}	APIError	"SIWE_INVALID or SIGNATURE_INVALID"
+//	@Failure		403	{object}	APIError	"USER_BANNED, the wallet belongs to a banned user"
+//	@Failure		409	{object}	APIError	"WALLET_LINKED, the wallet belongs to another user with other wallets"
+//	@Failure		429	{object}	APIError	"RATE_LIMITED, retry after the Retry-After header"
+//	@Failure		500	{object}	APIError
+func (h *handler) linkWallet(c *gin.Context) {
+	var request auth.LinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	_, err := h.authController.LinkWallet(c.Request.Context(), c.GetString("user"), c.GetString("address"), request)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	h.userInfo(c)
+}
+
+// @ Summary UnlinkWallet
+//
+//	@Description	Unlink a wallet from the user, it becomes a user of its own. The primary wallet can't be unlinked.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			address			path		string	true	"The linked wallet"
+//	@Success		200				{string}	string
+//	@Router			/v1/user/wallets/{address} [delete]
+//	@Failure		400	{object}	APIError	"PRIMARY_WALLET, the wallet is the primary one, or the address is invalid"
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) unlinkWallet(c *gin.Context) {
+	address, ok := canonicalAddress(c, c.Param("address"))
+	if !ok {
+		return
+	}
+
+	err := h.store.UnlinkWallet(c.GetString("user"), address)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Wallet not linked")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+// @ Summary SetPrimaryWallet
+//
+//	@Description	Select the primary wallet of the user among the linked ones
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			address			body		string	true	"The linked wallet"
+//	@Success		200				{string}	string
+//	@Router			/v1/user/primary [put]
+//	@Failure		400	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) setPrimaryWallet(c *gin.Context) {
+	var req SetPrimaryWalletReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	address, ok := canonicalAddress(c, req.Address)
+	if !ok {
+		return
+	}
+
+	err := h.store.SetPrimaryWallet(c.GetString("user"), address)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Wallet not linked")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+// userWallets returns the wallets linked to the caller's user, the primary
+// one first. A token issued before users existed only has its address.
+func (h *handler) userWallets(c *gin.Context) ([]string, error) {
+	address := c.GetString("address")
+	userID := c.GetString("user")
+	if userID == "" {
+		return []string{address}, nil
+	}
+
+	wallets, err := h.store.ListUserWallets(userID)
+	if xerrors.Is(err, database.ErrNotFound) || (err == nil && len(wallets) == 0) {
+		return []string{address}, nil
+	}
+	return wallets, err
+}
@@ -0,0 +1,199 @@
+package router
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	auth "github.com/memoio/xspace-server/authentication"
+	"github.com/memoio/xspace-server/config"
+	"golang.org/x/xerrors"
+)
+
+// FieldError is a field of the request failing validation.
+type FieldError struct {
+	// the field in the body or the query
+	Field string `json:"field" example:"Tweet"`
+	// the rule it breaks, a binding tag such as required, max or oneof
+	Rule    string `json:"rule" example:"max"`
+	Param   string `json:"param,omitempty" example:"280"`
+	Message string `json:"message" example:"must be at most 280 characters"`
+}
+
+// RegisterValidators adds the validators of the binding tags of the request
+// types to gin's validator:
+//
+//	evm_address  a hex Ethereum address
+//	wallet       an address of any wallet a user can log in with
+//	tweet        a tweet, at most MaxTweetLength characters
+//	image_url    an https url on one of ImageHosts
+//	event_type   a type of the domain events
+//
+// mintConfig returns the current mint config, so a reloaded MaxTweetLength
+// or ImageHosts applies to the next requests.
+func RegisterValidators(mintConfig func() config.MintConfig) error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return xerrors.New("unsupported binding validator")
+	}
+
+	v.RegisterTagNameFunc(fieldName)
+
+	validators := map[string]validator.Func{
+		"evm_address": func(fl validator.FieldLevel) bool {
+			return common.IsHexAddress(fl.Field().String())
+		},
+		"wallet": func(fl validator.FieldLevel) bool {
+			_, err := auth.CanonicalAddress(fl.Field().String())
+			return err == nil
+		},
+		"tweet": func(fl validator.FieldLevel) bool {
+			max := mintConfig().MaxTweetLength
+			return max <= 0 || utf8.RuneCountInString(fl.Field().String()) <= max
+		},
+		"image_url": func(fl validator.FieldLevel) bool {
+			return validImageURL(fl.Field().String(), mintConfig().ImageHosts)
+		},
+		"event_type": func(fl validator.FieldLevel) bool {
+			return validEventType(fl.Field().String())
+		},
+	}
+	for tag, fn := range validators {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldName names a field as the client sends it, by its json or form tag.
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func validImageURL(raw string, hosts []string) bool {
+	uri, err := url.Parse(raw)
+	if err != nil || uri.Scheme != "https" || uri.Host == "" {
+		return false
+	}
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, host := range hosts {
+		if strings.EqualFold(uri.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// abortWithBindError replies a request that couldn't be bound, with the
+// fields failing validation in the details.
+func abortWithBindError(c *gin.Context, err error) {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+		syntaxErr      *json.SyntaxError
+		numErr         *strconv.NumError
+	)
+	switch {
+	case xerrors.As(err, &validationErrs):
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, e := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   e.Field(),
+				Rule:    e.Tag(),
+				Param:   e.Param(),
+				Message: ruleMessage(e),
+			})
+		}
+		abortWithAPIError(c, &APIError{Status: 400, Code: CodeInvalidRequest, Message: "Invalid request", Details: fields})
+	case xerrors.As(err, &typeErr):
+		abortWithAPIError(c, &APIError{Status: 400, Code: CodeInvalidRequest, Message: "Invalid request", Details: []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   jsonType(typeErr.Type),
+			Message: "must be a " + jsonType(typeErr.Type),
+		}}})
+	case xerrors.As(err, &syntaxErr):
+		abortWithMessage(c, 400, "Malformed JSON body")
+	case xerrors.As(err, &numErr):
+		abortWithMessage(c, 400, "Invalid number "+strconv.Quote(numErr.Num))
+	default:
+		abortWithMessage(c, 400, err.Error())
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+func ruleMessage(e validator.FieldError) string {
+	unit := ""
+	switch e.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + e.Param() + unit
+	case "max":
+		return "must be at most " + e.Param() + unit
+	case "len":
+		return "must be " + e.Param() + unit + " long"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(e.Param(), " ", ", ")
+	case "gt":
+		return "must be greater than " + e.Param()
+	case "gtfield":
+		return "must be after " + e.Param()
+	case "alphanum":
+		return "must be letters and digits"
+	case "http_url":
+		return "must be an http or https url"
+	case "evm_address":
+		return "must be a hex address"
+	case "wallet":
+		return "must be a wallet address"
+	case "tweet":
+		return "is too long for a tweet"
+	case "image_url":
+		return "must be an https image url of an allowed host"
+	case "event_type":
+		return "is an unknown event type"
+	}
+	return "is invalid"
+}
@@ -0,0 +1,261 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/webhook"
+	"golang.org/x/xerrors"
+)
+
+var deliveryStatuses = []string{
+	database.DeliveryPending:   "pending",
+	database.DeliverySucceeded: "succeeded",
+	database.DeliveryDead:      "dead",
+}
+
+// @ Summary CreateWebhook
+//
+//	@Description	Subscribe a url to xspace events, for admins: nft.minted, nft.burned, nft.transferred, mint.updated, points.credited, point.charged, refer.bound and project.scored.
+//	@Description	The events are posted as json, signed with the returned secret: X-Xspace-Signature is the hex HMAC-SHA256 of X-Xspace-Timestamp, a dot and the body.
+//	@Description	A delivery without a 2xx response is retried with exponential backoff and dead-lettered after too many attempts.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string				true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			webhook			body		CreateWebhookReq	true	"The subscription"
+//	@Success		200				{object}	CreateWebhookRes
+//	@Router			/v1/admin/webhooks [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError	"Project not found"
+//	@Failure		500	{object}	APIError
+func (h *handler) createWebhook(c *gin.Context) {
+	var req CreateWebhookReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+	sub := &database.WebhookSubscription{
+		ProjectID: req.ProjectID,
+		URL:       req.URL,
+		Events:    strings.Join(req.Events, ","),
+		Secret:    secret,
+	}
+	err = h.store.CreateWebhook(c.GetString("address"), sub)
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Project not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(200, CreateWebhookRes{WebhookInfo: toWebhookInfo(*sub), Secret: secret})
+}
+
+// @ Summary ListWebhooks
+//
+//	@Description	List the webhook subscriptions, for admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			project			query		string	false	"Only list the subscriptions of the project"
+//	@Success		200				{object}	ListWebhooksRes
+//	@Router			/v1/admin/webhooks [get]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) listWebhooks(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.DefaultQuery("project", "0"), 10, 32)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid project id")
+		return
+	}
+
+	subs, err := h.store.ListWebhooks(uint(projectID))
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	infos := make([]WebhookInfo, 0, len(subs))
+	for _, sub := range subs {
+		infos = append(infos, toWebhookInfo(sub))
+	}
+	c.JSON(200, ListWebhooksRes{Webhooks: infos})
+}
+
+// @ Summary DeleteWebhook
+//
+//	@Description	Delete a webhook subscription, for admins. Its pending deliveries are dead-lettered.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			id				path		string	true	"Webhook id"
+//	@Success		200				{string}	string
+//	@Router			/v1/admin/webhooks/{id} [delete]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) deleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid webhook id")
+		return
+	}
+
+	err = h.store.DeleteWebhook(c.GetString("address"), uint(id))
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Webhook not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+// @ Summary ListWebhookDeliveries
+//
+//	@Description	List the deliveries of a webhook subscription with their attempts, newest first, for admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			id				path		string	true	"Webhook id"
+//	@Param			status			query		string	false	"Only list the deliveries with the status: pending, succeeded or dead"
+//	@Param			page			query		string	true	"Pages"
+//	@Param			size			query		string	true	"The amount of data displayed on each page, at most 100"
+//	@Success		200				{object}	ListWebhookDeliveriesRes
+//	@Router			/v1/admin/webhooks/{id}/deliveries [get]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		500	{object}	APIError
+func (h *handler) listWebhookDeliveries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid webhook id")
+		return
+	}
+	var query WebhookDeliveriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+	status := -1
+	for i, name := range deliveryStatuses {
+		if name == query.Status {
+			status = i
+		}
+	}
+
+	deliveries, err := h.store.ListDeliveries(uint(id), status, query.Page, query.Size)
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	infos := make([]WebhookDeliveryInfo, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		attempts, err := h.store.ListWebhookAttempts(delivery.ID)
+		if err != nil {
+			h.abortWithError(c, err)
+			return
+		}
+
+		info := WebhookDeliveryInfo{
+			DeliveryID: delivery.ID,
+			EventID:    delivery.EventID,
+			EventType:  delivery.EventType,
+			Status:     deliveryStatuses[delivery.Status],
+			Attempts:   make([]WebhookAttemptInfo, 0, len(attempts)),
+			CreateTime: delivery.CreatedAt,
+		}
+		if delivery.Status == database.DeliveryPending {
+			info.NextAttemptAt = delivery.NextAttemptAt
+		}
+		for _, attempt := range attempts {
+			info.Attempts = append(info.Attempts, WebhookAttemptInfo{
+				StatusCode: attempt.StatusCode,
+				Error:      attempt.Error,
+				Duration:   attempt.Duration,
+				Time:       attempt.CreatedAt,
+			})
+		}
+		infos = append(infos, info)
+	}
+	c.JSON(200, ListWebhookDeliveriesRes{Deliveries: infos})
+}
+
+// @ Summary RedeliverWebhook
+//
+//	@Description	Queue a dead-lettered delivery again, for admins
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer YOUR_ACCESS_TOKEN"
+//	@Param			id				path		string	true	"Delivery id"
+//	@Success		200				{string}	string
+//	@Router			/v1/admin/webhooks/deliveries/{id}/redeliver [post]
+//	@Failure		400	{object}	APIError
+//	@Failure		403	{object}	APIError
+//	@Failure		404	{object}	APIError	"The delivery is not dead or its webhook is deleted"
+//	@Failure		500	{object}	APIError
+func (h *handler) redeliverWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithMessage(c, 400, "Invalid delivery id")
+		return
+	}
+
+	err = h.store.RedeliverWebhook(c.GetString("address"), uint(id))
+	if xerrors.Is(err, database.ErrNotFound) {
+		abortWithMessage(c, 404, "Dead delivery not found")
+		return
+	}
+	if err != nil {
+		h.abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "success")
+}
+
+func toWebhookInfo(sub database.WebhookSubscription) WebhookInfo {
+	events := []string{}
+	if sub.Events != "" {
+		events = strings.Split(sub.Events, ",")
+	}
+	return WebhookInfo{
+		WebhookID:  sub.ID,
+		ProjectID:  sub.ProjectID,
+		URL:        sub.URL,
+		Events:     events,
+		CreateTime: sub.CreatedAt,
+	}
+}
+
+func validEventType(event string) bool {
+	for _, t := range webhook.EventTypes {
+		if t == event {
+			return true
+		}
+	}
+	return false
+}
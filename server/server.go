@@ -1,28 +1,50 @@
 package server
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/memoio/xspace-server/config"
 	"github.com/memoio/xspace-server/docs"
+	"github.com/memoio/xspace-server/lifecycle"
 	"github.com/memoio/xspace-server/server/router"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func NewServer(chain, port string) (*http.Server, error) {
+// NewServer builds the server listening on port, the returned manager
+// starts and stops it with the components it uses.
+func NewServer(chain, port string, cfg *config.Config) (*lifecycle.Manager, error) {
 	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
+	logger, err := router.NewLogger(cfg.Log)
+	if err != nil {
+		return nil, err
+	}
+	app := lifecycle.NewManager(logger)
+
+	r := gin.New()
 	r.MaxMultipartMemory = 8 << 20 // 8 MiB
+	// client IPs are rate limited, only trusted proxies may forward them
+	if err := r.SetTrustedProxies(cfg.RateLimit.TrustedProxies); err != nil {
+		return nil, err
+	}
 
-	r.Use(router.Cors())
+	cors, err := router.Cors(cfg.Cors, cfg.Auth.AllowedDomains)
+	if err != nil {
+		return nil, err
+	}
+	r.Use(gin.Recovery(), router.RequestID(), router.Tracing(), router.Logger(logger), router.Metrics(), cors)
+	r.NoRoute(router.NoRoute)
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "Welcome to Xspace Server",
 		})
 	})
 
-	err := router.NewRouter(chain, r.Group("/v1"))
+	err = router.NewRouter(app, chain, cfg, &r.RouterGroup, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -30,8 +52,33 @@ func NewServer(chain, port string) (*http.Server, error) {
 	docs.SwaggerInfo.Schemes = []string{"http", "https"}
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	return &http.Server{
+	srv := &http.Server{
 		Addr:    ":" + port,
 		Handler: r,
-	}, nil
+	}
+	app.Add(lifecycle.Component{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+					logger.Fatalf("serve: %s", err)
+				}
+			}()
+			return nil
+		},
+		// waits for the requests in flight, the ones left are cut
+		Stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				return err
+			}
+			return nil
+		},
+		Timeout: time.Duration(cfg.Shutdown.Timeout) * time.Second,
+	})
+	return app, nil
 }
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Headers used by the meeda store node API.
+const (
+	HeaderContentSha256 = "X-Content-Sha256"
+	HeaderChunkIndex    = "X-Chunk-Index"
+)
+
+type MeedaOptions struct {
+	// objects larger than ChunkSize are uploaded in chunks of this size
+	ChunkSize int64
+	// number of retries for one request
+	MaxRetries int
+	// the delay before the first retry, doubled after every retry
+	RetryDelay time.Duration
+	// downloaded objects up to this size are verified in memory, larger
+	// ones in a temporary file
+	MaxBufferSize int64
+	Client        *http.Client
+}
+
+func DefaultMeedaOptions() MeedaOptions {
+	return MeedaOptions{
+		ChunkSize:     4 << 20,
+		MaxRetries:    3,
+		RetryDelay:    500 * time.Millisecond,
+		MaxBufferSize: 16 << 20,
+		Client:        &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// MeedaStorage stores objects on a meeda store node through its HTTP API:
+//
+//	PUT    /object/{key}                     upload a small object in one request
+//	POST   /upload                           start a chunked upload, returns {"uploadID": ...}
+//	PUT    /upload/{uploadID}                upload one chunk, X-Chunk-Index is its index
+//	POST   /upload/{uploadID}/complete?key=  assemble the chunks into the object
+//	GET    /object/{key}                     download an object
+//	DELETE /object/{key}                     delete an object
+//
+// Every upload carries the sha256 of its content in X-Content-Sha256 and the
+// node rejects mismatching content; downloads are verified the same way
+// before they are returned, so a corrupt object is never served.
+// The POST requests are not idempotent and are never retried, a failed
+// chunked upload is started over by the caller.
+type MeedaStorage struct {
+	endpoint string
+	opts     MeedaOptions
+}
+
+var _ Storage = (*MeedaStorage)(nil)
+
+func NewMeedaStorage(endpoint string, opts MeedaOptions) (*MeedaStorage, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid meeda URL %s: %w", endpoint, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, xerrors.Errorf("invalid meeda URL %s: expected http(s)://host[:port]", endpoint)
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultMeedaOptions().ChunkSize
+	}
+	if opts.MaxBufferSize <= 0 {
+		opts.MaxBufferSize = DefaultMeedaOptions().MaxBufferSize
+	}
+	if opts.Client == nil {
+		opts.Client = DefaultMeedaOptions().Client
+	}
+	return &MeedaStorage{endpoint: endpoint, opts: opts}, nil
+}
+
+func (s *MeedaStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if size >= 0 && size <= s.opts.ChunkSize {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) != size {
+			return xerrors.Errorf("object %s: read %d bytes, expected %d", key, len(data), size)
+		}
+
+		_, err = s.do(ctx, http.MethodPut, s.objectURL(key), data, map[string]string{
+			HeaderContentSha256: sha256Hex(data),
+		})
+		return err
+	}
+
+	return s.putChunked(ctx, key, r, size)
+}
+
+func (s *MeedaStorage) putChunked(ctx context.Context, key string, r io.Reader, size int64) error {
+	body, err := s.do(ctx, http.MethodPost, s.endpoint+"/upload", nil, nil)
+	if err != nil {
+		return err
+	}
+	var upload struct {
+		UploadID string `json:"uploadID"`
+	}
+	if err := json.Unmarshal(body, &upload); err != nil {
+		return xerrors.Errorf("start upload of %s: %w", key, err)
+	}
+
+	uploadURL := s.endpoint + "/upload/" + url.PathEscape(upload.UploadID)
+	whole := sha256.New()
+	chunk := make([]byte, s.opts.ChunkSize)
+	var total int64
+	for index := 0; ; index++ {
+		n, err := io.ReadFull(r, chunk)
+		if n > 0 {
+			whole.Write(chunk[:n])
+			total += int64(n)
+
+			_, err := s.do(ctx, http.MethodPut, uploadURL, chunk[:n], map[string]string{
+				HeaderChunkIndex:    strconv.Itoa(index),
+				HeaderContentSha256: sha256Hex(chunk[:n]),
+			})
+			if err != nil {
+				return xerrors.Errorf("upload chunk %d of %s: %w", index, key, err)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if size >= 0 && total != size {
+		return xerrors.Errorf("object %s: read %d bytes, expected %d", key, total, size)
+	}
+
+	_, err = s.do(ctx, http.MethodPost, uploadURL+"/complete?key="+url.QueryEscape(key), nil, map[string]string{
+		HeaderContentSha256: hex.EncodeToString(whole.Sum(nil)),
+	})
+	return err
+}
+
+// Get downloads the whole object and checks its sha256 before returning it:
+// a mismatch found while the content is streamed to a client would come
+// after the response status and most of the content are sent.
+func (s *MeedaStorage) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	var res *http.Response
+	err := s.retry(ctx, func() (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+		if err != nil {
+			return false, err
+		}
+		res, err = s.opts.Client.Do(req)
+		if err != nil {
+			return true, err
+		}
+		if res.StatusCode != http.StatusOK {
+			defer res.Body.Close()
+			return checkResponse(res)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer res.Body.Close()
+
+	expected := res.Header.Get(HeaderContentSha256)
+	if expected == "" {
+		return nil, 0, xerrors.Errorf("object %s: missing %s header", key, HeaderContentSha256)
+	}
+
+	hash := sha256.New()
+	content, size, err := s.download(io.TeeReader(res.Body, hash))
+	if err != nil {
+		return nil, 0, xerrors.Errorf("download object %s: %w", key, err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != expected {
+		content.Close()
+		return nil, 0, xerrors.Errorf("meeda store node: content of object %s doesn't match its sha256", key)
+	}
+	return content, size, nil
+}
+
+// download reads the body into memory, or into a temporary file removed on
+// close if it's larger than MaxBufferSize.
+func (s *MeedaStorage) download(body io.Reader) (io.ReadCloser, int64, error) {
+	var buf bytes.Buffer
+	size, err := io.Copy(&buf, io.LimitReader(body, s.opts.MaxBufferSize+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if size <= s.opts.MaxBufferSize {
+		return io.NopCloser(&buf), size, nil
+	}
+
+	f, err := os.CreateTemp("", "meeda-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	file := &tempFile{f}
+	rest, err := io.Copy(f, io.MultiReader(&buf, body))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, rest, nil
+}
+
+func (s *MeedaStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil, nil)
+	return err
+}
+
+func (s *MeedaStorage) objectURL(key string) string {
+	return s.endpoint + "/object/" + url.PathEscape(key)
+}
+
+// do sends a request and returns the response body. Only the idempotent
+// requests are retried: a retried POST could start a second upload or
+// complete one twice.
+func (s *MeedaStorage) do(ctx context.Context, method, url string, data []byte, headers map[string]string) ([]byte, error) {
+	idempotent := method != http.MethodPost
+	var body []byte
+	err := s.retry(ctx, func() (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+		if err != nil {
+			return false, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		res, err := s.opts.Client.Do(req)
+		if err != nil {
+			return idempotent, err
+		}
+		defer res.Body.Close()
+
+		if retry, err := checkResponse(res); err != nil {
+			return retry && idempotent, err
+		}
+		body, err = io.ReadAll(res.Body)
+		return idempotent, err
+	})
+	return body, err
+}
+
+// retry calls fn until it succeeds, fails with a non-retryable error or the
+// retries are used up.
+func (s *MeedaStorage) retry(ctx context.Context, fn func() (bool, error)) error {
+	delay := s.opts.RetryDelay
+	for i := 0; ; i++ {
+		retryable, err := fn()
+		if err == nil || !retryable || i >= s.opts.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// checkResponse converts a non-2xx response to an error, server errors are
+// retryable.
+func checkResponse(res *http.Response) (bool, error) {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return false, ErrObjectNotFound
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return res.StatusCode >= 500, xerrors.Errorf("meeda store node: %s: %s", res.Status, bytes.TrimSpace(msg))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// tempFile is a temporary file removed when it's closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
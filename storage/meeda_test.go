@@ -0,0 +1,226 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/memoio/xspace-server/storage"
+	"github.com/memoio/xspace-server/storage/meedatest"
+	"golang.org/x/xerrors"
+)
+
+func newMeeda(t *testing.T, chunkSize int64) (*storage.MeedaStorage, *meedatest.StoreNode) {
+	t.Helper()
+	node := meedatest.NewStoreNode()
+	t.Cleanup(node.Close)
+
+	opts := storage.DefaultMeedaOptions()
+	opts.ChunkSize = chunkSize
+	opts.RetryDelay = time.Millisecond
+	s, err := storage.NewMeedaStorage(node.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, node
+}
+
+func put(t *testing.T, s *storage.MeedaStorage, key string, data []byte) error {
+	t.Helper()
+	return s.Put(context.Background(), key, bytes.NewReader(data), int64(len(data)))
+}
+
+func get(t *testing.T, s *storage.MeedaStorage, key string) ([]byte, error) {
+	t.Helper()
+	r, _, err := s.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func TestMeedaPutGet(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		size int
+	}{
+		{"single", 10},
+		{"chunk size", 16},
+		{"chunked", 40},
+		{"chunked exact", 48},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, node := newMeeda(t, 16)
+			data := bytes.Repeat([]byte("0123456789"), 5)[:tc.size]
+
+			if err := put(t, s, "a/b c", data); err != nil {
+				t.Fatal(err)
+			}
+			stored, ok := node.Object("a/b c")
+			if !ok || !bytes.Equal(stored, data) {
+				t.Fatalf("stored %q, expected %q", stored, data)
+			}
+
+			got, err := get(t, s, "a/b c")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("got %q, expected %q", got, data)
+			}
+		})
+	}
+}
+
+func TestMeedaPutSizeMismatch(t *testing.T) {
+	s, _ := newMeeda(t, 16)
+	for _, size := range []int64{20, 100} {
+		if err := s.Put(context.Background(), "key", bytes.NewReader(make([]byte, 10)), size); err == nil {
+			t.Fatalf("put of 10 bytes as %d succeeded", size)
+		}
+	}
+}
+
+func TestMeedaRetry(t *testing.T) {
+	s, node := newMeeda(t, 16)
+	data := []byte("retried")
+
+	node.FailNext(2)
+	if err := put(t, s, "key", data); err != nil {
+		t.Fatal(err)
+	}
+	node.FailNext(3)
+	got, err := get(t, s, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %q, expected %q", got, data)
+	}
+
+	node.FailNext(4)
+	if _, err := get(t, s, "key"); err == nil {
+		t.Fatal("get succeeded after the retries were used up")
+	}
+}
+
+func TestMeedaNoRetryPost(t *testing.T) {
+	s, node := newMeeda(t, 16)
+
+	node.FailNext(1)
+	if err := put(t, s, "key", make([]byte, 40)); err == nil {
+		t.Fatal("chunked upload succeeded after its start failed")
+	}
+	if _, ok := node.Object("key"); ok {
+		t.Fatal("object stored after a failed upload")
+	}
+
+	if err := put(t, s, "key", make([]byte, 40)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeedaCorrupt(t *testing.T) {
+	s, node := newMeeda(t, 16)
+	if err := put(t, s, "key", []byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the mismatch fails the request before any content is served
+	node.Corrupt("key")
+	if r, _, err := s.Get(context.Background(), "key"); err == nil {
+		r.Close()
+		t.Fatal("corrupt object returned without error")
+	}
+}
+
+func TestMeedaGetLarge(t *testing.T) {
+	node := meedatest.NewStoreNode()
+	t.Cleanup(node.Close)
+	opts := storage.DefaultMeedaOptions()
+	opts.MaxBufferSize = 16
+	s, err := storage.NewMeedaStorage(node.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	empty := func() bool {
+		entries, err := os.ReadDir(tmp)
+		return err == nil && len(entries) == 0
+	}
+
+	for _, size := range []int{16, 17, 100} {
+		data := bytes.Repeat([]byte("0123456789"), 10)[:size]
+		if err := put(t, s, "key", data); err != nil {
+			t.Fatal(err)
+		}
+
+		r, n, err := s.Get(context.Background(), "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(size) || !bytes.Equal(got, data) {
+			t.Fatalf("got %d bytes %q, expected %q", n, got, data)
+		}
+		r.Close()
+		if !empty() {
+			t.Fatalf("temporary file of a %d bytes object left after close", size)
+		}
+
+		node.Corrupt("key")
+		if r, _, err := s.Get(context.Background(), "key"); err == nil {
+			r.Close()
+			t.Fatalf("corrupt object of %d bytes returned without error", size)
+		}
+		if !empty() {
+			t.Fatalf("temporary file of a corrupt %d bytes object left", size)
+		}
+	}
+}
+
+func TestMeedaNotFound(t *testing.T) {
+	s, _ := newMeeda(t, 16)
+
+	if _, err := get(t, s, "missing"); !xerrors.Is(err, storage.ErrObjectNotFound) {
+		t.Fatalf("get: %v, expected ErrObjectNotFound", err)
+	}
+	if err := s.Delete(context.Background(), "missing"); !xerrors.Is(err, storage.ErrObjectNotFound) {
+		t.Fatalf("delete: %v, expected ErrObjectNotFound", err)
+	}
+
+	if err := put(t, s, "key", []byte("content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(context.Background(), "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get(t, s, "key"); !xerrors.Is(err, storage.ErrObjectNotFound) {
+		t.Fatalf("get after delete: %v, expected ErrObjectNotFound", err)
+	}
+}
+
+func TestNewMeedaStorage(t *testing.T) {
+	for endpoint, valid := range map[string]bool{
+		"http://127.0.0.1:38082": true,
+		"https://meeda.example/": true,
+		"127.0.0.1:38082":        false,
+		"ftp://127.0.0.1":        false,
+		"http://":                false,
+		"":                       false,
+		"http://[::1":            false,
+	} {
+		_, err := storage.NewMeedaStorage(endpoint, storage.MeedaOptions{})
+		if (err == nil) != valid {
+			t.Errorf("NewMeedaStorage(%q): %v", endpoint, err)
+		}
+	}
+}
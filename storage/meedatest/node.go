@@ -0,0 +1,187 @@
+// Package meedatest provides an in-process meeda store node for testing the
+// meeda storage backend.
+package meedatest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/memoio/xspace-server/storage"
+)
+
+// StoreNode implements the meeda store node API documented on
+// storage.MeedaStorage in memory.
+type StoreNode struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	objects  map[string]*object
+	uploads  map[string]map[int][]byte
+	failNext int
+}
+
+type object struct {
+	data []byte
+	// the sha256 recorded at upload time
+	sum string
+}
+
+func NewStoreNode() *StoreNode {
+	n := &StoreNode{
+		objects: make(map[string]*object),
+		uploads: make(map[string]map[int][]byte),
+	}
+	n.Server = httptest.NewServer(http.HandlerFunc(n.serve))
+	return n
+}
+
+// FailNext makes the next count requests fail with 503.
+func (n *StoreNode) FailNext(count int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.failNext = count
+}
+
+// Object returns the stored content of key.
+func (n *StoreNode) Object(key string) ([]byte, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	obj, ok := n.objects[key]
+	if !ok {
+		return nil, false
+	}
+	return obj.data, true
+}
+
+// Corrupt flips a byte of the stored object to exercise integrity checks.
+func (n *StoreNode) Corrupt(key string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if obj, ok := n.objects[key]; ok && len(obj.data) > 0 {
+		obj.data[0] ^= 0xff
+	}
+}
+
+func (n *StoreNode) serve(w http.ResponseWriter, r *http.Request) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.failNext > 0 {
+		n.failNext--
+		http.Error(w, "store node is busy", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "object":
+		key, err := url.PathUnescape(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n.serveObject(w, r, key, body)
+	case len(parts) == 1 && parts[0] == "upload" && r.Method == http.MethodPost:
+		id := make([]byte, 8)
+		rand.Read(id)
+		uploadID := hex.EncodeToString(id)
+		n.uploads[uploadID] = make(map[int][]byte)
+		json.NewEncoder(w).Encode(map[string]string{"uploadID": uploadID})
+	case len(parts) == 2 && parts[0] == "upload" && r.Method == http.MethodPut:
+		chunks, ok := n.uploads[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		index, err := strconv.Atoi(r.Header.Get(storage.HeaderChunkIndex))
+		if err != nil {
+			http.Error(w, "invalid chunk index", http.StatusBadRequest)
+			return
+		}
+		if !verify(w, r, body) {
+			return
+		}
+		chunks[index] = body
+	case len(parts) == 3 && parts[0] == "upload" && parts[2] == "complete" && r.Method == http.MethodPost:
+		chunks, ok := n.uploads[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		indexes := make([]int, 0, len(chunks))
+		for index := range chunks {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		var data bytes.Buffer
+		for _, index := range indexes {
+			data.Write(chunks[index])
+		}
+		if !verify(w, r, data.Bytes()) {
+			return
+		}
+		delete(n.uploads, parts[1])
+		n.objects[r.URL.Query().Get("key")] = &object{data: data.Bytes(), sum: sum(data.Bytes())}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (n *StoreNode) serveObject(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	switch r.Method {
+	case http.MethodPut:
+		if verify(w, r, body) {
+			n.objects[key] = &object{data: body, sum: sum(body)}
+		}
+	case http.MethodGet:
+		obj, ok := n.objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(storage.HeaderContentSha256, obj.sum)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Write(obj.data)
+	case http.MethodDelete:
+		if _, ok := n.objects[key]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(n.objects, key)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify checks the body against the X-Content-Sha256 header and replies
+// 400 if they don't match.
+func verify(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	if r.Header.Get(storage.HeaderContentSha256) != sum(body) {
+		http.Error(w, "content doesn't match its sha256", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/memoio/xspace-server/config"
+	"golang.org/x/xerrors"
+)
+
+var ErrObjectNotFound = xerrors.New("object not found")
+
+// Storage keeps the content of dataNFTs.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorage creates the storage backend selected by the config.
+func NewStorage(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Backend {
+	case "local", "":
+		return NewLocalStorage(cfg.Path)
+	case "meeda":
+		opts := DefaultMeedaOptions()
+		opts.ChunkSize = cfg.ChunkSize
+		return NewMeedaStorage(cfg.MeedaURL, opts)
+	default:
+		return nil, xerrors.Errorf("unsupported storage backend %s", cfg.Backend)
+	}
+}
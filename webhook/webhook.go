@@ -0,0 +1,303 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+	"github.com/memoio/xspace-server/event"
+)
+
+// EventTypes are the events subscriptions accept.
+var EventTypes = event.Types
+
+// Event is the payload posted to the subscribers.
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// the project of a project event
+	ProjectID uint        `json:"projectId,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher queues the events of the bus for the subscriptions accepting
+// them and posts them. A delivery failing (no 2xx response) is retried with exponential
+// backoff and dead-lettered after the configured number of attempts.
+//
+// Every subscription has at most one worker posting its deliveries one
+// after the other, so a slow subscriber doesn't hold up the others. The
+// deliveries are claimed with a lease, the dispatchers of several replicas
+// don't post a delivery twice.
+//
+// A request carries the event type in X-Xspace-Event, the delivery id in
+// X-Xspace-Delivery, the unix time in X-Xspace-Timestamp and in
+// X-Xspace-Signature the hex HMAC-SHA256, keyed with the subscription's
+// secret, of timestamp + "." + body.
+type Dispatcher struct {
+	cfg      atomic.Pointer[config.WebhookConfig]
+	store    *database.DataStore
+	client   *http.Client
+	logger   *klog.Helper
+	interval time.Duration
+
+	// the subscriptions with a running worker
+	lk      sync.Mutex
+	busy    map[uint]bool
+	workers sync.WaitGroup
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+const (
+	// due deliveries claimed at once
+	claimBatch = 100
+	// deliveries of a subscription claimed by its worker at once
+	claimPerSubscription = 10
+	// the time to post a delivery if no timeout is configured
+	defaultPostTime = 30 * time.Second
+)
+
+func NewDispatcher(cfg config.WebhookConfig, store *database.DataStore, logger *klog.Helper) *Dispatcher {
+	d := &Dispatcher{
+		store:    store,
+		client:   &http.Client{},
+		logger:   logger,
+		interval: time.Second,
+		busy:     make(map[uint]bool),
+		done:     make(chan struct{}),
+	}
+	d.SetConfig(cfg)
+	return d
+}
+
+// SetConfig applies cfg to the next deliveries.
+func (d *Dispatcher) SetConfig(cfg config.WebhookConfig) {
+	d.cfg.Store(&cfg)
+}
+
+// Subscribe queues the events of the bus, the bus retries an event until it
+// is queued.
+func (d *Dispatcher) Subscribe(bus *event.Bus) {
+	bus.Subscribe(d.enqueue, EventTypes...)
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, msg event.Message) error {
+	var projectID uint
+	if e, ok := msg.Event.(event.ProjectEvent); ok {
+		projectID = e.Project()
+	}
+
+	payload, err := json.Marshal(Event{
+		ID:        msg.ID,
+		Type:      msg.Event.Type(),
+		ProjectID: projectID,
+		CreatedAt: msg.CreatedAt.UTC(),
+		Data:      msg.Event,
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.store.EnqueueWebhookEvent(msg.ID, msg.Event.Type(), projectID, payload)
+}
+
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(d.done)
+		defer d.workers.Wait()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			d.deliverDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop interrupts the deliveries in flight, they are released to be retried
+// after the restart or by another replica.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.cancel()
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliverDue claims the due deliveries of the subscriptions without a
+// running worker and starts their workers.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	d.lk.Lock()
+	skip := make([]uint, 0, len(d.busy))
+	for id := range d.busy {
+		skip = append(skip, id)
+	}
+	d.lk.Unlock()
+
+	deliveries, subs, err := d.store.ClaimDueDeliveries(claimBatch, claimPerSubscription, d.lease(), skip)
+	if err != nil {
+		d.logger.Error(err)
+		return
+	}
+
+	queues := make(map[uint][]*database.WebhookDelivery)
+	for i := range deliveries {
+		id := deliveries[i].SubscriptionID
+		queues[id] = append(queues[id], &deliveries[i])
+	}
+
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	for id, queue := range queues {
+		d.busy[id] = true
+		d.workers.Add(1)
+		go d.work(ctx, subs[id], queue)
+	}
+}
+
+// work posts the claimed deliveries of a subscription in order.
+func (d *Dispatcher) work(ctx context.Context, sub database.WebhookSubscription, queue []*database.WebhookDelivery) {
+	defer d.workers.Done()
+	defer func() {
+		d.lk.Lock()
+		delete(d.busy, queue[0].SubscriptionID)
+		d.lk.Unlock()
+	}()
+
+	for _, delivery := range queue {
+		d.deliver(ctx, delivery, sub)
+	}
+}
+
+// lease is how long a worker may take to post the deliveries it claimed.
+func (d *Dispatcher) lease() time.Duration {
+	post := defaultPostTime
+	if timeout := d.cfg.Load().Timeout; timeout > 0 {
+		post = time.Duration(timeout) * time.Second
+	}
+	return claimPerSubscription * (post + time.Second)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *database.WebhookDelivery, sub database.WebhookSubscription) {
+	attempt := &database.WebhookAttempt{}
+	start := time.Now()
+	if !sub.Active {
+		attempt.Error = "subscription deleted"
+	} else {
+		attempt.StatusCode, attempt.Error = d.post(ctx, delivery, sub)
+	}
+	attempt.Duration = time.Since(start).Milliseconds()
+	if ctx.Err() != nil {
+		// shutting down, the delivery is retried after the restart
+		if err := d.store.ReleaseWebhookDelivery(delivery.ID); err != nil {
+			d.logger.Error(err)
+		}
+		return
+	}
+
+	var next time.Time
+	if attempt.Error != "" && sub.Active && delivery.Attempts+1 < d.cfg.Load().MaxAttempts {
+		next = time.Now().Add(d.backoff(delivery.Attempts))
+	}
+	if err := d.store.RecordWebhookAttempt(delivery, attempt, next); err != nil {
+		d.logger.Error(err)
+		return
+	}
+	if delivery.Status == database.DeliveryDead {
+		d.logger.Warnf("webhook delivery %d to %s is dead: %s", delivery.ID, sub.URL, attempt.Error)
+	}
+}
+
+// post sends the delivery, it returns the response status and an error
+// message if the delivery failed.
+func (d *Dispatcher) post(ctx context.Context, delivery *database.WebhookDelivery, sub database.WebhookSubscription) (int, string) {
+	if timeout := d.cfg.Load().Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "xspace-webhook")
+	req.Header.Set("X-Xspace-Event", delivery.EventType)
+	req.Header.Set("X-Xspace-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Xspace-Timestamp", timestamp)
+	req.Header.Set("X-Xspace-Signature", Sign(sub.Secret, timestamp, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Sprintf("unexpected status %s", res.Status)
+	}
+	return res.StatusCode, ""
+}
+
+// backoff returns the delay before the retry following attempts failed
+// attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	cfg := d.cfg.Load()
+	delay := time.Duration(cfg.RetryBase) * time.Second
+	max := time.Duration(cfg.RetryMax) * time.Second
+	for i := 0; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// Sign returns the signature of a payload, subscribers check it the same
+// way.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret for a subscription.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	klog "github.com/go-kratos/kratos/v2/log"
+	"github.com/memoio/xspace-server/config"
+	"github.com/memoio/xspace-server/database"
+)
+
+func TestDispatcherSlowSubscriber(t *testing.T) {
+	store, err := database.NewDataStore("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	received := make(chan string, 10)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Xspace-Event")
+	}))
+	defer fast.Close()
+
+	var slowSub database.WebhookSubscription
+	for _, url := range []string{slow.URL, fast.URL} {
+		sub := database.WebhookSubscription{URL: url, Secret: "secret"}
+		if err := store.CreateWebhook("admin", &sub); err != nil {
+			t.Fatal(err)
+		}
+		if url == slow.URL {
+			slowSub = sub
+		}
+	}
+	for _, id := range []string{"event1", "event2"} {
+		if err := store.EnqueueWebhookEvent(id, "nft.minted", 0, []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDispatcher(config.WebhookConfig{MaxAttempts: 3, Timeout: 60, RetryBase: 1, RetryMax: 60}, store, klog.NewHelper(klog.DefaultLogger))
+	d.interval = 10 * time.Millisecond
+	d.Start(context.Background())
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the slow subscriber holds up the fast one")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the interrupted deliveries of the slow subscriber are released
+	deliveries, _, err := store.ClaimDueDeliveries(10, 10, time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := 0
+	for _, delivery := range deliveries {
+		if delivery.SubscriptionID == slowSub.ID {
+			released++
+		}
+	}
+	if released != 2 {
+		t.Fatalf("%d deliveries of the slow subscriber released, expected 2", released)
+	}
+}